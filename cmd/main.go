@@ -12,6 +12,7 @@ import (
 
 	"reservio/config"
 	"reservio/routes"
+	"reservio/utils"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -25,6 +26,8 @@ func main() {
 
 	config.ConnectDatabase()
 	config.InitSessionStore()
+	utils.CheckTokenSigningKey()
+	config.InitRedis()
 
 	// Configure zap logger based on LOG_LEVEL (debug|info|warn|error)
 	level := zap.InfoLevel
@@ -51,6 +54,16 @@ func main() {
 
 	router := routes.SetupRouter()
 
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go utils.StartReminderScheduler(jobsCtx)
+	go utils.StartAnnouncementScheduler(jobsCtx)
+	go utils.StartWebhookWorker(jobsCtx)
+	go utils.StartAvailabilityRelay(jobsCtx)
+	go utils.StartActivityRelay(jobsCtx)
+	go utils.StartDeferredNotificationSender(jobsCtx)
+	go utils.StartMaintenancePurger(jobsCtx)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -65,6 +78,8 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	srv.RegisterOnShutdown(utils.CloseAvailabilityStreams)
+
 	// Start server in a goroutine
 	go func() {
 		logger.Info("Starting server", zap.String("port", port))
@@ -79,6 +94,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopJobs()
 
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -97,6 +113,10 @@ func main() {
 		}
 	}
 
+	if config.Redis != nil {
+		_ = config.Redis.Close()
+	}
+
 	// Close Redis session store
 	if config.Store != nil {
 		// For redistore, we need to close the underlying Redis connection
@@ -43,12 +43,31 @@ func ConnectDatabase() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	if err := database.AutoMigrate(&models.User{}, &models.Child{}, &models.Reservation{}, &models.Slot{}, &models.PasswordResetToken{}, &models.Announcement{}); err != nil {
+	// Accounts from before email verification existed are treated as verified, so turning on
+	// require_verified_email doesn't lock them out of reservations
+	verifyExistingUsers := database.Migrator().HasTable(&models.User{}) && !database.Migrator().HasColumn(&models.User{}, "EmailVerified")
+
+	if err := database.AutoMigrate(&models.User{}, &models.Child{}, &models.Reservation{}, &models.Slot{}, &models.PasswordResetToken{}, &models.Announcement{}, &models.NotificationPreference{}, &models.Notification{}, &models.ReservationReminder{}, &models.WebhookEndpoint{}, &models.WebhookDelivery{}, &models.ActivityEvent{}, &models.AnnouncementAttachment{}, &models.AnnouncementReceipt{}, &models.RecoveryCode{}, &models.Setting{}, &models.APIToken{}, &models.Invitation{}, &models.UserSession{}, &models.LoginThrottle{}, &models.Role{}, &models.Impersonation{}, &models.ImpersonationRequest{}, &models.MagicLinkToken{}, &models.PasswordHistory{}, &models.SecurityEvent{}, &models.DeferredNotification{}); err != nil {
 		log.Fatal("AutoMigrate failed:", err)
 	}
+	backfillLegacyRows(database)
+	if verifyExistingUsers {
+		database.Model(&models.User{}).Where("email_verified = ?", false).Update("email_verified", true)
+	}
 	DB = database
 }
 
+// backfillLegacyRows gives rows created before a column existed the value the column
+// now defaults to, since adding a column leaves existing rows NULL
+func backfillLegacyRows(db *gorm.DB) {
+	// Announcements from before scheduling and audiences are published to everyone
+	db.Model(&models.Announcement{}).Where("status IS NULL OR status = ''").Update("status", "published")
+	db.Model(&models.Announcement{}).Where("audience IS NULL OR audience = ''").Update("audience", "all")
+	// Reminder rows from before claims had a state were all sent
+	db.Exec("UPDATE reservation_reminders SET status = 'sent' WHERE status IS NULL OR status = ''")
+	db.Exec("UPDATE reservation_reminders SET claimed_at = sent_at WHERE claimed_at IS NULL")
+}
+
 // NOTE: This code assumes github.com/boj/redistore v1.4.1 is used.
 // NewRediStoreWithDB signature: func NewRediStoreWithDB(size int, network, address, password string, db int, key []byte) (*RediStore, error)
 func InitSessionStore() {
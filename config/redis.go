@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Redis is a connection pool for features that share state between app instances
+// (live update fan-out). It stays nil in test mode; callers must fall back to
+// in-process behaviour when it is nil.
+var Redis *redis.Pool
+
+// InitRedis creates the shared Redis pool from REDIS_ADDR / REDIS_PASSWORD
+func InitRedis() {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+	redisPassword := os.Getenv("REDIS_PASSWORD")
+
+	Redis = &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", redisAddr, redis.DialPassword(redisPassword), redis.DialConnectTimeout(5*time.Second))
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
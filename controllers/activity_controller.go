@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	activityWriteWait  = 10 * time.Second
+	activityPongWait   = 60 * time.Second
+	activityPingPeriod = 50 * time.Second
+)
+
+// The default origin check only accepts same-host browser connections, which together
+// with the session cookie keeps other sites from opening the feed.
+var activityUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// activityRequest is a message sent by the client to change its subscription.
+// With last_event_id set, missed events after that ID are replayed first.
+type activityRequest struct {
+	Action      string   `json:"action"`
+	Types       []string `json:"types"`
+	LastEventID *uint    `json:"last_event_id"`
+}
+
+// ActivityFeed streams domain events (new reservations, cancellations, registrations,
+// slot changes...) to admins over a WebSocket (admin only).
+// Query: types (comma-separated event types, default all) and last_event_id to resume
+// after a reconnect. Clients can send {"action":"subscribe","types":[...],"last_event_id":N}
+// at any time to change what they receive.
+func ActivityFeed(w http.ResponseWriter, r *http.Request) {
+	types, err := utils.ParseActivityTypes(r.URL.Query().Get("types"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid event types")
+		}
+		return
+	}
+	var lastID uint
+	if raw := r.URL.Query().Get("last_event_id"); raw != "" {
+		if lastID, err = utils.ParseUint(raw); err != nil {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid last_event_id", map[string]interface{}{
+				"last_event_id": raw,
+			}))
+			return
+		}
+	}
+
+	conn, err := activityUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		zap.L().Debug("Activity feed upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// Subscribe before replaying so nothing recorded in between is missed
+	sub := utils.SubscribeActivity(types)
+	defer utils.UnsubscribeActivity(sub)
+
+	requests := make(chan activityRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go readActivityRequests(conn, requests, done)
+
+	write := func(msg map[string]interface{}) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(activityWriteWait))
+		return conn.WriteJSON(msg)
+	}
+
+	// seen holds the IDs already delivered, so replayed events aren't sent twice and live
+	// events arriving out of ID order aren't lost
+	seen := utils.NewActivitySeen(lastID)
+	replay := func(after uint) error {
+		entries, err := utils.ActivitySince(after, types)
+		if err != nil {
+			zap.L().Error("Failed to load activity for resume", zap.Error(err))
+			return write(map[string]interface{}{"type": "error", "message": "Failed to load missed events"})
+		}
+		for _, entry := range entries {
+			if !seen.Add(entry.ID) {
+				continue
+			}
+			if err := write(map[string]interface{}{"type": "activity", "activity": entry}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := write(map[string]interface{}{"type": "subscribed", "types": activityTypeList(types)}); err != nil {
+		return
+	}
+	if lastID > 0 {
+		if err := replay(lastID); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(activityPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case entry := <-sub.C:
+			if !seen.Add(entry.ID) {
+				continue
+			}
+			if err := write(map[string]interface{}{"type": "activity", "activity": entry}); err != nil {
+				return
+			}
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			if req.Action != "subscribe" {
+				if err := write(map[string]interface{}{"type": "error", "message": "Unknown action"}); err != nil {
+					return
+				}
+				continue
+			}
+			newTypes, err := utils.ParseActivityTypes(strings.Join(req.Types, ","))
+			if err != nil {
+				if err := write(map[string]interface{}{"type": "error", "message": "Unknown event type", "valid_events": utils.DomainEventTypes}); err != nil {
+					return
+				}
+				continue
+			}
+			types = newTypes
+			sub.SetTypes(types)
+			if err := write(map[string]interface{}{"type": "subscribed", "types": activityTypeList(types)}); err != nil {
+				return
+			}
+			if req.LastEventID != nil {
+				seen = utils.NewActivitySeen(*req.LastEventID)
+				if err := replay(*req.LastEventID); err != nil {
+					return
+				}
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(activityWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readActivityRequests forwards client messages until the connection closes or done is
+// closed. Messages that aren't valid JSON are forwarded as an empty (unknown) action.
+func readActivityRequests(conn *websocket.Conn, requests chan<- activityRequest, done <-chan struct{}) {
+	defer close(requests)
+	conn.SetReadLimit(4096)
+	_ = conn.SetReadDeadline(time.Now().Add(activityPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(activityPongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req activityRequest
+		_ = json.Unmarshal(data, &req)
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+func activityTypeList(types map[string]bool) []string {
+	if types == nil {
+		return []string{"*"}
+	}
+	list := make([]string, 0, len(types))
+	for t := range types {
+		list = append(list, t)
+	}
+	return list
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"reservio/config"
+	"reservio/middleware"
 	"reservio/models"
 	"reservio/utils"
 
@@ -37,6 +38,7 @@ func CreateSlot(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create slot")
 		return
 	}
+	utils.PublishEvent(utils.EventSlotCreated, utils.SlotEventData(slot))
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Slot created successfully",
@@ -84,6 +86,11 @@ func ApproveReservation(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to approve reservation")
 		return
 	}
+	utils.PublishEvent(utils.EventReservationApproved, utils.ReservationEventData(reservation))
+
+	if err := utils.NotifyReservationStatus(reservation); err != nil {
+		zap.L().Warn("Failed to notify parent of reservation status", zap.Uint("reservation_id", reservation.ID), zap.Error(err))
+	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Reservation approved successfully",
@@ -132,6 +139,11 @@ func RejectReservation(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reject reservation")
 		return
 	}
+	utils.PublishEvent(utils.EventReservationRejected, utils.ReservationEventData(reservation))
+
+	if err := utils.NotifyReservationStatus(reservation); err != nil {
+		zap.L().Warn("Failed to notify parent of reservation status", zap.Uint("reservation_id", reservation.ID), zap.Error(err))
+	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Reservation rejected successfully",
@@ -225,12 +237,17 @@ func ListUsers(w http.ResponseWriter, r *http.Request) {
 	var users []models.User
 	var total int64
 
+	query := config.DB.Model(&models.User{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	// Get total count
-	config.DB.Model(&models.User{}).Count(&total)
+	query.Count(&total)
 
 	// Get paginated results
 	offset := (page - 1) * perPage
-	if err := config.DB.Offset(offset).Limit(perPage).Find(&users).Error; err != nil {
+	if err := query.Offset(offset).Limit(perPage).Find(&users).Error; err != nil {
 		zap.L().Error("Failed to get users", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve users")
 		return
@@ -240,9 +257,11 @@ func ListUsers(w http.ResponseWriter, r *http.Request) {
 	var usersData []map[string]interface{}
 	for _, user := range users {
 		userData := map[string]interface{}{
-			"id":    user.ID,
-			"email": user.Email,
-			"role":  user.Role,
+			"id":         user.ID,
+			"email":      user.Email,
+			"role":       user.Role,
+			"status":     user.Status,
+			"created_at": user.CreatedAt,
 		}
 
 		usersData = append(usersData, userData)
@@ -256,7 +275,13 @@ func ListUsers(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithPaginatedData(w, usersData, page, perPage, int(total))
 }
 
+// DeleteUser removes an account. Like role changes, staff can only delete users whose
+// permissions their own role also has; nobody can delete themselves or the last admin.
 func DeleteUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
 	id := vars["id"]
 	userID, err := utils.ParseUint(id)
@@ -267,26 +292,54 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := config.DB.Delete(&models.User{}, userID)
-	if result.Error != nil {
-		zap.L().Error("Failed to delete user", zap.Error(result.Error))
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user")
-		return
-	}
-	if result.RowsAffected == 0 {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
 		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
 			"user_id": userID,
 		}))
 		return
 	}
 
+	if user.ID == actor.ID {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrForbidden, "You can't delete your own account", map[string]interface{}{
+			"user_id": user.ID,
+		}))
+		return
+	}
+	if !utils.CanGrantRole(actor.Role, user.Role) {
+		respondRoleNotGrantable(w, user.Role)
+		return
+	}
+	if user.Role == utils.RoleAdmin {
+		var admins int64
+		config.DB.Model(&models.User{}).Where("role = ?", utils.RoleAdmin).Count(&admins)
+		if admins <= 1 {
+			utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrForbidden, "Can't delete the last admin", map[string]interface{}{
+				"user_id": user.ID,
+			}))
+			return
+		}
+	}
+
+	if err := config.DB.Delete(&user).Error; err != nil {
+		zap.L().Error("Failed to delete user", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "User deleted successfully",
 		"user_id": userID,
 	})
 }
 
+// UpdateUserRole assigns a built-in or custom role. Staff can only assign roles (and change
+// users) whose permissions their own role also has, and the last admin can't be demoted.
 func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	actor, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
 	id := vars["id"]
 	userID, err := utils.ParseUint(id)
@@ -323,12 +376,33 @@ func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !utils.CanGrantRole(actor.Role, user.Role) || !utils.CanGrantRole(actor.Role, body.Role) {
+		respondRoleNotGrantable(w, body.Role)
+		return
+	}
+	if user.Role == utils.RoleAdmin && body.Role != utils.RoleAdmin {
+		var admins int64
+		config.DB.Model(&models.User{}).Where("role = ?", utils.RoleAdmin).Count(&admins)
+		if admins <= 1 {
+			utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrForbidden, "Can't change the role of the last admin", map[string]interface{}{
+				"user_id": user.ID,
+			}))
+			return
+		}
+	}
+
+	previousRole := user.Role
 	user.Role = body.Role
 	if err := config.DB.Save(&user).Error; err != nil {
 		zap.L().Error("Failed to update user role", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update user role")
 		return
 	}
+	if previousRole != user.Role {
+		event := utils.SecurityEventFor(utils.SecurityRoleChanged, utils.OutcomeSuccess, &user, previousRole+" → "+user.Role)
+		event.ActorID = &actor.ID
+		utils.RecordSecurityEvent(r, event)
+	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "User role updated successfully",
@@ -340,6 +414,71 @@ func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ActivateUser approves an account that is waiting in approval_required mode
+func ActivateUser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	userID, err := utils.ParseUint(id)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid user ID", map[string]interface{}{
+			"user_id": id,
+		}))
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
+			"user_id": userID,
+		}))
+		return
+	}
+
+	if user.Status != utils.UserStatusActive {
+		if err := config.DB.Model(&user).Update("status", utils.UserStatusActive).Error; err != nil {
+			zap.L().Error("Failed to activate user", zap.Error(err))
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to activate user")
+			return
+		}
+		if err := utils.NotifyUser(user, utils.NotificationMessage{
+			Event:   utils.NotifyAccount,
+			Subject: "Your account has been approved",
+			Body:    "An administrator approved your Reservio account. You can now make reservations.",
+		}); err != nil {
+			zap.L().Warn("Failed to send approval notice", zap.Error(err))
+		}
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "User activated successfully",
+		"user": map[string]interface{}{
+			"id":     user.ID,
+			"email":  user.Email,
+			"role":   user.Role,
+			"status": user.Status,
+		},
+	})
+}
+
+// UnlockUserLogin clears the failed-login lockouts of a user's account (admin only)
+func UnlockUserLogin(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := utils.UnlockLogin(user.Email); err != nil {
+		zap.L().Error("Failed to unlock login", zap.Uint("user_id", user.ID), zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to unlock account")
+		return
+	}
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(uint)
+	zap.L().Info("Admin unlocked login", zap.Uint("admin_id", adminID), zap.Uint("user_id", user.ID))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Account unlocked",
+		"user_id": user.ID,
+	})
+}
+
 // ListChildrenWithParents returns all children with parent information (admin only)
 func ListChildrenWithParents(w http.ResponseWriter, r *http.Request) {
 	// Parse pagination parameters
@@ -436,6 +575,7 @@ func UpdateSlot(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update slot")
 		return
 	}
+	utils.PublishEvent(utils.EventSlotUpdated, utils.SlotEventData(slot))
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Slot updated successfully",
 		"slot":    slot,
@@ -451,10 +591,15 @@ func DeleteSlot(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid slot ID", nil))
 		return
 	}
+	// Load the slot (best effort) so the deletion event carries its date and capacity
+	var slot models.Slot
+	config.DB.First(&slot, slotID)
+	slot.ID = slotID
 	if err := config.DB.Delete(&models.Slot{}, slotID).Error; err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete slot")
 		return
 	}
+	utils.PublishEvent(utils.EventSlotDeleted, utils.SlotEventData(slot))
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Slot deleted successfully",
 		"slot_id": slotID,
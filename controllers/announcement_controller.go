@@ -2,17 +2,88 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reservio/config"
 	"reservio/middleware"
 	"reservio/models"
 	"reservio/utils"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
-// ListAnnouncements returns public announcements ordered by newest first (paginated).
+func attachmentResponse(att models.AnnouncementAttachment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            att.ID,
+		"original_name": att.OriginalName,
+		"content_type":  att.ContentType,
+		"size":          att.Size,
+		"download_url":  fmt.Sprintf("/api/user/announcements/%d/attachments/%d", att.AnnouncementID, att.ID),
+	}
+}
+
+func announcementResponse(a models.Announcement) map[string]interface{} {
+	publishedAt := a.CreatedAt
+	if a.PublishAt != nil {
+		publishedAt = *a.PublishAt
+	}
+	audience := a.Audience
+	if audience == "" {
+		audience = utils.AudienceAll
+	}
+	attachments := []map[string]interface{}{}
+	for _, att := range a.Attachments {
+		attachments = append(attachments, attachmentResponse(att))
+	}
+	item := map[string]interface{}{
+		"id":           a.ID,
+		"title":        a.Title,
+		"content":      a.Content,
+		"content_html": utils.RenderMarkdown(a.Content),
+		"attachments":  attachments,
+		"created_at":   a.CreatedAt,
+		"published_at": publishedAt,
+		"expires_at":   a.ExpiresAt,
+		"pinned":       a.Pinned,
+		"audience":     audience,
+		"requires_ack": a.RequiresAck,
+	}
+	if a.Author != nil {
+		item["author"] = map[string]interface{}{
+			"id":    a.Author.ID,
+			"email": a.Author.Email,
+		}
+	}
+	return item
+}
+
+// adminAnnouncementResponse adds the scheduling and targeting details only admins see
+func adminAnnouncementResponse(a models.Announcement, now time.Time) map[string]interface{} {
+	item := announcementResponse(a)
+	status := a.Status
+	if status == "" {
+		status = utils.AnnouncementPublished
+	}
+	item["status"] = status
+	item["publish_at"] = a.PublishAt
+	item["urgent"] = a.Urgent
+	item["audience_slot_id"] = a.AudienceSlotID
+	item["audience_from"] = a.AudienceFrom
+	item["audience_to"] = a.AudienceTo
+	item["notified_at"] = a.NotifiedAt
+	item["live"] = utils.AnnouncementIsLive(a, now)
+	return item
+}
+
+// ListAnnouncements returns the live announcements the viewer may see, pinned first and
+// then newest first (paginated). Anonymous visitors only see announcements for everyone;
+// logged-in parents also see those targeted at parents or at their reservations, and each
+// item carries read/acknowledged flags for the viewer.
 func ListAnnouncements(w http.ResponseWriter, r *http.Request) {
 	// Parse pagination parameters
 	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
@@ -25,32 +96,47 @@ func ListAnnouncements(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The session is optional here (middleware.OptionalAuth)
+	var viewer *models.User
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(uint); ok {
+		var user models.User
+		if err := config.DB.First(&user, userID).Error; err == nil {
+			viewer = &user
+		}
+	}
+
+	now := time.Now()
 	var announcements []models.Announcement
 	var total int64
 
-	config.DB.Model(&models.Announcement{}).Count(&total)
+	utils.AnnouncementsVisibleTo(config.DB.Model(&models.Announcement{}), viewer, now).Count(&total)
 
 	offset := (page - 1) * perPage
-	if err := config.DB.Preload("Author").Order("created_at DESC").Offset(offset).Limit(perPage).Find(&announcements).Error; err != nil {
+	query := utils.AnnouncementsVisibleTo(config.DB.Preload("Author").Preload("Attachments"), viewer, now)
+	if err := query.Order("pinned DESC, COALESCE(publish_at, created_at) DESC").Offset(offset).Limit(perPage).Find(&announcements).Error; err != nil {
 		zap.L().Error("Failed to get announcements", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve announcements")
 		return
 	}
 
+	// Logged-in viewers get their read/acknowledged state for badges
+	var receipts map[uint]models.AnnouncementReceipt
+	if viewer != nil {
+		ids := make([]uint, 0, len(announcements))
+		for _, a := range announcements {
+			ids = append(ids, a.ID)
+		}
+		receipts = utils.AnnouncementReceipts(viewer.ID, ids)
+	}
+
 	// Convert to response format (avoid sending author password)
 	var data []map[string]interface{}
 	for _, a := range announcements {
-		item := map[string]interface{}{
-			"id":         a.ID,
-			"title":      a.Title,
-			"content":    a.Content,
-			"created_at": a.CreatedAt,
-		}
-		if a.Author != nil {
-			item["author"] = map[string]interface{}{
-				"id":    a.Author.ID,
-				"email": a.Author.Email,
-			}
+		item := announcementResponse(a)
+		if viewer != nil {
+			receipt := receipts[a.ID]
+			item["read"] = receipt.ReadAt != nil
+			item["acknowledged"] = receipt.AcknowledgedAt != nil
 		}
 		data = append(data, item)
 	}
@@ -62,45 +148,184 @@ func ListAnnouncements(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithPaginatedData(w, data, page, perPage, int(total))
 }
 
-// CreateAnnouncement (admin only)
+// ListAllAnnouncements returns every announcement including drafts, scheduled and expired
+// ones (admin only, paginated). Optional state filter: draft, scheduled, live or expired.
+func ListAllAnnouncements(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	now := time.Now()
+	query := config.DB.Model(&models.Announcement{})
+	switch state := r.URL.Query().Get("state"); state {
+	case "":
+	case "draft":
+		query = query.Where("status = ?", utils.AnnouncementDraft)
+	case "scheduled":
+		query = query.Where("COALESCE(status, '') <> ? AND publish_at > ?", utils.AnnouncementDraft, now)
+	case "live":
+		query = utils.LiveAnnouncements(query, now)
+	case "expired":
+		query = query.Where("COALESCE(status, '') <> ? AND expires_at <= ?", utils.AnnouncementDraft, now)
+	default:
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid state. Must be 'draft', 'scheduled', 'live' or 'expired'", map[string]interface{}{
+			"field": "state",
+			"value": state,
+		}))
+		return
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var announcements []models.Announcement
+	offset := (page - 1) * perPage
+	if err := query.Preload("Author").Preload("Attachments").Order("created_at DESC").Offset(offset).Limit(perPage).Find(&announcements).Error; err != nil {
+		zap.L().Error("Failed to get announcements", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve announcements")
+		return
+	}
+
+	data := []map[string]interface{}{}
+	for _, a := range announcements {
+		data = append(data, adminAnnouncementResponse(a, now))
+	}
+	utils.RespondWithPaginatedData(w, data, page, perPage, int(total))
+}
+
+// announcementInput is the admin request body for creating and updating announcements.
+// Omitted fields are left unchanged on update; publish_at and expires_at take RFC 3339
+// timestamps, and an empty string clears them.
+type announcementInput struct {
+	Title          *string `json:"title"`
+	Content        *string `json:"content"`
+	Urgent         *bool   `json:"urgent"` // e.g. closures: bypass quiet hours and send by SMS
+	Status         *string `json:"status"`
+	PublishAt      *string `json:"publish_at"`
+	ExpiresAt      *string `json:"expires_at"`
+	Pinned         *bool   `json:"pinned"`
+	RequiresAck    *bool   `json:"requires_ack"`
+	Audience       *string `json:"audience"`
+	AudienceSlotID *uint   `json:"audience_slot_id"`
+	AudienceFrom   *string `json:"audience_from"`
+	AudienceTo     *string `json:"audience_to"`
+}
+
+// apply copies the provided fields onto ann and validates the result
+func (in announcementInput) apply(ann *models.Announcement) error {
+	if in.Title != nil && utils.IsFieldPresent(*in.Title) {
+		ann.Title = *in.Title
+	}
+	if in.Content != nil && utils.IsFieldPresent(*in.Content) {
+		ann.Content = *in.Content
+	}
+	if in.Urgent != nil {
+		ann.Urgent = *in.Urgent
+	}
+	if in.Status != nil {
+		ann.Status = *in.Status
+	}
+	if in.PublishAt != nil {
+		t, err := utils.ParseAnnouncementTime("publish_at", *in.PublishAt)
+		if err != nil {
+			return err
+		}
+		ann.PublishAt = t
+	}
+	if in.ExpiresAt != nil {
+		t, err := utils.ParseAnnouncementTime("expires_at", *in.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		ann.ExpiresAt = t
+	}
+	if in.Pinned != nil {
+		ann.Pinned = *in.Pinned
+	}
+	if in.RequiresAck != nil {
+		ann.RequiresAck = *in.RequiresAck
+	}
+	if in.Audience != nil {
+		ann.Audience = *in.Audience
+	}
+	if in.AudienceSlotID != nil {
+		ann.AudienceSlotID = in.AudienceSlotID
+	}
+	if in.AudienceFrom != nil {
+		ann.AudienceFrom = *in.AudienceFrom
+	}
+	if in.AudienceTo != nil {
+		ann.AudienceTo = *in.AudienceTo
+	}
+	// Rows created before scheduling existed have no status or audience yet
+	if ann.Status == "" {
+		ann.Status = utils.AnnouncementPublished
+	}
+	if ann.Audience == "" {
+		ann.Audience = utils.AudienceAll
+	}
+	if ann.Audience != utils.AudienceSlot {
+		ann.AudienceSlotID = nil
+	}
+	if ann.Audience != utils.AudienceDates {
+		ann.AudienceFrom, ann.AudienceTo = "", ""
+	}
+	return utils.ValidateAnnouncement(*ann)
+}
+
+// CreateAnnouncement (admin only). Announcements are published immediately unless
+// status is "draft" or publish_at is in the future; the audience is notified when the
+// announcement goes live.
 func CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
 	// Must be admin (middleware.AdminOnly already enforced in route)
 	userID, _ := r.Context().Value(middleware.UserIDKey).(uint)
-	var body struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
-	}
+	var body announcementInput
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
 		return
 	}
-	if !utils.IsFieldPresent(body.Title) {
+	if body.Title == nil || !utils.IsFieldPresent(*body.Title) {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Title is required", nil))
 		return
 	}
-	if !utils.IsFieldPresent(body.Content) {
+	if body.Content == nil || !utils.IsFieldPresent(*body.Content) {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Content is required", nil))
 		return
 	}
-	ann := models.Announcement{Title: body.Title, Content: body.Content, AuthorID: userID}
+	ann := models.Announcement{AuthorID: userID}
+	if err := body.apply(&ann); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid announcement data")
+		}
+		return
+	}
 	if err := config.DB.Create(&ann).Error; err != nil {
 		zap.L().Error("Failed to create announcement", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create announcement")
 		return
 	}
 
+	now := time.Now()
+	if utils.AnnouncementIsLive(ann, now) {
+		go utils.NotifyAnnouncement(ann, now)
+	}
+
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"message": "Announcement created successfully",
-		"announcement": map[string]interface{}{
-			"id":         ann.ID,
-			"title":      ann.Title,
-			"content":    ann.Content,
-			"created_at": ann.CreatedAt,
-		},
+		"message":      "Announcement created successfully",
+		"announcement": adminAnnouncementResponse(ann, now),
 	})
 }
 
-// UpdateAnnouncement (admin only)
+// UpdateAnnouncement (admin only). Publishing a draft notifies its audience if it is
+// already live; scheduled announcements are notified by the scheduler.
 func UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -111,25 +336,25 @@ func UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var ann models.Announcement
-	if err := config.DB.First(&ann, annID).Error; err != nil {
+	if err := config.DB.Preload("Attachments").First(&ann, annID).Error; err != nil {
 		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Announcement not found", nil))
 		return
 	}
 
-	var body struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
-	}
+	var body announcementInput
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
 		return
 	}
 
-	if utils.IsFieldPresent(body.Title) {
-		ann.Title = body.Title
-	}
-	if utils.IsFieldPresent(body.Content) {
-		ann.Content = body.Content
+	wasDraft := ann.Status == utils.AnnouncementDraft
+	if err := body.apply(&ann); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid announcement data")
+		}
+		return
 	}
 
 	if err := config.DB.Save(&ann).Error; err != nil {
@@ -138,14 +363,14 @@ func UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	now := time.Now()
+	if wasDraft && ann.PublishAt == nil && utils.AnnouncementIsLive(ann, now) {
+		go utils.NotifyAnnouncement(ann, now)
+	}
+
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"message": "Announcement updated successfully",
-		"announcement": map[string]interface{}{
-			"id":         ann.ID,
-			"title":      ann.Title,
-			"content":    ann.Content,
-			"created_at": ann.CreatedAt,
-		},
+		"message":      "Announcement updated successfully",
+		"announcement": adminAnnouncementResponse(ann, now),
 	})
 }
 
@@ -159,6 +384,7 @@ func DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	config.DB.Where("announcement_id = ?", annID).Delete(&models.AnnouncementAttachment{})
 	if err := config.DB.Delete(&models.Announcement{}, annID).Error; err != nil {
 		zap.L().Error("Failed to delete announcement", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete announcement")
@@ -170,3 +396,312 @@ func DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
 		"announcement_id": annID,
 	})
 }
+
+// UploadAnnouncementAttachment attaches a file (multipart field "file") to an announcement
+// (admin only). Allowed types and the size limit come from utils.AttachmentPolicy.
+func UploadAnnouncementAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(uint)
+	annID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid announcement ID", nil))
+		return
+	}
+	var ann models.Announcement
+	if err := config.DB.First(&ann, annID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Announcement not found", nil))
+		return
+	}
+
+	upload, err := utils.SaveUpload(w, r, "file", fmt.Sprintf("announcement_%d", ann.ID), utils.AttachmentPolicy())
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			status := http.StatusBadRequest
+			if validationErr.Code == utils.ErrFileTooLarge {
+				status = http.StatusRequestEntityTooLarge
+			}
+			utils.RespondWithValidationError(w, status, validationErr)
+		} else {
+			zap.L().Error("Failed to save attachment", zap.Error(err))
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save file")
+		}
+		return
+	}
+
+	att := models.AnnouncementAttachment{
+		AnnouncementID: ann.ID,
+		Filename:       upload.Filename,
+		OriginalName:   upload.OriginalName,
+		ContentType:    upload.ContentType,
+		Size:           upload.Size,
+		UploadedByID:   userID,
+	}
+	if err := config.DB.Create(&att).Error; err != nil {
+		zap.L().Error("Failed to create attachment", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save attachment")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    "Attachment uploaded successfully",
+		"attachment": attachmentResponse(att),
+	})
+}
+
+// findAttachment loads the attachment named by the {id}/{attachment_id} route variables
+func findAttachment(w http.ResponseWriter, r *http.Request) (models.AnnouncementAttachment, bool) {
+	var att models.AnnouncementAttachment
+	vars := mux.Vars(r)
+	annID, err := utils.ParseUint(vars["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid announcement ID", nil))
+		return att, false
+	}
+	attID, err := utils.ParseUint(vars["attachment_id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid attachment ID", nil))
+		return att, false
+	}
+	if err := config.DB.Where("announcement_id = ?", annID).First(&att, attID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Attachment not found", nil))
+		return att, false
+	}
+	return att, true
+}
+
+// DeleteAnnouncementAttachment removes an attachment and its file (admin only)
+func DeleteAnnouncementAttachment(w http.ResponseWriter, r *http.Request) {
+	att, ok := findAttachment(w, r)
+	if !ok {
+		return
+	}
+	if err := config.DB.Unscoped().Delete(&att).Error; err != nil {
+		zap.L().Error("Failed to delete attachment", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete attachment")
+		return
+	}
+	if err := os.Remove(filepath.Join(utils.AttachmentPolicy().Dir, att.Filename)); err != nil && !os.IsNotExist(err) {
+		zap.L().Warn("Failed to remove attachment file", zap.String("file", att.Filename), zap.Error(err))
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":       "Attachment deleted successfully",
+		"attachment_id": att.ID,
+	})
+}
+
+// DownloadAnnouncementAttachment serves an attachment to a logged-in user who can see the
+// announcement; anyone else gets 404 so hidden announcements don't leak.
+func DownloadAnnouncementAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	att, ok := findAttachment(w, r)
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	var visible int64
+	utils.AnnouncementsVisibleTo(config.DB.Model(&models.Announcement{}), &user, time.Now()).
+		Where("announcements.id = ?", att.AnnouncementID).Count(&visible)
+	if visible == 0 {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Attachment not found", nil))
+		return
+	}
+
+	file, err := os.Open(filepath.Join(utils.AttachmentPolicy().Dir, att.Filename))
+	if err != nil {
+		zap.L().Error("Attachment file missing", zap.String("file", att.Filename), zap.Error(err))
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Attachment not found", nil))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.OriginalName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, r, "", att.CreatedAt, file)
+}
+
+// findVisibleAnnouncement loads the {id} announcement if the current user can see it
+func findVisibleAnnouncement(w http.ResponseWriter, r *http.Request) (models.Announcement, models.User, bool) {
+	var ann models.Announcement
+	var user models.User
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return ann, user, false
+	}
+	annID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid announcement ID", nil))
+		return ann, user, false
+	}
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return ann, user, false
+	}
+	if err := utils.AnnouncementsVisibleTo(config.DB, &user, time.Now()).First(&ann, annID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Announcement not found", nil))
+		return ann, user, false
+	}
+	return ann, user, true
+}
+
+// MarkAnnouncementRead records that the current user has read an announcement
+func MarkAnnouncementRead(w http.ResponseWriter, r *http.Request) {
+	recordAnnouncementReceipt(w, r, false)
+}
+
+// AcknowledgeAnnouncement records the current user's acknowledgement (which also marks
+// the announcement read). Only announcements with requires_ack can be acknowledged.
+func AcknowledgeAnnouncement(w http.ResponseWriter, r *http.Request) {
+	recordAnnouncementReceipt(w, r, true)
+}
+
+func recordAnnouncementReceipt(w http.ResponseWriter, r *http.Request, acknowledge bool) {
+	ann, user, ok := findVisibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	if acknowledge && !ann.RequiresAck {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "This announcement does not require acknowledgement", map[string]interface{}{
+			"announcement_id": ann.ID,
+		}))
+		return
+	}
+	receipt, err := utils.MarkAnnouncementRead(ann.ID, user.ID, acknowledge, time.Now())
+	if err != nil {
+		zap.L().Error("Failed to record announcement receipt", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update announcement")
+		return
+	}
+	message := "Announcement marked as read"
+	if acknowledge {
+		message = "Announcement acknowledged"
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":         message,
+		"announcement_id": ann.ID,
+		"read_at":         receipt.ReadAt,
+		"acknowledged_at": receipt.AcknowledgedAt,
+	})
+}
+
+// GetUnreadAnnouncementCount returns the current user's unread and unacknowledged announcement counts
+func GetUnreadAnnouncementCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	unread, unacknowledged := utils.CountUnreadAnnouncements(user)
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"unread":         unread,
+		"unacknowledged": unacknowledged,
+	})
+}
+
+// findAnnouncement loads the {id} announcement for admin endpoints
+func findAnnouncement(w http.ResponseWriter, r *http.Request) (models.Announcement, bool) {
+	var ann models.Announcement
+	annID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid announcement ID", nil))
+		return ann, false
+	}
+	if err := config.DB.First(&ann, annID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Announcement not found", nil))
+		return ann, false
+	}
+	return ann, true
+}
+
+// GetAnnouncementReceipts reports which recipients have read and acknowledged an
+// announcement (admin only). Optional filter: pending=true lists only recipients who
+// still have to act (acknowledge, or read when no acknowledgement is required).
+func GetAnnouncementReceipts(w http.ResponseWriter, r *http.Request) {
+	ann, ok := findAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	report, err := utils.AnnouncementReceiptReport(ann)
+	if err != nil {
+		zap.L().Error("Failed to build receipt report", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve receipts")
+		return
+	}
+
+	pendingOnly := r.URL.Query().Get("pending") == "true"
+	var read, acknowledged int
+	recipients := []map[string]interface{}{}
+	for _, status := range report {
+		if status.ReadAt != nil {
+			read++
+		}
+		if status.AcknowledgedAt != nil {
+			acknowledged++
+		}
+		done := status.ReadAt != nil
+		if ann.RequiresAck {
+			done = status.AcknowledgedAt != nil
+		}
+		if pendingOnly && done {
+			continue
+		}
+		recipients = append(recipients, map[string]interface{}{
+			"user_id":         status.User.ID,
+			"email":           status.User.Email,
+			"first_name":      status.User.FirstName,
+			"last_name":       status.User.LastName,
+			"read_at":         status.ReadAt,
+			"acknowledged_at": status.AcknowledgedAt,
+		})
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"announcement_id": ann.ID,
+		"requires_ack":    ann.RequiresAck,
+		"summary": map[string]interface{}{
+			"recipients":   len(report),
+			"read":         read,
+			"acknowledged": acknowledged,
+		},
+		"recipients": recipients,
+	})
+}
+
+// RemindAnnouncement re-notifies recipients who haven't acknowledged the announcement yet
+// (or haven't read it, when no acknowledgement is required) (admin only)
+func RemindAnnouncement(w http.ResponseWriter, r *http.Request) {
+	ann, ok := findAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	if !utils.AnnouncementIsLive(ann, time.Now()) {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Only live announcements can be reminded", map[string]interface{}{
+			"announcement_id": ann.ID,
+		}))
+		return
+	}
+	reminded, err := utils.RemindAnnouncementRecipients(ann)
+	if err != nil {
+		zap.L().Error("Failed to send announcement reminders", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to send reminders")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":  "Reminders sent",
+		"reminded": reminded,
+	})
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func apiTokenResponse(token models.APIToken) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       strings.Split(token.Scopes, ","),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"last_used_ip": token.LastUsedIP,
+		"revoked_at":   token.RevokedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
+
+// ListAPITokens returns the current user's personal access tokens (never the token values).
+// Revoked and expired tokens are included unless active=true.
+func ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	query := config.DB.Where("user_id = ?", user.ID)
+	if r.URL.Query().Get("active") == "true" {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	var tokens []models.APIToken
+	if err := query.Order("created_at DESC").Find(&tokens).Error; err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tokens")
+		return
+	}
+	data := []map[string]interface{}{}
+	for _, token := range tokens {
+		data = append(data, apiTokenResponse(token))
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"tokens": data,
+	})
+}
+
+// CreateAPIToken issues a named, scoped token that expires after expires_in_days
+// (default 90, max 365). The token value is returned only in this response.
+func CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 100 {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Token name is required (max 100 characters)", map[string]interface{}{
+			"field": "name",
+		}))
+		return
+	}
+	if err := utils.ValidateAPITokenScopes(body.Scopes, user.Role); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid scopes")
+		}
+		return
+	}
+	if body.ExpiresInDays == 0 {
+		body.ExpiresInDays = utils.DefaultAPITokenDays
+	}
+	if body.ExpiresInDays < 1 || body.ExpiresInDays > utils.MaxAPITokenDays {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "expires_in_days must be between 1 and 365", map[string]interface{}{
+			"field": "expires_in_days",
+			"value": body.ExpiresInDays,
+		}))
+		return
+	}
+
+	now := time.Now()
+	var active int64
+	config.DB.Model(&models.APIToken{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, now).Count(&active)
+	if active >= utils.MaxAPITokensPerUser {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Too many active tokens; revoke one first", map[string]interface{}{
+			"max_tokens": utils.MaxAPITokensPerUser,
+		}))
+		return
+	}
+
+	raw, prefix := utils.GenerateAPIToken()
+	token := models.APIToken{
+		UserID:    user.ID,
+		Name:      body.Name,
+		Prefix:    prefix,
+		TokenHash: utils.HashToken(raw),
+		Scopes:    strings.Join(body.Scopes, ","),
+		ExpiresAt: now.AddDate(0, 0, body.ExpiresInDays),
+	}
+	if err := config.DB.Create(&token).Error; err != nil {
+		zap.L().Error("Failed to create API token", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+	zap.L().Info("API token created", zap.Uint("user_id", user.ID), zap.Uint("token_id", token.ID), zap.String("scopes", token.Scopes))
+
+	resp := apiTokenResponse(token)
+	resp["token"] = raw
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Token created. Copy it now, it won't be shown again",
+		"token":   resp,
+	})
+}
+
+// RevokeAPIToken revokes one of the current user's tokens; it stops working immediately
+func RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	tokenID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid token ID", nil))
+		return
+	}
+	var token models.APIToken
+	if err := config.DB.Where("id = ? AND user_id = ?", tokenID, user.ID).First(&token).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Token not found", map[string]interface{}{
+			"token_id": tokenID,
+		}))
+		return
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		if err := config.DB.Model(&token).Update("revoked_at", now).Error; err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Token revoked",
+		"token":   apiTokenResponse(token),
+	})
+}
@@ -1,17 +1,15 @@
 package controllers
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
-	"os"
 	"reservio/config"
 	"reservio/middleware"
 	"reservio/models"
 	"reservio/utils"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,19 +18,14 @@ import (
 	"gorm.io/gorm"
 )
 
-func generateResetToken() string {
-	b := make([]byte, 32)
-	_, _ = rand.Read(b)
-	return base64.URLEncoding.EncodeToString(b)
-}
-
 func Register(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
-		Email     string `json:"email"`
-		Password  string `json:"password"`
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Phone     string `json:"phone"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		FirstName  string `json:"first_name"`
+		LastName   string `json:"last_name"`
+		Phone      string `json:"phone"`
+		InviteCode string `json:"invite_code"`
 	}
 	var body Request
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -60,19 +53,74 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte(body.Password), 14)
+	// Normalize phone to E.164 if provided
+	phone, err := utils.NormalizeUserPhone(body.Phone)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid phone number")
+		}
+		return
+	}
+
+	// An invitation sets the role and skips approval; invite_only mode requires one
+	now := time.Now()
+	var invitation *models.Invitation
+	if body.InviteCode != "" {
+		inv, err := utils.FindInvitation(body.InviteCode, now)
+		if err == nil && !utils.InvitationMatchesEmail(inv, body.Email) {
+			err = utils.NewValidationError(utils.ErrInvalidInvitation, "Invitation was issued for a different email address", nil)
+		}
+		if err != nil {
+			if validationErr, ok := err.(utils.ValidationError); ok {
+				utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+			} else {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid invitation")
+			}
+			return
+		}
+		invitation = &inv
+	} else if utils.RegistrationMode() == utils.RegistrationInviteOnly {
+		utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrInvitationRequired, "Registration is by invitation only", nil))
+		return
+	}
+
+	hash, err := utils.HashPassword(body.Password)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to register user")
+		return
+	}
 	user := models.User{
 		Email:     body.Email,
-		Password:  string(hash),
+		Password:  hash,
 		Role:      "parent",
+		Status:    utils.NewUserStatus(invitation != nil),
 		FirstName: body.FirstName,
 		LastName:  body.LastName,
-		Phone:     body.Phone,
+		Phone:     phone,
+	}
+	if invitation != nil {
+		// The code was emailed to this address
+		user.Role = invitation.Role
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
 	}
 
-	if result := config.DB.Create(&user); result.Error != nil {
-		// Check for duplicate email error
-		if strings.Contains(result.Error.Error(), "duplicate key value") && strings.Contains(result.Error.Error(), "email") {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if invitation != nil {
+			return utils.AcceptInvitation(tx, *invitation, user.ID, now)
+		}
+		return nil
+	})
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else if strings.Contains(err.Error(), "duplicate key value") && strings.Contains(err.Error(), "email") {
+			// Check for duplicate email error
 			utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrDuplicateEmail, "Email already registered", map[string]interface{}{
 				"email": body.Email,
 			}))
@@ -81,11 +129,25 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	utils.PublishEvent(utils.EventUserRegistered, utils.UserEventData(user))
+
+	// The account works right away, but the address has to be confirmed before it is trusted
+	if !user.EmailVerified {
+		if err := utils.SendEmailVerification(user, user.Email, now); err != nil {
+			zap.L().Warn("Failed to send verification email", zap.Error(err))
+		}
+		config.DB.Model(&user).Update("verification_sent_at", now)
+	}
+
+	message := "User registered successfully"
+	if user.Status == utils.UserStatusPending {
+		message = "User registered; the account awaits admin approval"
+	}
 
 	utils.SetSession(w, r, user.ID)
 	// CSRF token is attached to the response by SetSession
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"message": "User registered successfully",
+		"message": message,
 		"user": map[string]interface{}{
 			"id":              user.ID,
 			"email":           user.Email,
@@ -94,6 +156,8 @@ func Register(w http.ResponseWriter, r *http.Request) {
 			"last_name":       user.LastName,
 			"phone":           user.Phone,
 			"profile_picture": user.ProfilePicture,
+			"status":          user.Status,
+			"email_verified":  user.EmailVerified,
 		},
 	})
 }
@@ -119,44 +183,79 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate password presence
-	if err := utils.ValidatePassword(body.Password); err != nil {
-		if validationErr, ok := err.(utils.ValidationError); ok {
-			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
-		} else {
-			utils.RespondWithError(w, http.StatusBadRequest, "Password is required")
-		}
+	// Validate password presence; the policy only applies to new passwords
+	if !utils.IsFieldPresent(body.Password) {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Password is required", map[string]interface{}{
+			"field": "password",
+		}))
 		return
 	}
 
-	la := utils.GetLoginAttempt(body.Email)
-	if la.Count >= 5 && time.Now().Unix()-la.LastFailed < 300 {
-		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Too many failed login attempts. Please try again in 5 minutes.", map[string]interface{}{
-			"retry_after": 300 - (time.Now().Unix() - la.LastFailed),
+	// Brute-force protection, shared by all instances
+	now := time.Now()
+	ip := utils.ClientIP(r)
+	if wait := utils.LoginLockedFor(body.Email, ip, now); wait > 0 {
+		// Blocked attempts on a real account belong in that account's security log
+		var event models.SecurityEvent
+		var locked models.User
+		if err := config.DB.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(body.Email)).First(&locked).Error; err == nil {
+			event = utils.SecurityEventFor(utils.SecurityLoginFailed, utils.OutcomeBlocked, &locked, "login locked")
+		} else {
+			event = utils.SecurityEventFor(utils.SecurityLoginFailed, utils.OutcomeBlocked, nil, "login locked")
+			event.Email = body.Email
+		}
+		utils.RecordSecurityEvent(r, event)
+		retryAfter := int(wait.Round(time.Second).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError(utils.ErrLoginLocked, "Too many failed login attempts. Please try again later.", map[string]interface{}{
+			"retry_after": retryAfter,
 		}))
 		return
 	}
 
 	var user models.User
 	if err := config.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
-		utils.IncrementLoginAttempt(body.Email)
+		utils.RecordLoginFailure(nil, body.Email, ip, now)
+		event := utils.SecurityEventFor(utils.SecurityLoginFailed, utils.OutcomeFailure, nil, "unknown account")
+		event.Email = body.Email
+		utils.RecordSecurityEvent(r, event)
 		zap.L().Debug("Invalid credentials", zap.String("email", body.Email))
 		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Invalid credentials", nil))
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
-		utils.IncrementLoginAttempt(body.Email)
+		utils.RecordLoginFailure(&user, body.Email, ip, now)
+		utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLoginFailed, utils.OutcomeFailure, &user, "wrong password"))
 		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Invalid credentials", nil))
 		return
 	}
 
-	utils.ResetLoginAttempt(body.Email)
+	if utils.PasswordNeedsRehash(user.Password) {
+		if err := utils.RehashPassword(&user, body.Password); err != nil {
+			zap.L().Warn("Failed to rehash password", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	// With two-factor enabled the session stays unauthenticated until VerifyTwoFactorLogin,
+	// and the failure counters are only cleared once the code is accepted there
+	if user.TOTPEnabled {
+		utils.SetPendingTwoFactor(w, r, user.ID)
+		utils.RespondWithSuccess(w, map[string]interface{}{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+		})
+		return
+	}
+
+	utils.RecordLoginSuccess(body.Email, ip)
 	utils.SetSession(w, r, user.ID)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogin, utils.OutcomeSuccess, &user, "password"))
 	// CSRF token is attached to the response by SetSession
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"message": "Logged in successfully",
+		"message":                   "Logged in successfully",
+		"two_factor_setup_required": utils.TwoFactorRequired(user),
 		"user": map[string]interface{}{
 			"id":              user.ID,
 			"email":           user.Email,
@@ -170,6 +269,12 @@ func Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func Logout(w http.ResponseWriter, r *http.Request) {
+	if userID, ok := utils.SessionUserID(r); ok {
+		var user models.User
+		if config.DB.First(&user, userID).Error == nil {
+			utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogout, utils.OutcomeSuccess, &user, ""))
+		}
+	}
 	utils.ClearSession(w, r)
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Logged out successfully",
@@ -186,6 +291,11 @@ func RefreshSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// While impersonating, the session still belongs to the admin
+	if imp, ok := middleware.ImpersonationFromContext(r); ok {
+		userID = imp.AdminID
+	}
+
 	// Renew cookie expiry
 	utils.SetSession(w, r, userID)
 
@@ -200,6 +310,17 @@ func RefreshSession(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetCSRFToken returns the session's CSRF token, for front-ends that arrive with a session
+// but without a token (for example after a single sign-on redirect)
+func GetCSRFToken(w http.ResponseWriter, r *http.Request) {
+	session, _ := config.Store.Get(r, "session")
+	token, _ := session.Values["csrf_token"].(string)
+	w.Header().Set("X-CSRF-Token", token)
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"csrf_token": token,
+	})
+}
+
 func GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
 	if !ok {
@@ -228,7 +349,14 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 			"last_name":       user.LastName,
 			"phone":           user.Phone,
 			"profile_picture": user.ProfilePicture,
+			"sms_opt_in":      user.SMSOptIn,
+			"totp_enabled":    user.TOTPEnabled,
+			"status":          user.Status,
+			"email_verified":  user.EmailVerified,
+			"pending_email":   user.PendingEmail,
+			"permissions":     utils.RolePermissions(user.Role),
 		},
+		"impersonation": impersonationInfo(r),
 	})
 }
 
@@ -261,7 +389,16 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An impersonating admin can't change the login credentials of the account
+	if _, impersonating := middleware.ImpersonationFromContext(r); impersonating {
+		if body.Password != "" || (body.Email != "" && !strings.EqualFold(body.Email, user.Email)) {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrImpersonationForbidden, "Password and email can't be changed while impersonating a user", nil))
+			return
+		}
+	}
+
 	// Validate email if provided
+	emailChangeRequested := false
 	if body.Email != "" {
 		if err := utils.ValidateEmail(body.Email); err != nil {
 			if validationErr, ok := err.(utils.ValidationError); ok {
@@ -271,7 +408,21 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		user.Email = body.Email
+		// A new address only replaces the current one once it is confirmed (VerifyEmail)
+		if !strings.EqualFold(body.Email, user.Email) {
+			var taken int64
+			config.DB.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", body.Email, user.ID).Count(&taken)
+			if taken > 0 {
+				utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrDuplicateEmail, "Email already in use", map[string]interface{}{
+					"email": body.Email,
+				}))
+				return
+			}
+			emailChangeRequested = user.PendingEmail != body.Email
+			user.PendingEmail = body.Email
+		} else {
+			user.PendingEmail = ""
+		}
 	}
 
 	// Validate password if provided
@@ -284,21 +435,43 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		hash, _ := bcrypt.GenerateFromPassword([]byte(body.Password), 14)
-		user.Password = string(hash)
-		user.SessionVersion++ // invalidate other sessions
+	}
+
+	// Normalize phone to E.164; SMS consent belongs to a number, so a new number needs a new opt-in
+	phone, err := utils.NormalizeUserPhone(body.Phone)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid phone number")
+		}
+		return
+	}
+	if phone != user.Phone {
+		user.SMSOptIn = false
+		user.SMSOptInAt = nil
 	}
 
 	user.FirstName = body.FirstName
 	user.LastName = body.LastName
-	user.Phone = body.Phone
+	user.Phone = phone
 	if body.ProfilePicture != "" {
 		user.ProfilePicture = body.ProfilePicture
 	}
 
-	if err := config.DB.Save(&user).Error; err != nil {
-		// Check for duplicate email error
-		if strings.Contains(err.Error(), "duplicate key value") && strings.Contains(err.Error(), "email") {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if body.Password != "" {
+			if err := utils.ChangePassword(tx, &user, body.Password); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else if strings.Contains(err.Error(), "duplicate key value") && strings.Contains(err.Error(), "email") {
+			// Check for duplicate email error
 			utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrDuplicateEmail, "Email already in use", map[string]interface{}{
 				"email": body.Email,
 			}))
@@ -308,8 +481,27 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if emailChangeRequested {
+		now := time.Now()
+		if err := utils.SendEmailVerification(user, user.PendingEmail, now); err != nil {
+			zap.L().Warn("Failed to send verification email", zap.Error(err))
+		}
+		config.DB.Model(&user).Update("verification_sent_at", now)
+		if err := utils.NotifyUser(user, utils.NotificationMessage{
+			Event:   utils.NotifyAccount,
+			Subject: "Email change requested",
+			Body:    "A change of your account email to " + user.PendingEmail + " was requested. It takes effect once the new address is confirmed. If this wasn't you, change your password.",
+		}); err != nil {
+			zap.L().Warn("Failed to send email change notice", zap.Error(err))
+		}
+	}
+
 	if body.Password != "" {
-		utils.InvalidateAllUserSessions(w, r)
+		utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityPasswordChanged, utils.OutcomeSuccess, &user, "profile"))
+		utils.InvalidateAllUserSessions(w, r, user.ID)
+		if err := utils.NotifyPasswordChanged(user, time.Now()); err != nil {
+			zap.L().Warn("Failed to send password change notice", zap.Error(err))
+		}
 	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
@@ -322,10 +514,16 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 			"last_name":       user.LastName,
 			"phone":           user.Phone,
 			"profile_picture": user.ProfilePicture,
+			"sms_opt_in":      user.SMSOptIn,
+			"email_verified":  user.EmailVerified,
+			"pending_email":   user.PendingEmail,
+			"permissions":     utils.RolePermissions(user.Role),
 		},
 	})
 }
 
+// RequestPasswordReset emails a reset link. The response is the same whether or not the
+// account exists, so it can't be used to find registered addresses.
 func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
 	type Req struct {
 		Email string `json:"email"`
@@ -335,6 +533,7 @@ func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
 		return
 	}
+	body.Email = strings.TrimSpace(body.Email)
 
 	// Validate email
 	if err := utils.ValidateEmail(body.Email); err != nil {
@@ -346,34 +545,34 @@ func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	if err := config.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
-		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
-			"email": body.Email,
+	now := time.Now()
+	if result := utils.AllowPasswordResetRequest(body.Email, utils.ClientIP(r), now); !result.Allowed {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Too many password reset requests. Please try again later.", map[string]interface{}{
+			"retry_after": retryAfter,
 		}))
 		return
 	}
 
-	// Create/replace DB token for this user
-	token := generateResetToken()
-	expiry := time.Now().Add(30 * time.Minute).Unix()
-
-	// Remove existing tokens for user
-	config.DB.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{})
-
-	prt := models.PasswordResetToken{UserID: user.ID, Token: token, ExpiresAt: expiry}
-	if err := config.DB.Create(&prt).Error; err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
-		return
-	}
-
-	resetLink := "http://localhost:3000/reset-password?token=" + token
-	if err := utils.SendMail(user.Email, "Password Reset", "Reset your password: "+resetLink); err != nil {
-		zap.L().Warn("Failed to send reset email", zap.Error(err))
+	var user models.User
+	if err := config.DB.Where("LOWER(email) = LOWER(?)", body.Email).First(&user).Error; err == nil {
+		utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityPasswordResetRequested, utils.OutcomeSuccess, &user, ""))
+		link, err := utils.IssuePasswordReset(user, now)
+		if err != nil {
+			zap.L().Error("Failed to create reset token", zap.Uint("user_id", user.ID), zap.Error(err))
+		} else {
+			// Sent in the background so the response time doesn't tell whether the account exists
+			go func() {
+				if err := utils.SendPasswordReset(user, link); err != nil {
+					zap.L().Warn("Failed to send reset email", zap.Uint("user_id", user.ID), zap.Error(err))
+				}
+			}()
+		}
 	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"message": "Password reset email sent",
+		"message": "Password reset email sent if an account exists for this email",
 		"email":   body.Email,
 	})
 }
@@ -399,18 +598,16 @@ func ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var prt models.PasswordResetToken
-	if err := config.DB.Where("token = ?", body.Token).First(&prt).Error; err != nil {
-		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError("INVALID_TOKEN", "Invalid or expired token", map[string]interface{}{"token": body.Token}))
-		return
-	}
-	if time.Now().Unix() > prt.ExpiresAt {
-		// Delete expired token
-		config.DB.Delete(&prt)
-		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError("TOKEN_EXPIRED", "Token has expired", nil))
+	now := time.Now()
+	userID, err := utils.PasswordResetUser(body.Token, now)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		}
 		return
 	}
-	userID := prt.UserID
 
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
@@ -420,18 +617,35 @@ func ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte(body.Password), 14)
-	user.Password = string(hash)
-	user.SessionVersion++ // invalidate other sessions
-	if err := config.DB.Save(&user).Error; err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+	// The history, the used-up token and the new password are written together, so the
+	// token is only spent once the new password is accepted and stored
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := utils.ChangePassword(tx, &user, body.Password); err != nil {
+			return err
+		}
+		if _, err := utils.ConsumePasswordReset(tx, body.Token, now); err != nil {
+			return err
+		}
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+		}
 		return
 	}
 
-	// Delete token after successful reset
-	config.DB.Delete(&prt)
-
-	utils.InvalidateAllUserSessions(w, r)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityPasswordReset, utils.OutcomeSuccess, &user, ""))
+	utils.InvalidateAllUserSessions(w, r, user.ID)
+	// Proving control of the mailbox lifts a lockout from failed logins
+	if err := utils.UnlockLogin(user.Email); err != nil {
+		zap.L().Warn("Failed to clear login throttle", zap.Error(err))
+	}
+	if err := utils.NotifyPasswordChanged(user, now); err != nil {
+		zap.L().Warn("Failed to send password change notice", zap.Error(err))
+	}
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Password reset successful",
@@ -453,7 +667,10 @@ func LogoutAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.InvalidateAllUserSessions(w, r)
+	var user models.User
+	config.DB.First(&user, userID)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogoutAll, utils.OutcomeSuccess, &user, ""))
+	utils.InvalidateAllUserSessions(w, r, userID)
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message": "Logged out from all devices",
@@ -468,56 +685,14 @@ func UploadProfilePicture(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10MB max
-	if err != nil {
-		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid form data", nil))
-		return
-	}
-
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "File is required", nil))
-		return
-	}
-	defer file.Close()
-
-	// Only allow certain file types (basic check)
-	allowed := map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
-	if !allowed[handler.Header.Get("Content-Type")] {
-		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Unsupported file type", nil))
-		return
-	}
-
-	// Create uploads dir if not exists
-	uploadDir := "uploads"
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		_ = os.Mkdir(uploadDir, 0755)
-	}
-
-	// Generate unique filename
-	ext := ""
-	switch handler.Header.Get("Content-Type") {
-	case "image/jpeg":
-		ext = ".jpg"
-	case "image/png":
-		ext = ".png"
-	case "image/gif":
-		ext = ".gif"
-	}
-	filename := fmt.Sprintf("user_%d_%d%s", userID, time.Now().UnixNano(), ext)
-	filepath := uploadDir + "/" + filename
-
-	// Save file
-	out, err := os.Create(filepath)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save file")
-		return
-	}
-	defer out.Close()
-	_, err = io.Copy(out, file)
+	upload, err := utils.SaveUpload(w, r, "file", fmt.Sprintf("user_%d", userID), utils.ProfilePicturePolicy)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save file")
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			zap.L().Error("Failed to save profile picture", zap.Error(err))
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save file")
+		}
 		return
 	}
 
@@ -529,7 +704,7 @@ func UploadProfilePicture(w http.ResponseWriter, r *http.Request) {
 		}))
 		return
 	}
-	user.ProfilePicture = "/uploads/" + filename
+	user.ProfilePicture = "/uploads/" + upload.Filename
 	if err := config.DB.Save(&user).Error; err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update profile picture")
 		return
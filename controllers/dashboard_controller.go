@@ -11,6 +11,7 @@ import (
 // GetDashboardStats returns counts for children, reservations and open slots.
 // For admins it returns totals across the system.
 // For parents it returns counts scoped to their data.
+// Both include the caller's unread in-app notification and announcement counts.
 func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
 	if !ok {
@@ -19,7 +20,7 @@ func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user models.User
-	if err := config.DB.Select("role").First(&user, userID).Error; err != nil {
+	if err := config.DB.Select("id", "role").First(&user, userID).Error; err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user role")
 		return
 	}
@@ -28,7 +29,7 @@ func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	var totalReservations int64
 	var openSlots int64
 
-	if user.Role == "admin" {
+	if utils.IsStaffRole(user.Role) {
 		config.DB.Model(&models.Child{}).Count(&totalChildren)
 		config.DB.Model(&models.Reservation{}).Count(&totalReservations)
 		// open slots: count of slots where reservations < capacity
@@ -53,9 +54,13 @@ func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 			Count(&openSlots)
 	}
 
+	unreadAnnouncements, _ := utils.CountUnreadAnnouncements(user)
+
 	utils.RespondWithSuccess(w, map[string]interface{}{
-		"total_children":     totalChildren,
-		"total_reservations": totalReservations,
-		"open_slots":         openSlots,
+		"total_children":       totalChildren,
+		"total_reservations":   totalReservations,
+		"open_slots":           openSlots,
+		"unread_notifications": utils.CountUnreadNotifications(userID),
+		"unread_announcements": unreadAnnouncements,
 	})
 }
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// VerifyEmail confirms an address with the token from a verification email. For a
+// pending email change, this is when the new address replaces the old one.
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+
+	now := time.Now()
+	token, err := utils.VerifySignedToken(body.Token, utils.PurposeEmailVerification, now)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		}
+		return
+	}
+	invalid := utils.NewValidationError(utils.ErrInvalidToken, "Invalid or expired token", nil)
+
+	var user models.User
+	if err := config.DB.First(&user, token.UserID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, invalid)
+		return
+	}
+
+	email := user.Email
+	updates := map[string]interface{}{}
+	switch {
+	case user.PendingEmail != "" && token.Email == strings.ToLower(user.PendingEmail):
+		var taken int64
+		config.DB.Model(&models.User{}).Where("LOWER(email) = ? AND id <> ?", token.Email, user.ID).Count(&taken)
+		if taken > 0 {
+			utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrDuplicateEmail, "Email already in use", map[string]interface{}{
+				"email": user.PendingEmail,
+			}))
+			return
+		}
+		email = user.PendingEmail
+		updates["email"] = email
+		updates["pending_email"] = ""
+		updates["email_verified"] = true
+		updates["email_verified_at"] = now
+	case token.Email == strings.ToLower(user.Email):
+		if !user.EmailVerified {
+			updates["email_verified"] = true
+			updates["email_verified_at"] = now
+		}
+	default:
+		// The token was for an address the account no longer uses or requests
+		utils.RespondWithValidationError(w, http.StatusBadRequest, invalid)
+		return
+	}
+
+	if len(updates) > 0 {
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			zap.L().Error("Failed to verify email", zap.Error(err))
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+			return
+		}
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":        "Email verified",
+		"email":          email,
+		"email_verified": true,
+	})
+}
+
+// ResendEmailVerification sends a new verification email for the pending address, or
+// for the current one if it isn't verified yet (at most once a minute)
+func ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	email := user.PendingEmail
+	if email == "" {
+		if user.EmailVerified {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Email is already verified", nil))
+			return
+		}
+		email = user.Email
+	}
+
+	now := time.Now()
+	if !utils.CanResendVerification(user, now) {
+		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Please wait before requesting another verification email", map[string]interface{}{
+			"retry_after": 60 - int(now.Sub(*user.VerificationSentAt).Seconds()),
+		}))
+		return
+	}
+	if err := utils.SendEmailVerification(user, email, now); err != nil {
+		zap.L().Warn("Failed to send verification email", zap.Error(err))
+	}
+	config.DB.Model(&user).Update("verification_sent_at", now)
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Verification email sent",
+		"email":   email,
+	})
+}
+
+// CancelEmailChange withdraws a pending email change
+func CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.PendingEmail != "" {
+		if err := config.DB.Model(&user).Update("pending_email", "").Error; err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to cancel email change")
+			return
+		}
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Email change cancelled",
+		"email":   user.Email,
+	})
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// feedSize is the number of most recent announcements included in feeds
+const feedSize = 50
+
+// AnnouncementAtomFeed serves the public announcements as an Atom feed
+func AnnouncementAtomFeed(w http.ResponseWriter, r *http.Request) {
+	serveAnnouncementFeed(w, r, "application/atom+xml; charset=utf-8", utils.BuildAtomFeed)
+}
+
+// AnnouncementRSSFeed serves the public announcements as an RSS 2.0 feed
+func AnnouncementRSSFeed(w http.ResponseWriter, r *http.Request) {
+	serveAnnouncementFeed(w, r, "application/rss+xml; charset=utf-8", utils.BuildRSSFeed)
+}
+
+// serveAnnouncementFeed renders the latest live announcements addressed to everyone.
+// Drafts, scheduled, expired and targeted announcements are never included. Responses
+// carry ETag and Last-Modified, so polling readers get 304 Not Modified.
+func serveAnnouncementFeed(w http.ResponseWriter, r *http.Request, contentType string, build func(utils.FeedInfo, []models.Announcement) ([]byte, error)) {
+	var announcements []models.Announcement
+	err := utils.AnnouncementsVisibleTo(config.DB, nil, time.Now()).
+		Preload("Author").
+		Order("COALESCE(publish_at, created_at) DESC").
+		Limit(feedSize).
+		Find(&announcements).Error
+	if err != nil {
+		zap.L().Error("Failed to load announcements for feed", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve announcements")
+		return
+	}
+
+	base := utils.PublicURL(r)
+	info := utils.FeedInfo{
+		Title:   utils.FeedTitle(),
+		BaseURL: base,
+		SelfURL: base + r.URL.Path,
+	}
+	body, err := build(info, announcements)
+	if err != nil {
+		zap.L().Error("Failed to render announcement feed", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to render feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", utils.FeedETag(body))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	// ServeContent answers If-None-Match / If-Modified-Since with 304 and sets Last-Modified
+	http.ServeContent(w, r, "", utils.FeedLastModified(announcements), bytes.NewReader(body))
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// impersonationInfo describes the impersonation a request runs under, or nil
+func impersonationInfo(r *http.Request) map[string]interface{} {
+	imp, ok := middleware.ImpersonationFromContext(r)
+	if !ok {
+		return nil
+	}
+	var admin models.User
+	config.DB.Select("id", "email").First(&admin, imp.AdminID)
+	return map[string]interface{}{
+		"id":          imp.ID,
+		"admin_id":    imp.AdminID,
+		"admin_email": admin.Email,
+		"user_id":     imp.UserID,
+		"started_at":  imp.StartedAt,
+		"expires_at":  imp.ExpiresAt,
+	}
+}
+
+// StartImpersonation lets an admin act as a parent account from their current session.
+// Every request until it ends is served as the parent and recorded in the audit log.
+func StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	target, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if err := utils.ValidateImpersonation(admin, target, body.Reason); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			code := http.StatusBadRequest
+			if validationErr.Code == utils.ErrForbidden {
+				code = http.StatusForbidden
+			}
+			utils.RespondWithValidationError(w, code, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid impersonation request")
+		}
+		return
+	}
+
+	imp, err := utils.StartImpersonation(w, r, admin, target, body.Reason, time.Now())
+	if err != nil {
+		zap.L().Error("Failed to start impersonation", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start impersonation")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":       "Impersonation started. Requests are now made as " + target.Email,
+		"impersonation": imp,
+		"user": map[string]interface{}{
+			"id":         target.ID,
+			"email":      target.Email,
+			"first_name": target.FirstName,
+			"last_name":  target.LastName,
+		},
+	})
+}
+
+// GetImpersonation reports whether the session is impersonating a user
+func GetImpersonation(w http.ResponseWriter, r *http.Request) {
+	info := impersonationInfo(r)
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"impersonating": info != nil,
+		"impersonation": info,
+	})
+}
+
+// StopImpersonation ends the session's impersonation; the session is the admin's again
+func StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	imp, ok := middleware.ImpersonationFromContext(r)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Not impersonating a user", nil))
+		return
+	}
+	utils.EndImpersonation(w, r)
+	zap.L().Info("Impersonation ended", zap.Uint("admin_id", imp.AdminID), zap.Uint("user_id", imp.UserID), zap.Uint("impersonation_id", imp.ID))
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Impersonation ended",
+	})
+}
+
+// ListImpersonations returns impersonations, newest first, with the number of requests
+// made in each. Filters: admin_id, user_id, active=true.
+func ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	query := config.DB.Table("impersonations i")
+	for _, filter := range []string{"admin_id", "user_id"} {
+		if value := r.URL.Query().Get(filter); value != "" {
+			id, err := utils.ParseUint(value)
+			if err != nil {
+				utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid "+filter, map[string]interface{}{
+					filter: value,
+				}))
+				return
+			}
+			query = query.Where("i."+filter+" = ?", id)
+		}
+	}
+	if r.URL.Query().Get("active") == "true" {
+		query = query.Where("i.ended_at IS NULL AND i.expires_at > ?", time.Now())
+	}
+
+	var total int64
+	query.Count(&total)
+
+	type row struct {
+		models.Impersonation
+		AdminEmail string `json:"admin_email"`
+		UserEmail  string `json:"user_email"`
+		Requests   int64  `json:"requests"`
+	}
+	rows := []row{}
+	if err := query.
+		Select("i.*, a.email AS admin_email, u.email AS user_email, (SELECT COUNT(*) FROM impersonation_requests ir WHERE ir.impersonation_id = i.id) AS requests").
+		Joins("LEFT JOIN users a ON a.id = i.admin_id").
+		Joins("LEFT JOIN users u ON u.id = i.user_id").
+		Order("i.started_at DESC").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Scan(&rows).Error; err != nil {
+		zap.L().Error("Failed to list impersonations", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve impersonations")
+		return
+	}
+
+	utils.RespondWithPaginatedData(w, rows, page, perPage, int(total))
+}
+
+// ListImpersonationRequests returns the audit trail of one impersonation, oldest first
+func ListImpersonationRequests(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	impID, err := utils.ParseUint(id)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid impersonation ID", map[string]interface{}{
+			"impersonation_id": id,
+		}))
+		return
+	}
+	var imp models.Impersonation
+	if err := config.DB.First(&imp, impID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Impersonation not found", map[string]interface{}{
+			"impersonation_id": impID,
+		}))
+		return
+	}
+	requests := []models.ImpersonationRequest{}
+	if err := config.DB.Where("impersonation_id = ?", imp.ID).Order("id").Find(&requests).Error; err != nil {
+		zap.L().Error("Failed to list impersonated requests", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve requests")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"impersonation": imp,
+		"requests":      requests,
+	})
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func invitationResponse(inv models.Invitation, now time.Time) map[string]interface{} {
+	status := "pending"
+	switch {
+	case inv.AcceptedAt != nil:
+		status = "accepted"
+	case inv.RevokedAt != nil:
+		status = "revoked"
+	case !now.Before(inv.ExpiresAt):
+		status = "expired"
+	}
+	return map[string]interface{}{
+		"id":             inv.ID,
+		"email":          inv.Email,
+		"role":           inv.Role,
+		"status":         status,
+		"invited_by_id":  inv.InvitedByID,
+		"expires_at":     inv.ExpiresAt,
+		"accepted_at":    inv.AcceptedAt,
+		"accepted_by_id": inv.AcceptedByID,
+		"revoked_at":     inv.RevokedAt,
+		"created_at":     inv.CreatedAt,
+	}
+}
+
+// GetRegistrationInfo tells the registration and login pages which registration mode is
+// active, whether login links are enabled and what new passwords must satisfy
+func GetRegistrationInfo(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"mode":             utils.RegistrationMode(),
+		"magic_link_login": utils.MagicLinkEnabled(),
+		"password_policy":  utils.CurrentPasswordPolicy(),
+	})
+}
+
+// GetInvitation returns the email and role of a usable invitation code, so the
+// registration page can prefill the form
+func GetInvitation(w http.ResponseWriter, r *http.Request) {
+	inv, err := utils.FindInvitation(mux.Vars(r)["code"], time.Now())
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusNotFound, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusNotFound, "Invitation not found")
+		}
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"email":      inv.Email,
+		"role":       inv.Role,
+		"expires_at": inv.ExpiresAt,
+	})
+}
+
+// ListInvitations returns all invitations, newest first (pending ones only with pending=true)
+func ListInvitations(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	query := config.DB.Model(&models.Invitation{})
+	if r.URL.Query().Get("pending") == "true" {
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	}
+	var invitations []models.Invitation
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		zap.L().Error("Failed to get invitations", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve invitations")
+		return
+	}
+	data := []map[string]interface{}{}
+	for _, inv := range invitations {
+		data = append(data, invitationResponse(inv, now))
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"invitations":       data,
+		"registration_mode": utils.RegistrationMode(),
+	})
+}
+
+// CreateInvitation issues a single-use invitation for an email and role and emails the
+// registration link. Earlier unused invitations for the address are revoked. The code
+// is returned only in this response.
+func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Email         string `json:"email"`
+		Role          string `json:"role"`
+		ExpiresInDays int    `json:"expires_in_days"`
+		SendEmail     *bool  `json:"send_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+
+	body.Email = strings.TrimSpace(body.Email)
+	if err := utils.ValidateEmail(body.Email); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid email format")
+		}
+		return
+	}
+	if body.Role == "" {
+		body.Role = "parent"
+	}
+	if err := utils.ValidateRole(body.Role); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid role")
+		}
+		return
+	}
+	if !utils.CanGrantRole(admin.Role, body.Role) {
+		respondRoleNotGrantable(w, body.Role)
+		return
+	}
+	if body.ExpiresInDays == 0 {
+		body.ExpiresInDays = utils.DefaultInvitationDays
+	}
+	if body.ExpiresInDays < 1 || body.ExpiresInDays > utils.MaxInvitationDays {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "expires_in_days must be between 1 and 90", map[string]interface{}{
+			"field": "expires_in_days",
+			"value": body.ExpiresInDays,
+		}))
+		return
+	}
+
+	var existing int64
+	config.DB.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", body.Email).Count(&existing)
+	if existing > 0 {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrDuplicateEmail, "A user with this email already exists", map[string]interface{}{
+			"email": body.Email,
+		}))
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(&models.Invitation{}).
+		Where("LOWER(email) = LOWER(?) AND accepted_at IS NULL AND revoked_at IS NULL", body.Email).
+		Update("revoked_at", now)
+
+	code := utils.GenerateInvitationCode()
+	inv := models.Invitation{
+		Email:       body.Email,
+		Role:        body.Role,
+		CodeHash:    utils.HashToken(code),
+		InvitedByID: admin.ID,
+		ExpiresAt:   now.AddDate(0, 0, body.ExpiresInDays),
+	}
+	if err := config.DB.Create(&inv).Error; err != nil {
+		zap.L().Error("Failed to create invitation", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+	zap.L().Info("Invitation created", zap.Uint("invitation_id", inv.ID), zap.Uint("invited_by", admin.ID), zap.String("role", inv.Role))
+
+	if body.SendEmail == nil || *body.SendEmail {
+		if err := utils.SendInvitation(inv, code); err != nil {
+			zap.L().Warn("Failed to send invitation email", zap.Error(err))
+		}
+	}
+
+	resp := invitationResponse(inv, now)
+	resp["code"] = code
+	resp["link"] = utils.InvitationLink(code)
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    "Invitation created",
+		"invitation": resp,
+	})
+}
+
+// RevokeInvitation withdraws an unused invitation
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid invitation ID", nil))
+		return
+	}
+	var inv models.Invitation
+	if err := config.DB.First(&inv, invitationID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Invitation not found", map[string]interface{}{
+			"invitation_id": invitationID,
+		}))
+		return
+	}
+	if inv.AcceptedAt != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invitation was already accepted", nil))
+		return
+	}
+	now := time.Now()
+	if inv.RevokedAt == nil {
+		inv.RevokedAt = &now
+		if err := config.DB.Model(&inv).Update("revoked_at", now).Error; err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke invitation")
+			return
+		}
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    "Invitation revoked",
+		"invitation": invitationResponse(inv, now),
+	})
+}
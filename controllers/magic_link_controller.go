@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+
+	"go.uber.org/zap"
+)
+
+// respondMagicLinkDisabled answers requests while magic-link login is switched off
+func respondMagicLinkDisabled(w http.ResponseWriter) {
+	utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrMagicLinkDisabled, "Login links are not enabled", nil))
+}
+
+// RequestMagicLink emails a single-use login link to a parent account. The response is
+// the same whether or not the account exists.
+func RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	body.Email = strings.TrimSpace(body.Email)
+	if err := utils.ValidateEmail(body.Email); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid email format")
+		}
+		return
+	}
+	if !utils.MagicLinkEnabled() {
+		respondMagicLinkDisabled(w)
+		return
+	}
+
+	now := time.Now()
+	ip := utils.ClientIP(r)
+	if result := utils.AllowMagicLinkRequest(body.Email, ip, now); !result.Allowed {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Too many login link requests. Please try again later.", map[string]interface{}{
+			"retry_after": retryAfter,
+		}))
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("LOWER(email) = LOWER(?)", body.Email).First(&user).Error; err == nil && utils.MagicLinkAllowed(user) {
+		link, err := utils.IssueMagicLink(user, ip, now)
+		if err != nil {
+			zap.L().Error("Failed to issue magic link", zap.Uint("user_id", user.ID), zap.Error(err))
+		} else {
+			// Sent in the background so the response time doesn't tell whether the account exists
+			go func() {
+				if err := utils.SendMagicLink(user, link); err != nil {
+					zap.L().Warn("Failed to send magic link", zap.Uint("user_id", user.ID), zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "If an account exists for this email, a login link is on its way",
+	})
+}
+
+// LoginWithMagicLink redeems a login link and starts the session (or the two-factor step
+// for accounts with two-factor enabled). Following the link also confirms the email.
+func LoginWithMagicLink(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if !utils.MagicLinkEnabled() {
+		respondMagicLinkDisabled(w)
+		return
+	}
+
+	now := time.Now()
+	user, err := utils.ConsumeMagicLink(body.Token, now)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired login link")
+		}
+		return
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+		config.DB.Model(&user).Updates(map[string]interface{}{"email_verified": true, "email_verified_at": now})
+	}
+	zap.L().Info("Magic link login", zap.Uint("user_id", user.ID))
+
+	// The lockout is only cleared once the session exists; with two-factor enabled that
+	// happens in VerifyTwoFactorLogin
+	if user.TOTPEnabled {
+		utils.SetPendingTwoFactor(w, r, user.ID)
+		utils.RespondWithSuccess(w, map[string]interface{}{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+		})
+		return
+	}
+
+	utils.RecordLoginSuccess(user.Email, utils.ClientIP(r))
+	utils.SetSession(w, r, user.ID)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogin, utils.OutcomeSuccess, &user, "magic link"))
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Logged in successfully",
+		"user": map[string]interface{}{
+			"id":              user.ID,
+			"email":           user.Email,
+			"role":            user.Role,
+			"first_name":      user.FirstName,
+			"last_name":       user.LastName,
+			"phone":           user.Phone,
+			"profile_picture": user.ProfilePicture,
+		},
+	})
+}
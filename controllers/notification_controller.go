@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func notificationPreferenceResponse(pref models.NotificationPreference) map[string]interface{} {
+	events := map[string]interface{}{}
+	for _, event := range utils.NotificationEvents {
+		channels := map[string]interface{}{}
+		for _, channel := range utils.NotificationChannels {
+			channels[channel] = utils.NotificationChannelEnabled(pref, event, channel)
+		}
+		events[event] = channels
+	}
+	return map[string]interface{}{
+		"events": events,
+		"quiet_hours": map[string]interface{}{
+			"start":    pref.QuietHoursStart,
+			"end":      pref.QuietHoursEnd,
+			"timezone": pref.Timezone,
+		},
+	}
+}
+
+// GetNotificationPreferences returns the current user's notification preferences
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	pref, err := utils.GetNotificationPreference(userID)
+	if err != nil {
+		zap.L().Error("Failed to get notification preferences", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve notification preferences")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"preferences": notificationPreferenceResponse(pref),
+	})
+}
+
+// UpdateNotificationPreferences changes event/channel flags and quiet hours.
+// Only the events, channels and quiet-hours fields present in the body are changed.
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	var body struct {
+		Events     map[string]map[string]bool `json:"events"`
+		QuietHours *struct {
+			Start    *string `json:"start"`
+			End      *string `json:"end"`
+			Timezone *string `json:"timezone"`
+		} `json:"quiet_hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+
+	pref, err := utils.GetNotificationPreference(userID)
+	if err != nil {
+		zap.L().Error("Failed to get notification preferences", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve notification preferences")
+		return
+	}
+
+	for event, channels := range body.Events {
+		for channel, enabled := range channels {
+			flag := utils.NotificationPreferenceFlag(&pref, event, channel)
+			if flag == nil {
+				utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Unknown notification event or channel", map[string]interface{}{
+					"event":          event,
+					"channel":        channel,
+					"valid_events":   utils.NotificationEvents,
+					"valid_channels": utils.NotificationChannels,
+				}))
+				return
+			}
+			*flag = enabled
+		}
+	}
+
+	if body.QuietHours != nil {
+		if body.QuietHours.Start != nil {
+			pref.QuietHoursStart = *body.QuietHours.Start
+		}
+		if body.QuietHours.End != nil {
+			pref.QuietHoursEnd = *body.QuietHours.End
+		}
+		if body.QuietHours.Timezone != nil {
+			pref.Timezone = *body.QuietHours.Timezone
+		}
+	}
+	if err := utils.ValidateQuietHours(pref.QuietHoursStart, pref.QuietHoursEnd, pref.Timezone); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid quiet hours")
+		}
+		return
+	}
+
+	if err := config.DB.Save(&pref).Error; err != nil {
+		zap.L().Error("Failed to save notification preferences", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update notification preferences")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":     "Notification preferences updated successfully",
+		"preferences": notificationPreferenceResponse(pref),
+	})
+}
+
+func notificationResponse(n models.Notification) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         n.ID,
+		"event":      n.Event,
+		"title":      n.Title,
+		"body":       n.Body,
+		"read":       n.ReadAt != nil,
+		"read_at":    n.ReadAt,
+		"created_at": n.CreatedAt,
+	}
+}
+
+// ListNotifications returns the current user's inbox, newest first (paginated).
+// Pass unread=true to only list unread notifications.
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	// Parse pagination parameters
+	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if r.URL.Query().Get("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var notifications []models.Notification
+	offset := (page - 1) * perPage
+	if err := query.Order("created_at DESC").Offset(offset).Limit(perPage).Find(&notifications).Error; err != nil {
+		zap.L().Error("Failed to get notifications", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve notifications")
+		return
+	}
+
+	var data []map[string]interface{}
+	for _, n := range notifications {
+		data = append(data, notificationResponse(n))
+	}
+	if data == nil {
+		data = []map[string]interface{}{}
+	}
+
+	utils.RespondWithPaginatedData(w, data, page, perPage, int(total))
+}
+
+// GetUnreadNotificationCount returns the number of unread notifications for the current user
+func GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"unread_count": utils.CountUnreadNotifications(userID),
+	})
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	setNotificationRead(w, r, true)
+}
+
+// MarkNotificationUnread marks one of the current user's notifications as unread again
+func MarkNotificationUnread(w http.ResponseWriter, r *http.Request) {
+	setNotificationRead(w, r, false)
+}
+
+func setNotificationRead(w http.ResponseWriter, r *http.Request, read bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	idStr := mux.Vars(r)["id"]
+	notificationID, err := utils.ParseUint(idStr)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid notification ID", map[string]interface{}{
+			"notification_id": idStr,
+		}))
+		return
+	}
+
+	var n models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", notificationID, userID).First(&n).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Notification not found", map[string]interface{}{
+			"notification_id": notificationID,
+		}))
+		return
+	}
+
+	if read {
+		if n.ReadAt == nil {
+			now := time.Now()
+			n.ReadAt = &now
+		}
+	} else {
+		n.ReadAt = nil
+	}
+	if err := config.DB.Model(&n).Update("read_at", n.ReadAt).Error; err != nil {
+		zap.L().Error("Failed to update notification", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":      "Notification updated successfully",
+		"notification": notificationResponse(n),
+	})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	result := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	if result.Error != nil {
+		zap.L().Error("Failed to mark notifications read", zap.Error(result.Error))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update notifications")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "All notifications marked as read",
+		"updated": result.RowsAffected,
+	})
+}
+
+// SendAdminMessage lets an admin message a single user, or every user when broadcast is set.
+// Messages go through the notification dispatcher like any other notification; urgent
+// messages skip quiet hours and also go out by SMS.
+func SendAdminMessage(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID    uint   `json:"user_id"`
+		Broadcast bool   `json:"broadcast"`
+		Title     string `json:"title"`
+		Body      string `json:"body"`
+		Urgent    bool   `json:"urgent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if !utils.IsFieldPresent(body.Title) {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Title is required", nil))
+		return
+	}
+	if !utils.IsFieldPresent(body.Body) {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Body is required", nil))
+		return
+	}
+
+	msg := utils.NotificationMessage{Event: utils.NotifyMessages, Subject: body.Title, Body: body.Body, Urgent: body.Urgent}
+
+	if body.Broadcast {
+		go utils.NotifyAllUsers(msg)
+		utils.RespondWithSuccess(w, map[string]interface{}{
+			"message": "Message queued for all users",
+		})
+		return
+	}
+
+	if body.UserID == 0 {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "User ID is required unless broadcast is set", map[string]interface{}{
+			"field": "user_id",
+		}))
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, body.UserID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
+			"user_id": body.UserID,
+		}))
+		return
+	}
+	if err := utils.NotifyUser(user, msg); err != nil {
+		zap.L().Warn("Admin message delivery failed", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Message sent successfully",
+		"user_id": user.ID,
+	})
+}
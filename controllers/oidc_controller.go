@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"os"
+	"reservio/config"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gorilla/sessions"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// oidcFlowTTL is how long a started single sign-on login may take
+const oidcFlowTTL = 10 * time.Minute
+
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// oidcFlowSession keeps state, nonce and PKCE verifier between the redirect to the
+// provider and the callback. It is a separate SameSite=Lax cookie, because the callback
+// is a cross-site navigation that wouldn't carry the Strict session cookie.
+func oidcFlowSession(r *http.Request) *sessions.Session {
+	session, _ := config.Store.Get(r, "oidc")
+	session.Options.MaxAge = int(oidcFlowTTL.Seconds())
+	session.Options.HttpOnly = true
+	session.Options.SameSite = http.SameSiteLaxMode
+	session.Options.Secure = os.Getenv("TEST_MODE") != "1" && strings.ToLower(os.Getenv("ENVIRONMENT")) == "production"
+	return session
+}
+
+// ssoRedirect sends the browser back to the front-end, with params describing the outcome
+func ssoRedirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	cfg, _ := utils.LoadOIDCConfig()
+	target, err := url.Parse(cfg.PostLoginURL)
+	if err != nil {
+		target = &url.URL{Path: "/"}
+	}
+	if len(params) > 0 {
+		q := target.Query()
+		for k, v := range params {
+			q[k] = v
+		}
+		target.RawQuery = q.Encode()
+	}
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func ssoFailed(w http.ResponseWriter, r *http.Request, code string, reason string, err error) {
+	zap.L().Warn("Single sign-on failed", zap.String("code", code), zap.String("reason", reason), zap.Error(err))
+	ssoRedirect(w, r, url.Values{"sso_error": {code}})
+}
+
+// OIDCLogin starts a single sign-on login: it redirects to the identity provider using
+// the authorization code flow with PKCE
+func OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if _, enabled := utils.LoadOIDCConfig(); !enabled {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Single sign-on is not enabled", nil))
+		return
+	}
+	client, err := utils.GetOIDCClient(r.Context())
+	if err != nil {
+		zap.L().Error("OIDC provider unavailable", zap.Error(err))
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Single sign-on is currently unavailable")
+		return
+	}
+
+	state, nonce, verifier := randomString(), randomString(), oauth2.GenerateVerifier()
+	flow := oidcFlowSession(r)
+	flow.Values["state"] = state
+	flow.Values["nonce"] = nonce
+	flow.Values["verifier"] = verifier
+	flow.Values["expiry"] = time.Now().Add(oidcFlowTTL).Unix()
+	if err := flow.Save(r, w); err != nil {
+		zap.L().Error("Failed to save OIDC flow state", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start single sign-on")
+		return
+	}
+
+	http.Redirect(w, r, client.OAuth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), http.StatusFound)
+}
+
+// OIDCCallback finishes the login: it checks state, exchanges the code (with the PKCE
+// verifier), validates the ID token and nonce, and maps the claims to an account.
+// The browser is redirected to OIDC_POST_LOGIN_URL, with sso_error=<code> on failure or
+// two_factor_required=true when the account still needs its TOTP code.
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if _, enabled := utils.LoadOIDCConfig(); !enabled {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Single sign-on is not enabled", nil))
+		return
+	}
+
+	flow := oidcFlowSession(r)
+	state, _ := flow.Values["state"].(string)
+	nonce, _ := flow.Values["nonce"].(string)
+	verifier, _ := flow.Values["verifier"].(string)
+	expiry, _ := flow.Values["expiry"].(int64)
+	// The flow state is single use
+	flow.Options.MaxAge = -1
+	_ = flow.Save(r, w)
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		ssoFailed(w, r, utils.ErrSSOFailed, "provider returned "+providerErr, nil)
+		return
+	}
+	if state == "" || time.Now().Unix() > expiry || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		ssoFailed(w, r, utils.ErrSSOFailed, "invalid or expired state", nil)
+		return
+	}
+
+	client, err := utils.GetOIDCClient(r.Context())
+	if err != nil {
+		ssoFailed(w, r, utils.ErrSSOFailed, "provider unavailable", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	token, err := client.OAuth2.Exchange(ctx, query.Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		ssoFailed(w, r, utils.ErrSSOFailed, "code exchange failed", err)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		ssoFailed(w, r, utils.ErrSSOFailed, "no id_token in token response", nil)
+		return
+	}
+	idToken, err := client.Verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		ssoFailed(w, r, utils.ErrSSOFailed, "invalid id_token", err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		ssoFailed(w, r, utils.ErrSSOFailed, "nonce mismatch", nil)
+		return
+	}
+	claims, err := utils.ParseOIDCClaims(idToken, client.Config.GroupsClaim)
+	if err != nil {
+		ssoFailed(w, r, utils.ErrSSOFailed, "invalid claims", err)
+		return
+	}
+
+	user, created, err := utils.ResolveOIDCUser(client.Config, claims)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			ssoFailed(w, r, validationErr.Code, validationErr.Message, nil)
+		} else {
+			ssoFailed(w, r, utils.ErrSSOFailed, "account lookup failed", err)
+		}
+		return
+	}
+	if created {
+		utils.PublishEvent(utils.EventUserRegistered, utils.UserEventData(user))
+	}
+	zap.L().Info("Single sign-on login", zap.Uint("user_id", user.ID), zap.Bool("created", created))
+
+	if user.TOTPEnabled {
+		utils.SetPendingTwoFactor(w, r, user.ID)
+		ssoRedirect(w, r, url.Values{"two_factor_required": {"true"}})
+		return
+	}
+	utils.SetSession(w, r, user.ID)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogin, utils.OutcomeSuccess, &user, "single sign-on"))
+	ssoRedirect(w, r, nil)
+}
@@ -39,6 +39,20 @@ func MakeReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Policy: accounts awaiting approval can't reserve, and parents may have to verify
+	// their email first
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err == nil {
+		if user.Status == utils.UserStatusPending {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrAccountPending, "Your account is awaiting approval by an administrator", nil))
+			return
+		}
+		if utils.EmailVerificationRequired(user) {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrEmailNotVerified, "Please verify your email address before making reservations", nil))
+			return
+		}
+	}
+
 	// Business logic validation
 	validator := utils.NewBusinessLogicValidator()
 
@@ -93,6 +107,7 @@ func MakeReservation(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create reservation")
 		return
 	}
+	utils.PublishEvent(utils.EventReservationCreated, utils.ReservationEventData(reservation))
 
 	// Get slot availability for response
 	availability, _ := validator.GetSlotAvailability(body.SlotID)
@@ -217,11 +232,20 @@ func CancelReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := config.DB.Delete(&models.Reservation{}, reservationID).Error; err != nil {
+	var reservation models.Reservation
+	if err := config.DB.First(&reservation, reservationID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrReservationNotFound, "Reservation not found", map[string]interface{}{
+			"reservation_id": reservationID,
+		}))
+		return
+	}
+
+	if err := config.DB.Delete(&reservation).Error; err != nil {
 		zap.L().Error("Failed to cancel reservation", zap.Error(err))
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to cancel reservation")
 		return
 	}
+	utils.PublishEvent(utils.EventReservationCancelled, utils.ReservationEventData(reservation))
 
 	utils.RespondWithSuccess(w, map[string]interface{}{
 		"message":        "Reservation cancelled successfully",
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// respondRoleNotGrantable rejects handing out a role (or permissions) beyond the actor's own
+func respondRoleNotGrantable(w http.ResponseWriter, role string) {
+	utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrForbidden, "You can't grant permissions your own role doesn't have", map[string]interface{}{
+		"role": role,
+	}))
+}
+
+// ListRoles returns the built-in and custom roles with their permissions, and every
+// permission a custom role can be given
+func ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := utils.ListRoles()
+	if err != nil {
+		zap.L().Error("Failed to list roles", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve roles")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"roles":       roles,
+		"permissions": utils.Permissions,
+	})
+}
+
+type roleInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// decodeRoleInput reads and validates a role body; it writes the error response itself
+func decodeRoleInput(w http.ResponseWriter, r *http.Request, actor models.User) (roleInput, []string, bool) {
+	var body roleInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return body, nil, false
+	}
+	body.Description = strings.TrimSpace(body.Description)
+	if len(body.Description) > 255 {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Description must be at most 255 characters", map[string]interface{}{
+			"field": "description",
+		}))
+		return body, nil, false
+	}
+	perms, err := utils.NormalizePermissions(body.Permissions)
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid permissions")
+		}
+		return body, nil, false
+	}
+	if !utils.CanGrantPermissions(actor.Role, perms) {
+		respondRoleNotGrantable(w, body.Name)
+		return body, nil, false
+	}
+	return body, perms, true
+}
+
+// CreateRole defines a custom role
+func CreateRole(w http.ResponseWriter, r *http.Request) {
+	actor, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	body, perms, ok := decodeRoleInput(w, r, actor)
+	if !ok {
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if err := utils.ValidateRoleName(body.Name); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid role name")
+		}
+		return
+	}
+	if utils.RoleExists(body.Name) {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidRole, "A role with this name already exists", map[string]interface{}{
+			"name": body.Name,
+		}))
+		return
+	}
+
+	role := models.Role{Name: body.Name, Description: body.Description, Permissions: strings.Join(perms, ",")}
+	if err := config.DB.Create(&role).Error; err != nil {
+		zap.L().Error("Failed to create role", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+	zap.L().Info("Role created", zap.String("role", role.Name), zap.Uint("by", actor.ID))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Role created successfully",
+		"role":    utils.CustomRoleInfo(role),
+	})
+}
+
+// findCustomRoleParam loads the custom role named in the URL; built-in roles can't be changed
+func findCustomRoleParam(w http.ResponseWriter, r *http.Request) (models.Role, bool) {
+	name := mux.Vars(r)["name"]
+	var role models.Role
+	if utils.IsBuiltInRole(name) {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidRole, "Built-in roles can't be changed", map[string]interface{}{
+			"name": name,
+		}))
+		return role, false
+	}
+	if err := config.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Role not found", map[string]interface{}{
+			"name": name,
+		}))
+		return role, false
+	}
+	return role, true
+}
+
+// UpdateRole changes the description and permissions of a custom role. Users with the
+// role get the new permissions on their next request.
+func UpdateRole(w http.ResponseWriter, r *http.Request) {
+	actor, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	role, ok := findCustomRoleParam(w, r)
+	if !ok {
+		return
+	}
+	if !utils.CanGrantRole(actor.Role, role.Name) {
+		respondRoleNotGrantable(w, role.Name)
+		return
+	}
+	body, perms, ok := decodeRoleInput(w, r, actor)
+	if !ok {
+		return
+	}
+
+	role.Description = body.Description
+	role.Permissions = strings.Join(perms, ",")
+	if err := config.DB.Save(&role).Error; err != nil {
+		zap.L().Error("Failed to update role", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+	zap.L().Info("Role updated", zap.String("role", role.Name), zap.Uint("by", actor.ID))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Role updated successfully",
+		"role":    utils.CustomRoleInfo(role),
+	})
+}
+
+// DeleteRole removes a custom role that no user or pending invitation has
+func DeleteRole(w http.ResponseWriter, r *http.Request) {
+	actor, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	role, ok := findCustomRoleParam(w, r)
+	if !ok {
+		return
+	}
+	if !utils.CanGrantRole(actor.Role, role.Name) {
+		respondRoleNotGrantable(w, role.Name)
+		return
+	}
+
+	var users, invitations int64
+	config.DB.Model(&models.User{}).Where("role = ?", role.Name).Count(&users)
+	config.DB.Model(&models.Invitation{}).Where("role = ? AND accepted_at IS NULL AND revoked_at IS NULL", role.Name).Count(&invitations)
+	if users > 0 || invitations > 0 {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidRole, "Role is still assigned; change those users' role first", map[string]interface{}{
+			"name":                role.Name,
+			"users":               users,
+			"pending_invitations": invitations,
+		}))
+		return
+	}
+
+	if err := config.DB.Delete(&role).Error; err != nil {
+		zap.L().Error("Failed to delete role", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete role")
+		return
+	}
+	zap.L().Info("Role deleted", zap.String("role", role.Name), zap.Uint("by", actor.ID))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Role deleted successfully",
+	})
+}
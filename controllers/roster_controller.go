@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type rosterEntry struct {
+	ReservationID   uint       `json:"reservation_id"`
+	ChildID         uint       `json:"child_id"`
+	ChildName       string     `json:"child_name"`
+	ChildAge        int        `json:"child_age"`
+	ParentID        uint       `json:"parent_id"`
+	ParentFirstName string     `json:"parent_first_name"`
+	ParentLastName  string     `json:"parent_last_name"`
+	ParentPhone     string     `json:"parent_phone"`
+	CheckedInAt     *time.Time `json:"checked_in_at"`
+	CheckedOutAt    *time.Time `json:"checked_out_at"`
+}
+
+// GetRoster lists the children with an approved reservation for a day (?date=YYYY-MM-DD,
+// default today) along with their check-in state
+func GetRoster(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidDate, "Invalid date format. Use YYYY-MM-DD", map[string]interface{}{
+			"field": "date",
+			"value": date,
+		}))
+		return
+	}
+
+	var slot models.Slot
+	if err := config.DB.Where("date = ?", date).First(&slot).Error; err != nil {
+		utils.RespondWithSuccess(w, map[string]interface{}{
+			"date":     date,
+			"slot":     nil,
+			"children": []rosterEntry{},
+		})
+		return
+	}
+
+	entries := []rosterEntry{}
+	if err := config.DB.Table("reservations r").
+		Select("r.id AS reservation_id, c.id AS child_id, c.name AS child_name, c.age AS child_age, u.id AS parent_id, u.first_name AS parent_first_name, u.last_name AS parent_last_name, u.phone AS parent_phone, r.checked_in_at, r.checked_out_at").
+		Joins("JOIN children c ON c.id = r.child_id AND c.deleted_at IS NULL").
+		Joins("JOIN users u ON u.id = c.parent_id").
+		Where("r.slot_id = ? AND r.status = 'approved' AND r.deleted_at IS NULL", slot.ID).
+		Order("c.name").
+		Scan(&entries).Error; err != nil {
+		zap.L().Error("Failed to load roster", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve roster")
+		return
+	}
+
+	checkedIn := 0
+	for _, e := range entries {
+		if e.CheckedInAt != nil && e.CheckedOutAt == nil {
+			checkedIn++
+		}
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"date": date,
+		"slot": map[string]interface{}{
+			"id":       slot.ID,
+			"capacity": slot.Capacity,
+		},
+		"children": entries,
+		"present":  checkedIn,
+		"total":    len(entries),
+	})
+}
+
+// loadTodayReservation loads an approved reservation for today's slot; it writes the
+// error response itself
+func loadTodayReservation(w http.ResponseWriter, r *http.Request) (models.Reservation, bool) {
+	id := mux.Vars(r)["id"]
+	var reservation models.Reservation
+	reservationID, err := utils.ParseUint(id)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid reservation ID", map[string]interface{}{
+			"reservation_id": id,
+		}))
+		return reservation, false
+	}
+	if err := config.DB.First(&reservation, reservationID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrReservationNotFound, "Reservation not found", map[string]interface{}{
+			"reservation_id": reservationID,
+		}))
+		return reservation, false
+	}
+	if reservation.Status != "approved" {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidStatus, "Reservation isn't approved", map[string]interface{}{
+			"reservation_id": reservation.ID,
+			"status":         reservation.Status,
+		}))
+		return reservation, false
+	}
+	var slot models.Slot
+	if err := config.DB.First(&slot, reservation.SlotID).Error; err != nil || slot.Date != time.Now().Format("2006-01-02") {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Check-in is only possible on the day of the reservation", map[string]interface{}{
+			"reservation_id": reservation.ID,
+			"date":           slot.Date,
+		}))
+		return reservation, false
+	}
+	return reservation, true
+}
+
+func checkInResponse(reservation models.Reservation) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             reservation.ID,
+		"child_id":       reservation.ChildID,
+		"slot_id":        reservation.SlotID,
+		"checked_in_at":  reservation.CheckedInAt,
+		"checked_out_at": reservation.CheckedOutAt,
+	}
+}
+
+// CheckInReservation records that the child of an approved reservation arrived today
+func CheckInReservation(w http.ResponseWriter, r *http.Request) {
+	staff, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	reservation, ok := loadTodayReservation(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	result := config.DB.Model(&models.Reservation{}).
+		Where("id = ? AND checked_in_at IS NULL", reservation.ID).
+		Updates(map[string]interface{}{"checked_in_at": now, "checked_in_by_id": staff.ID})
+	if result.Error != nil {
+		zap.L().Error("Failed to check in reservation", zap.Error(result.Error))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check in")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidStatus, "Child is already checked in", map[string]interface{}{
+			"reservation_id": reservation.ID,
+			"checked_in_at":  reservation.CheckedInAt,
+		}))
+		return
+	}
+	reservation.CheckedInAt = &now
+	reservation.CheckedInByID = &staff.ID
+	utils.PublishEvent(utils.EventReservationCheckedIn, utils.ReservationEventData(reservation))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":     "Checked in",
+		"reservation": checkInResponse(reservation),
+	})
+}
+
+// CheckOutReservation records that a checked-in child left
+func CheckOutReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := loadTodayReservation(w, r)
+	if !ok {
+		return
+	}
+	if reservation.CheckedInAt == nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidStatus, "Child isn't checked in", map[string]interface{}{
+			"reservation_id": reservation.ID,
+		}))
+		return
+	}
+
+	now := time.Now()
+	result := config.DB.Model(&models.Reservation{}).
+		Where("id = ? AND checked_out_at IS NULL", reservation.ID).
+		Update("checked_out_at", now)
+	if result.Error != nil {
+		zap.L().Error("Failed to check out reservation", zap.Error(result.Error))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check out")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidStatus, "Child is already checked out", map[string]interface{}{
+			"reservation_id": reservation.ID,
+			"checked_out_at": reservation.CheckedOutAt,
+		}))
+		return
+	}
+	reservation.CheckedOutAt = &now
+	utils.PublishEvent(utils.EventReservationCheckedOut, utils.ReservationEventData(reservation))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":     "Checked out",
+		"reservation": checkInResponse(reservation),
+	})
+}
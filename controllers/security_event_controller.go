@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+
+	"go.uber.org/zap"
+)
+
+// ListMySecurityEvents returns the current user's account activity (logins, failed logins,
+// password changes...), newest first
+func ListMySecurityEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	// Alerts are for staff; the user sees the events themselves
+	query := config.DB.Model(&models.SecurityEvent{}).Where("user_id = ? AND type <> ?", userID, utils.SecurityAlert)
+	var total int64
+	query.Count(&total)
+
+	events := []models.SecurityEvent{}
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&events).Error; err != nil {
+		zap.L().Error("Failed to list security events", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve security events")
+		return
+	}
+	// Who changed a role is staff business
+	for i := range events {
+		events[i].ActorID = nil
+	}
+
+	utils.RespondWithPaginatedData(w, events, page, perPage, int(total))
+}
+
+// ListSecurityEvents returns the security log across users, newest first.
+// Filters: user_id, type (comma-separated), outcome, ip, email, since and until (RFC 3339).
+func ListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, perPage, err := utils.ParsePagination(q.Get("page"), q.Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	query := config.DB.Model(&models.SecurityEvent{})
+	if value := q.Get("user_id"); value != "" {
+		id, err := utils.ParseUint(value)
+		if err != nil {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid user_id", map[string]interface{}{
+				"user_id": value,
+			}))
+			return
+		}
+		query = query.Where("user_id = ?", id)
+	}
+	types, err := utils.ParseSecurityEventTypes(q.Get("type"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid event type")
+		}
+		return
+	}
+	if types != nil {
+		query = query.Where("type IN ?", types)
+	}
+	if value := q.Get("outcome"); value != "" {
+		if value != utils.OutcomeSuccess && value != utils.OutcomeFailure && value != utils.OutcomeBlocked {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "outcome must be one of success, failure, blocked", map[string]interface{}{
+				"outcome": value,
+			}))
+			return
+		}
+		query = query.Where("outcome = ?", value)
+	}
+	if value := q.Get("ip"); value != "" {
+		query = query.Where("ip = ?", value)
+	}
+	if value := q.Get("email"); value != "" {
+		query = query.Where("email = ?", strings.ToLower(strings.TrimSpace(value)))
+	}
+	for _, bound := range []struct{ param, op string }{{"since", ">="}, {"until", "<"}} {
+		value := q.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidDate, "Invalid "+bound.param+", use RFC 3339", map[string]interface{}{
+				bound.param: value,
+			}))
+			return
+		}
+		query = query.Where("created_at "+bound.op+" ?", t)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	events := []models.SecurityEvent{}
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&events).Error; err != nil {
+		zap.L().Error("Failed to list security events", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve security events")
+		return
+	}
+
+	utils.RespondWithPaginatedData(w, events, page, perPage, int(total))
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"net/http"
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func sessionResponse(s models.UserSession, currentID uint) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           s.ID,
+		"device":       utils.DescribeUserAgent(s.UserAgent),
+		"user_agent":   s.UserAgent,
+		"ip":           s.IP,
+		"created_at":   s.CreatedAt,
+		"last_seen_at": s.LastSeenAt,
+		"expires_at":   s.ExpiresAt,
+		"current":      s.ID == currentID,
+	}
+}
+
+func sessionsResponse(sessions []models.UserSession, currentID uint) []map[string]interface{} {
+	data := []map[string]interface{}{}
+	for _, s := range sessions {
+		data = append(data, sessionResponse(s, currentID))
+	}
+	return data
+}
+
+// ListSessions returns the current user's active sessions; the one making the request
+// is marked current
+func ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	sessions, err := utils.ActiveSessions(userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve sessions")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"sessions": sessionsResponse(sessions, utils.CurrentSessionID(r, userID)),
+	})
+}
+
+// RevokeSession logs out one of the current user's sessions. Revoking the current
+// session logs the caller out.
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	sessionID, err := utils.ParseUint(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid session ID", nil))
+		return
+	}
+	current := utils.CurrentSessionID(r, userID)
+	if !utils.RevokeSession(userID, sessionID) {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Session not found", map[string]interface{}{
+			"session_id": sessionID,
+		}))
+		return
+	}
+	if sessionID == current {
+		utils.ClearSession(w, r)
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    "Session revoked",
+		"session_id": sessionID,
+		"current":    sessionID == current,
+	})
+}
+
+// RevokeOtherSessions logs out all of the current user's sessions except this one
+func RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+	revoked := utils.RevokeUserSessions(userID, utils.CurrentSessionID(r, userID))
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Other sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// adminTargetUser loads the user named by the {id} route variable
+func adminTargetUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+	id := mux.Vars(r)["id"]
+	userID, err := utils.ParseUint(id)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid user ID", map[string]interface{}{
+			"user_id": id,
+		}))
+		return user, false
+	}
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
+			"user_id": userID,
+		}))
+		return user, false
+	}
+	return user, true
+}
+
+// ListUserSessions returns a user's active sessions (admin only)
+func ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := utils.ActiveSessions(user.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve sessions")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"user_id":  user.ID,
+		"sessions": sessionsResponse(sessions, 0),
+	})
+}
+
+// RevokeAllUserSessions logs a user out everywhere (admin only). The session version is
+// bumped too, so sessions from before tracking existed end as well.
+func RevokeAllUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := config.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("session_version", gorm.Expr("session_version + 1")).Error; err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	revoked := utils.RevokeUserSessions(user.ID, 0)
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(uint)
+	zap.L().Info("Admin revoked user sessions", zap.Uint("admin_id", adminID), zap.Uint("user_id", user.ID), zap.Int64("revoked", revoked))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "User sessions revoked",
+		"user_id": user.ID,
+		"revoked": revoked,
+	})
+}
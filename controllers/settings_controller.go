@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/utils"
+
+	"go.uber.org/zap"
+)
+
+// GetSettings returns all runtime settings (admin only)
+func GetSettings(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"settings": utils.AllSettings(),
+	})
+}
+
+// UpdateSettings changes one or more settings (admin only). Only the keys sent are changed.
+func UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+
+	// Don't let an admin lock themselves out of the admin API
+	if require, _ := body[utils.SettingRequireAdmin2FA].(bool); require && !user.TOTPEnabled {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrTwoFactorRequired, "Enable two-factor authentication on your own account first", nil))
+		return
+	}
+
+	if err := utils.SaveSettings(body); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			zap.L().Error("Failed to save settings", zap.Error(err))
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save settings")
+		}
+		return
+	}
+	zap.L().Info("Settings updated", zap.Uint("admin_id", user.ID), zap.Any("settings", body))
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":  "Settings updated",
+		"settings": utils.AllSettings(),
+	})
+}
@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"reservio/config"
 	"reservio/models"
 	"reservio/utils"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 // ListSlotsCalendar returns slots grouped by date with availability counts.
@@ -34,3 +39,90 @@ func ListSlotsCalendar(w http.ResponseWriter, r *http.Request) {
 		"calendar": availabilityMap,
 	})
 }
+
+// StreamSlotAvailability pushes live availability changes as Server-Sent Events.
+// Optional filters: slot_ids (comma-separated), from and to (YYYY-MM-DD, inclusive).
+// The stream starts with the current availability of every matching slot, then sends an
+// "availability" event whenever a reservation or slot change affects a matching slot.
+func StreamSlotAvailability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := utils.ParseAvailabilityFilter(query.Get("slot_ids"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid stream filter")
+		}
+		return
+	}
+
+	// Subscribe before taking the snapshot so no change slips in between
+	sub := utils.SubscribeAvailability(filter)
+	defer utils.UnsubscribeAvailability(sub)
+
+	slotQuery := config.DB.Order("date ASC")
+	if len(filter.SlotIDs) > 0 {
+		ids := make([]uint, 0, len(filter.SlotIDs))
+		for id := range filter.SlotIDs {
+			ids = append(ids, id)
+		}
+		slotQuery = slotQuery.Where("id IN ?", ids)
+	}
+	if filter.From != "" {
+		slotQuery = slotQuery.Where("date >= ?", filter.From)
+	}
+	if filter.To != "" {
+		slotQuery = slotQuery.Where("date <= ?", filter.To)
+	}
+	var slots []models.Slot
+	if err := slotQuery.Find(&slots).Error; err != nil {
+		zap.L().Error("Failed to get slots for availability stream", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve slots")
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, slot := range slots {
+		update := utils.SlotAvailability(slot)
+		update.Event = "snapshot"
+		writeSSE(w, "availability", update)
+	}
+	if err := rc.Flush(); err != nil {
+		zap.L().Error("Availability stream does not support flushing", zap.Error(err))
+		return
+	}
+
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done:
+			return
+		case update := <-sub.C:
+			writeSSE(w, "availability", update)
+		case <-heartbeat.C:
+			// Comment line keeps proxies from closing an idle connection
+			_, _ = w.Write([]byte(": ping\n\n"))
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes one Server-Sent Event with a JSON data line
+func writeSSE(w http.ResponseWriter, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OptInSMS records the current user's consent to receive SMS on their profile phone number
+func OptInSMS(w http.ResponseWriter, r *http.Request) {
+	setSMSOptIn(w, r, true)
+}
+
+// OptOutSMS withdraws the current user's SMS consent
+func OptOutSMS(w http.ResponseWriter, r *http.Request) {
+	setSMSOptIn(w, r, false)
+}
+
+func setSMSOptIn(w http.ResponseWriter, r *http.Request, optIn bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
+			"user_id": userID,
+		}))
+		return
+	}
+	if optIn && user.Phone == "" {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidPhone, "Add a phone number to your profile before opting in to SMS", map[string]interface{}{
+			"field": "phone",
+		}))
+		return
+	}
+
+	updates := map[string]interface{}{"sms_opt_in": optIn, "sms_opt_in_at": nil}
+	if optIn {
+		updates["sms_opt_in_at"] = time.Now()
+	}
+	if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+		zap.L().Error("Failed to update SMS opt-in", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update SMS preference")
+		return
+	}
+
+	message := "Opted out of SMS notifications"
+	if optIn {
+		message = "Opted in to SMS notifications"
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    message,
+		"sms_opt_in": optIn,
+		"phone":      user.Phone,
+	})
+}
+
+// HandleInboundSMS handles replies forwarded by the SMS provider (Twilio-style form fields
+// From and Body). STOP-type keywords opt the sender out, START-type keywords opt back in.
+// The provider must pass the shared SMS_INBOUND_TOKEN as the token query parameter.
+func HandleInboundSMS(w http.ResponseWriter, r *http.Request) {
+	expected := os.Getenv("SMS_INBOUND_TOKEN")
+	token := r.URL.Query().Get("token")
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Resource not found", nil))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid form data", nil))
+		return
+	}
+
+	from, err := utils.NormalizePhone(r.FormValue("From"), "")
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidPhone, "Invalid sender number", nil))
+		return
+	}
+
+	var updates map[string]interface{}
+	switch strings.ToUpper(strings.TrimSpace(r.FormValue("Body"))) {
+	case "STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT":
+		updates = map[string]interface{}{"sms_opt_in": false, "sms_opt_in_at": nil}
+	case "START", "UNSTOP", "YES":
+		updates = map[string]interface{}{"sms_opt_in": true, "sms_opt_in_at": time.Now()}
+	default:
+		utils.RespondWithSuccess(w, map[string]interface{}{"message": "Ignored"})
+		return
+	}
+
+	result := config.DB.Model(&models.User{}).Where("phone = ?", from).Updates(updates)
+	if result.Error != nil {
+		zap.L().Error("Failed to apply inbound SMS keyword", zap.Error(result.Error))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update SMS preference")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "SMS preference updated",
+		"updated": result.RowsAffected,
+	})
+}
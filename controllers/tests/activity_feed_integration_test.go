@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func dialActivityFeed(t *testing.T, serverURL, cookie, query string) *websocket.Conn {
+	header := http.Header{}
+	header.Set("Cookie", cookie)
+	header.Set("Origin", serverURL)
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/api/admin/activity/ws" + query
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readActivity returns the next feed message of the given type
+func readActivity(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg["type"] == msgType {
+			return msg
+		}
+	}
+}
+
+func TestAdminActivityFeed(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "feed-admin@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+	// Non-admins can't open the feed
+	req, _ := http.NewRequest("GET", server.URL+"/api/admin/activity/ws", nil)
+	req.Header.Set("Cookie", cookie)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+
+	conn := dialActivityFeed(t, server.URL, cookie, "?types=user.registered")
+	readActivity(t, conn, "subscribed")
+
+	otherToken, otherCookie := getCSRFTokenAndCookie(server)
+	registerAndLogin(server, "feed-parent@example.com", "testpassword123", otherToken, otherCookie)
+
+	msg := readActivity(t, conn, "activity")
+	activity := msg["activity"].(map[string]interface{})
+	assert.Equal(t, "user.registered", activity["type"])
+	lastID := int(activity["id"].(float64))
+	_ = conn.Close()
+
+	// Events recorded while disconnected are replayed on resume
+	date := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+	createSlot(server, csrfToken, cookie, date, 5)
+
+	resumed := dialActivityFeed(t, server.URL, cookie, "?last_event_id="+strconv.Itoa(lastID))
+	defer resumed.Close()
+	msg = readActivity(t, resumed, "activity")
+	activity = msg["activity"].(map[string]interface{})
+	assert.Equal(t, "slot.created", activity["type"])
+	assert.Greater(t, int(activity["id"].(float64)), lastID)
+}
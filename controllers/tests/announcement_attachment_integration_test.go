@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reservio/config"
+	"reservio/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func uploadAttachment(t *testing.T, serverURL, csrfToken, cookie string, annID int, name, contentType string, content []byte) *http.Response {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, name))
+	header.Set("Content-Type", contentType)
+	part, _ := mw.CreatePart(header)
+	_, _ = part.Write(content)
+	_ = mw.Close()
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/admin/announcements/%d/attachments", serverURL, annID), &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-CSRF-Token", csrfToken)
+	req.Header.Set("Cookie", cookie)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestAnnouncementMarkdownAndAttachments(t *testing.T) {
+	t.Setenv("ATTACHMENT_DIR", t.TempDir())
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "attach-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	_, parentCookie := registerAndLogin(server, "attach-parent@example.com", "testpassword123", parentInit, parentInitCookie)
+
+	annID := createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":   "Menu",
+		"content": "**Soup** of the day <script>alert(1)</script>",
+	})
+	hiddenID := createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title": "Staff only", "content": "x", "audience": "admins",
+	})
+
+	pdf := []byte("%PDF-1.4\n% menu\n")
+	resp := uploadAttachment(t, server.URL, adminToken, adminCookie, annID, "menu.pdf", "application/pdf", pdf)
+	assert.Equal(t, 200, resp.StatusCode)
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	downloadURL := result["attachment"].(map[string]interface{})["download_url"].(string)
+
+	// Content that doesn't match the declared type is rejected
+	bad := uploadAttachment(t, server.URL, adminToken, adminCookie, annID, "menu.pdf", "application/pdf", []byte("MZ\x90\x00 not a pdf"))
+	assert.Equal(t, 400, bad.StatusCode)
+
+	// Listing renders sanitized HTML and lists attachments
+	listReq, _ := http.NewRequest("GET", server.URL+"/api/announcements", nil)
+	listResp, err := http.DefaultClient.Do(listReq)
+	assert.NoError(t, err)
+	var list map[string]interface{}
+	if err := json.NewDecoder(listResp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	item := list["data"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, item["content_html"], "<strong>Soup</strong>")
+	assert.NotContains(t, item["content_html"], "<script")
+	assert.Len(t, item["attachments"], 1)
+
+	// Download requires a session
+	anonResp, err := http.Get(server.URL + downloadURL)
+	assert.NoError(t, err)
+	assert.Equal(t, 401, anonResp.StatusCode)
+
+	dlReq, _ := http.NewRequest("GET", server.URL+downloadURL, nil)
+	dlReq.Header.Set("Cookie", parentCookie)
+	dlResp, err := http.DefaultClient.Do(dlReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, dlResp.StatusCode)
+	assert.Contains(t, dlResp.Header.Get("Content-Disposition"), "menu.pdf")
+	body, _ := io.ReadAll(dlResp.Body)
+	assert.Equal(t, pdf, body)
+
+	// Attachments of announcements the user can't see are not served
+	hiddenResp := uploadAttachment(t, server.URL, adminToken, adminCookie, hiddenID, "rota.pdf", "application/pdf", pdf)
+	var hiddenResult map[string]interface{}
+	if err := json.NewDecoder(hiddenResp.Body).Decode(&hiddenResult); err != nil {
+		t.Fatal(err)
+	}
+	hiddenURL := hiddenResult["attachment"].(map[string]interface{})["download_url"].(string)
+	hiddenReq, _ := http.NewRequest("GET", server.URL+hiddenURL, nil)
+	hiddenReq.Header.Set("Cookie", parentCookie)
+	hiddenDl, err := http.DefaultClient.Do(hiddenReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, hiddenDl.StatusCode)
+}
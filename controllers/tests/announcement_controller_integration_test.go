@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createAnnouncement(t *testing.T, serverURL, csrfToken, cookie string, payload map[string]interface{}) int {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", serverURL+"/api/admin/announcements", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", csrfToken)
+	req.Header.Set("Cookie", cookie)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return int(result["announcement"].(map[string]interface{})["id"].(float64))
+}
+
+func listAnnouncementTitles(t *testing.T, url, cookie string) []string {
+	req, _ := http.NewRequest("GET", url, nil)
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, item := range result["data"].([]interface{}) {
+		titles = append(titles, item.(map[string]interface{})["title"].(string))
+	}
+	return titles
+}
+
+func TestAnnouncementVisibility(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "ann-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentToken, parentCookie := registerAndLogin(server, "ann-parent@example.com", "testpassword123", parentInit, parentInitCookie)
+
+	date := time.Now().AddDate(0, 0, 4).Format("2006-01-02")
+	slotID := createSlot(server, adminToken, adminCookie, date, 5)
+	otherSlotID := createSlot(server, adminToken, adminCookie, time.Now().AddDate(0, 0, 6).Format("2006-01-02"), 5)
+	childID := createChild(server, parentToken, parentCookie, "Ann", 4)
+	createReservation(server, parentToken, parentCookie, slotID, childID)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Everyone", "content": "x"})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Pinned", "content": "x", "pinned": true})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Parents", "content": "x", "audience": "parents"})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Admins", "content": "x", "audience": "admins"})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "My slot", "content": "x", "audience": "slot", "audience_slot_id": slotID})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Other slot", "content": "x", "audience": "slot", "audience_slot_id": otherSlotID})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "My dates", "content": "x", "audience": "dates", "audience_from": date, "audience_to": date})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Draft", "content": "x", "status": "draft"})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Scheduled", "content": "x", "publish_at": future})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{"title": "Expired", "content": "x", "expires_at": past})
+
+	anonymous := listAnnouncementTitles(t, server.URL+"/api/announcements", "")
+	assert.Equal(t, []string{"Pinned", "Everyone"}, anonymous)
+
+	parent := listAnnouncementTitles(t, server.URL+"/api/announcements", parentCookie)
+	assert.ElementsMatch(t, []string{"Pinned", "Everyone", "Parents", "My slot", "My dates"}, parent)
+	assert.Equal(t, "Pinned", parent[0])
+
+	admin := listAnnouncementTitles(t, server.URL+"/api/announcements", adminCookie)
+	assert.ElementsMatch(t, []string{"Pinned", "Everyone", "Parents", "Admins", "My slot", "Other slot", "My dates"}, admin)
+
+	drafts := listAnnouncementTitles(t, server.URL+"/api/admin/announcements?state=draft", adminCookie)
+	assert.Equal(t, []string{"Draft"}, drafts)
+	scheduled := listAnnouncementTitles(t, server.URL+"/api/admin/announcements?state=scheduled", adminCookie)
+	assert.Equal(t, []string{"Scheduled"}, scheduled)
+
+	// Invalid audience is rejected
+	badBody, _ := json.Marshal(map[string]interface{}{"title": "Bad", "content": "x", "audience": "slot"})
+	badReq, _ := http.NewRequest("POST", server.URL+"/api/admin/announcements", bytes.NewReader(badBody))
+	badReq.Header.Set("Content-Type", "application/json")
+	badReq.Header.Set("X-CSRF-Token", adminToken)
+	badReq.Header.Set("Cookie", adminCookie)
+	badResp, err := http.DefaultClient.Do(badReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, badResp.StatusCode)
+}
+
+func TestLegacyAnnouncementsStayVisible(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "legacy-ann-admin@example.com"
+	_, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	_, parentCookie := registerAndLogin(server, "legacy-ann-parent@example.com", "testpassword123", parentInit, parentInitCookie)
+
+	// A row from before scheduling and audiences existed has NULL in the new columns
+	assert.NoError(t, config.DB.Exec(`INSERT INTO announcements (created_at, updated_at, title, content, status, audience)
+		VALUES (NOW(), NOW(), 'Legacy', 'x', NULL, NULL)`).Error)
+
+	assert.Equal(t, []string{"Legacy"}, listAnnouncementTitles(t, server.URL+"/api/announcements", ""))
+	assert.Equal(t, []string{"Legacy"}, listAnnouncementTitles(t, server.URL+"/api/announcements", parentCookie))
+	assert.Equal(t, []string{"Legacy"}, listAnnouncementTitles(t, server.URL+"/api/admin/announcements?state=live", adminCookie))
+
+	resp, err := http.Get(server.URL + "/api/announcements/feed.atom")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body := new(bytes.Buffer)
+	_, _ = body.ReadFrom(resp.Body)
+	assert.Contains(t, body.String(), "Legacy")
+}
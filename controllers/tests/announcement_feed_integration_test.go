@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncementFeeds(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "feed-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":   "Summer schedule",
+		"content": "Opening hours change in **July**.",
+	})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":    "Parents evening",
+		"content":  "Only for parents.",
+		"audience": "parents",
+	})
+	createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":   "Unfinished draft",
+		"content": "Not yet.",
+		"status":  "draft",
+	})
+
+	t.Run("Atom feed lists only public announcements", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/announcements/feed.atom")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "application/atom+xml")
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), "<title>Summer schedule</title>")
+		assert.Contains(t, string(body), "&lt;strong&gt;July&lt;/strong&gt;")
+		assert.NotContains(t, string(body), "Parents evening")
+		assert.NotContains(t, string(body), "Unfinished draft")
+	})
+
+	t.Run("RSS feed", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/announcements/feed.rss")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "application/rss+xml")
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `<rss version="2.0">`)
+		assert.Contains(t, string(body), "<title>Summer schedule</title>")
+	})
+
+	t.Run("Conditional GET returns 304", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/announcements/feed.atom")
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		req, _ := http.NewRequest("GET", server.URL+"/api/announcements/feed.atom", nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		cached, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		cached.Body.Close()
+		assert.Equal(t, http.StatusNotModified, cached.StatusCode)
+
+		req, _ = http.NewRequest("GET", server.URL+"/api/announcements/feed.atom", nil)
+		req.Header.Set("If-Modified-Since", resp.Header.Get("Last-Modified"))
+		cached, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		cached.Body.Close()
+		assert.Equal(t, http.StatusNotModified, cached.StatusCode)
+	})
+}
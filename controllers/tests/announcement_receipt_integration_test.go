@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func announcementRequest(t *testing.T, method, url, csrfToken, cookie string) (int, map[string]interface{}) {
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("X-CSRF-Token", csrfToken)
+	req.Header.Set("Cookie", cookie)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestAnnouncementReceipts(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "receipt-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentToken, parentCookie := registerAndLogin(server, "receipt-parent@example.com", "testpassword123", parentInit, parentInitCookie)
+
+	ackID := createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":        "New pickup rules",
+		"content":      "Please confirm you have read the new rules.",
+		"audience":     "parents",
+		"requires_ack": true,
+	})
+	infoID := createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+		"title":   "Garden party",
+		"content": "Friday afternoon.",
+	})
+	ackURL := server.URL + "/api/user/announcements/" + strconv.Itoa(ackID)
+	infoURL := server.URL + "/api/user/announcements/" + strconv.Itoa(infoID)
+
+	t.Run("Unread count starts with every visible announcement", func(t *testing.T) {
+		status, result := announcementRequest(t, "GET", server.URL+"/api/user/announcements/unread-count", parentToken, parentCookie)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(2), result["unread"])
+		assert.Equal(t, float64(1), result["unacknowledged"])
+	})
+
+	t.Run("Acknowledging is only allowed when required", func(t *testing.T) {
+		status, _ := announcementRequest(t, "POST", infoURL+"/acknowledge", parentToken, parentCookie)
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("Read and acknowledge update the counts", func(t *testing.T) {
+		status, _ := announcementRequest(t, "POST", infoURL+"/read", parentToken, parentCookie)
+		assert.Equal(t, 200, status)
+		status, result := announcementRequest(t, "POST", ackURL+"/acknowledge", parentToken, parentCookie)
+		assert.Equal(t, 200, status)
+		assert.NotNil(t, result["read_at"])
+		assert.NotNil(t, result["acknowledged_at"])
+
+		_, result = announcementRequest(t, "GET", server.URL+"/api/user/announcements/unread-count", parentToken, parentCookie)
+		assert.Equal(t, float64(0), result["unread"])
+		assert.Equal(t, float64(0), result["unacknowledged"])
+	})
+
+	t.Run("Listing carries the viewer's receipt flags", func(t *testing.T) {
+		status, result := announcementRequest(t, "GET", server.URL+"/api/announcements", "", parentCookie)
+		assert.Equal(t, 200, status)
+		for _, item := range result["data"].([]interface{}) {
+			ann := item.(map[string]interface{})
+			assert.Equal(t, true, ann["read"])
+			if int(ann["id"].(float64)) == ackID {
+				assert.Equal(t, true, ann["acknowledged"])
+			}
+		}
+	})
+
+	t.Run("Hidden announcements can't be marked read", func(t *testing.T) {
+		adminOnly := createAnnouncement(t, server.URL, adminToken, adminCookie, map[string]interface{}{
+			"title":    "Staff meeting",
+			"content":  "Admins only.",
+			"audience": "admins",
+		})
+		status, _ := announcementRequest(t, "POST", server.URL+"/api/user/announcements/"+strconv.Itoa(adminOnly)+"/read", parentToken, parentCookie)
+		assert.Equal(t, 404, status)
+	})
+
+	t.Run("Admin report and reminders", func(t *testing.T) {
+		status, result := announcementRequest(t, "GET", server.URL+"/api/admin/announcements/"+strconv.Itoa(ackID)+"/receipts", adminToken, adminCookie)
+		assert.Equal(t, 200, status)
+		summary := result["summary"].(map[string]interface{})
+		assert.Equal(t, float64(1), summary["recipients"])
+		assert.Equal(t, float64(1), summary["acknowledged"])
+
+		status, result = announcementRequest(t, "POST", server.URL+"/api/admin/announcements/"+strconv.Itoa(ackID)+"/remind", adminToken, adminCookie)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(0), result["reminded"])
+
+		// The admin hasn't read the announcement for everyone yet
+		status, result = announcementRequest(t, "GET", server.URL+"/api/admin/announcements/"+strconv.Itoa(infoID)+"/receipts?pending=true", adminToken, adminCookie)
+		assert.Equal(t, 200, status)
+		pending := result["recipients"].([]interface{})
+		assert.Len(t, pending, 1)
+		assert.Equal(t, adminEmail, pending[0].(map[string]interface{})["email"])
+
+		status, result = announcementRequest(t, "POST", server.URL+"/api/admin/announcements/"+strconv.Itoa(infoID)+"/remind", adminToken, adminCookie)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), result["reminded"])
+	})
+}
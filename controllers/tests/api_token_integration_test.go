@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bearerRequest(t *testing.T, method, url, token string) int {
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPITokens(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "token-user@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+	createToken := func(payload map[string]interface{}) (int, map[string]interface{}) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/user/tokens", csrfToken, cookie, payload)
+		if token, ok := result["token"].(map[string]interface{}); ok {
+			return status, token
+		}
+		return status, result
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		status, _ := createToken(map[string]interface{}{"name": "", "scopes": []string{"read"}})
+		assert.Equal(t, 400, status)
+		status, _ = createToken(map[string]interface{}{"name": "x", "scopes": []string{"admin"}})
+		assert.Equal(t, 400, status)
+		status, _ = createToken(map[string]interface{}{"name": "x", "scopes": []string{"read"}, "expires_in_days": 1000})
+		assert.Equal(t, 400, status)
+	})
+
+	status, readToken := createToken(map[string]interface{}{"name": "Nightly report", "scopes": []string{"read"}})
+	assert.Equal(t, 200, status)
+	read := readToken["token"].(string)
+	_, writeToken := createToken(map[string]interface{}{"name": "Sync", "scopes": []string{"read", "write"}, "expires_in_days": 7})
+	write := writeToken["token"].(string)
+
+	t.Run("Bearer authentication without CSRF", func(t *testing.T) {
+		assert.Equal(t, 200, bearerRequest(t, "GET", server.URL+"/api/user/profile", read))
+		assert.Equal(t, 200, bearerRequest(t, "GET", server.URL+"/api/parent/children", read))
+		assert.Equal(t, 401, bearerRequest(t, "GET", server.URL+"/api/user/profile", "rsv_not-a-real-token"))
+
+		// read scope can't change anything; write can, without a CSRF token
+		assert.Equal(t, 403, bearerRequest(t, "POST", server.URL+"/api/user/notifications/read-all", read))
+		assert.Equal(t, 200, bearerRequest(t, "POST", server.URL+"/api/user/notifications/read-all", write))
+	})
+
+	t.Run("Tokens can't manage tokens or reach the admin API", func(t *testing.T) {
+		assert.Equal(t, 403, bearerRequest(t, "GET", server.URL+"/api/user/tokens", write))
+		assert.Equal(t, 403, bearerRequest(t, "GET", server.URL+"/api/user/2fa", write))
+
+		config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+		assert.Equal(t, 403, bearerRequest(t, "GET", server.URL+"/api/admin/users", write))
+		_, adminToken := createToken(map[string]interface{}{"name": "Admin script", "scopes": []string{"read", "admin"}})
+		assert.Equal(t, 200, bearerRequest(t, "GET", server.URL+"/api/admin/users", adminToken["token"].(string)))
+	})
+
+	t.Run("Listing shows last use and hides token values", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/tokens", "", cookie, nil)
+		assert.Equal(t, 200, status)
+		tokens := result["tokens"].([]interface{})
+		assert.Len(t, tokens, 3)
+		for _, item := range tokens {
+			token := item.(map[string]interface{})
+			assert.Nil(t, token["token"])
+			if token["name"] == "Nightly report" {
+				assert.NotNil(t, token["last_used_at"])
+			}
+		}
+	})
+
+	t.Run("Revoked tokens stop working", func(t *testing.T) {
+		id := strconv.Itoa(int(readToken["id"].(float64)))
+		status, _, _ := sendJSON(t, "DELETE", server.URL+"/api/user/tokens/"+id, csrfToken, cookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, 401, bearerRequest(t, "GET", server.URL+"/api/user/profile", read))
+
+		status, _, _ = sendJSON(t, "DELETE", server.URL+"/api/user/tokens/9999", csrfToken, cookie, nil)
+		assert.Equal(t, 404, status)
+	})
+
+	t.Run("Logging out everywhere revokes tokens", func(t *testing.T) {
+		assert.Equal(t, 200, bearerRequest(t, "GET", server.URL+"/api/user/profile", write))
+		status, _, _ := sendJSON(t, "POST", server.URL+"/api/auth/logout-all", csrfToken, cookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, 401, bearerRequest(t, "GET", server.URL+"/api/user/profile", write))
+	})
+}
@@ -378,7 +378,7 @@ func TestRequestPasswordReset_InvalidEmail(t *testing.T) {
 	}
 	assert.Equal(t, "Invalid email format", invalidEmailResult["error"])
 
-	// Test with non-existent email
+	// A non-existent email gets the same answer as a registered one
 	nonexistentEmailPayload := map[string]interface{}{"email": "nonexistent@example.com"}
 	nonexistentEmailBody, _ := json.Marshal(nonexistentEmailPayload)
 	nonexistentEmailReq, _ := http.NewRequest("POST", server.URL+"/api/auth/request-reset", bytes.NewReader(nonexistentEmailBody))
@@ -387,13 +387,13 @@ func TestRequestPasswordReset_InvalidEmail(t *testing.T) {
 	nonexistentEmailReq.Header.Set("Cookie", cookie)
 	nonexistentEmailResp, err := http.DefaultClient.Do(nonexistentEmailReq)
 	assert.NoError(t, err)
-	assert.Equal(t, 404, nonexistentEmailResp.StatusCode)
+	assert.Equal(t, 200, nonexistentEmailResp.StatusCode)
 
 	var nonexistentEmailResult map[string]interface{}
 	if err := json.NewDecoder(nonexistentEmailResp.Body).Decode(&nonexistentEmailResult); err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, "User not found", nonexistentEmailResult["error"])
+	assert.Contains(t, nonexistentEmailResult["message"], "Password reset email sent")
 }
 
 func TestRegister_WeakPassword(t *testing.T) {
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "verify-parent@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+	var user models.User
+	config.DB.Where("email = ?", email).First(&user)
+	assert.False(t, user.EmailVerified)
+	assert.NotNil(t, user.VerificationSentAt)
+
+	t.Run("Resend is throttled", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/user/email/verification", csrfToken, cookie, nil)
+		assert.Equal(t, 429, status)
+		assert.Equal(t, "RATE_LIMIT_EXCEEDED", result["code"])
+	})
+
+	t.Run("Reservations can require a verified email", func(t *testing.T) {
+		assert.NoError(t, utils.SaveSettings(map[string]interface{}{utils.SettingRequireVerifiedEmail: true}))
+		defer func() { _ = utils.SaveSettings(map[string]interface{}{utils.SettingRequireVerifiedEmail: false}) }()
+
+		adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+		adminToken, adminCookie := registerAndLogin(server, "verify-admin@example.com", "testpassword123", adminInit, adminInitCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", "verify-admin@example.com").Update("role", "admin")
+		slotID := createSlot(server, adminToken, adminCookie, time.Now().AddDate(0, 0, 7).Format("2006-01-02"), 5)
+		childID := createChild(server, csrfToken, cookie, "Verified Kid", 5)
+
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/parent/reserve", csrfToken, cookie, map[string]int{"slot_id": slotID, "child_id": childID})
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrEmailNotVerified, result["code"])
+	})
+
+	t.Run("Verify registration email", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": "bogus"})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrInvalidToken, result["code"])
+
+		expired := utils.EmailVerificationToken(user.ID, email, time.Now().Add(-48*time.Hour))
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": expired})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrTokenExpired, result["code"])
+
+		token := utils.EmailVerificationToken(user.ID, email, time.Now())
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": token})
+		assert.Equal(t, 200, status)
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/user/profile", csrfToken, cookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, result["user"].(map[string]interface{})["email_verified"])
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/email/verification", csrfToken, cookie, nil)
+		assert.Equal(t, 400, status, "already verified")
+	})
+
+	t.Run("Email change waits for confirmation", func(t *testing.T) {
+		oldToken := utils.EmailVerificationToken(user.ID, email, time.Now())
+		newEmail := "verify-new@example.com"
+
+		status, _, _ := sendJSON(t, "PUT", server.URL+"/api/user/profile", csrfToken, cookie, map[string]string{"email": newEmail})
+		assert.Equal(t, 200, status)
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/profile", csrfToken, cookie, nil)
+		assert.Equal(t, 200, status)
+		profile := result["user"].(map[string]interface{})
+		assert.Equal(t, email, profile["email"])
+		assert.Equal(t, newEmail, profile["pending_email"])
+
+		token := utils.EmailVerificationToken(user.ID, newEmail, time.Now())
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": token})
+		assert.Equal(t, 200, status)
+		assert.Equal(t, newEmail, result["email"])
+
+		// Links for the old address no longer work
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": oldToken})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrInvalidToken, result["code"])
+	})
+
+	t.Run("Cancel a pending change", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "PUT", server.URL+"/api/user/profile", csrfToken, cookie, map[string]string{"email": "verify-other@example.com"})
+		assert.Equal(t, 200, status)
+		status, _, _ = sendJSON(t, "DELETE", server.URL+"/api/user/email/pending", csrfToken, cookie, nil)
+		assert.Equal(t, 200, status)
+
+		token := utils.EmailVerificationToken(user.ID, "verify-other@example.com", time.Now())
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/verify-email", initToken, initCookie, map[string]string{"token": token})
+		assert.Equal(t, 400, status)
+	})
+}
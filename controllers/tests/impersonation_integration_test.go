@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"fmt"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "imp-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentEmail := "imp-parent@example.com"
+	parentToken, parentCookie := registerAndLogin(server, parentEmail, "testpassword123", parentInit, parentInitCookie)
+	createChild(server, parentToken, parentCookie, "Impersonated Kid", 4)
+
+	var admin, parent models.User
+	config.DB.Where("email = ?", adminEmail).First(&admin)
+	config.DB.Where("email = ?", parentEmail).First(&parent)
+	impersonateURL := fmt.Sprintf("%s/api/admin/users/%d/impersonate", server.URL, parent.ID)
+
+	t.Run("Start requires a reason and a parent target", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "POST", impersonateURL, adminToken, adminCookie, map[string]string{})
+		assert.Equal(t, 400, status)
+		status, _, _ = sendJSON(t, "POST", fmt.Sprintf("%s/api/admin/users/%d/impersonate", server.URL, admin.ID), adminToken, adminCookie, map[string]string{"reason": "test"})
+		assert.Equal(t, 400, status)
+		status, _, _ = sendJSON(t, "POST", impersonateURL, parentToken, parentCookie, map[string]string{"reason": "test"})
+		assert.Equal(t, 403, status)
+	})
+
+	var impID float64
+	t.Run("Requests are served as the parent", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "POST", impersonateURL, adminToken, adminCookie, map[string]string{"reason": "Support ticket 17"})
+		assert.Equal(t, 200, status)
+		impID = result["impersonation"].(map[string]interface{})["id"].(float64)
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/user/profile", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, parentEmail, result["user"].(map[string]interface{})["email"])
+		imp := result["impersonation"].(map[string]interface{})
+		assert.Equal(t, float64(admin.ID), imp["admin_id"])
+		assert.Equal(t, adminEmail, imp["admin_email"])
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/parent/children", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Contains(t, fmt.Sprint(result), "Impersonated Kid")
+
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/users", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status, "staff routes aren't available as the parent")
+	})
+
+	t.Run("Sensitive actions are blocked", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "PUT", server.URL+"/api/user/profile", adminToken, adminCookie, map[string]string{"password": "newpassword123"})
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrImpersonationForbidden, result["code"])
+
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/setup", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrImpersonationForbidden, result["code"])
+
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/user/sessions", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/logout-all", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+
+		// Read receipts and acknowledgements must come from the parent themselves
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/announcements/1/read", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/announcements/1/acknowledge", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+
+		// So are notification preferences and the state of the parent's inbox
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/user/notification-preferences", adminToken, adminCookie, map[string]interface{}{})
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/notifications/read-all", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/user/notifications/1/read", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/user/notifications/1/unread", adminToken, adminCookie, nil)
+		assert.Equal(t, 403, status)
+
+		var stored models.User
+		config.DB.First(&stored, parent.ID)
+		assert.Equal(t, parent.Password, stored.Password)
+	})
+
+	t.Run("Stop and audit trail", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "DELETE", server.URL+"/api/user/impersonation", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/profile", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, adminEmail, result["user"].(map[string]interface{})["email"])
+		assert.Nil(t, result["impersonation"])
+
+		status, result, _ = sendJSON(t, "GET", fmt.Sprintf("%s/api/admin/impersonations/%d/requests", server.URL, int(impID)), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		requests := result["requests"].([]interface{})
+		paths := []string{}
+		for _, req := range requests {
+			entry := req.(map[string]interface{})
+			paths = append(paths, fmt.Sprintf("%s %s %v", entry["method"], entry["path"], entry["status"]))
+		}
+		assert.Contains(t, paths, "GET /api/parent/children 200")
+		assert.Contains(t, paths, "PUT /api/user/profile 403")
+		assert.Contains(t, paths, "DELETE /api/user/impersonation 200")
+
+		status, result, _ = sendJSON(t, "GET", fmt.Sprintf("%s/api/admin/impersonations?user_id=%d", server.URL, parent.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		data := result["data"].([]interface{})
+		if assert.Len(t, data, 1) {
+			entry := data[0].(map[string]interface{})
+			assert.Equal(t, "Support ticket 17", entry["reason"])
+			assert.NotNil(t, entry["ended_at"])
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"fmt"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationModes(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "invite-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	setMode := func(mode string) {
+		status, _, _ := sendJSON(t, "PUT", server.URL+"/api/admin/settings", adminToken, adminCookie, map[string]interface{}{utils.SettingRegistrationMode: mode})
+		assert.Equal(t, 200, status)
+	}
+	defer setMode(utils.RegistrationOpen)
+
+	register := func(email, code string) (int, map[string]interface{}) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/register", initToken, initCookie, map[string]string{
+			"email":       email,
+			"password":    "testpassword123",
+			"invite_code": code,
+		})
+		return status, result
+	}
+	invite := func(email, role string) (string, float64) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/admin/invitations", adminToken, adminCookie, map[string]string{"email": email, "role": role})
+		assert.Equal(t, 200, status)
+		inv := result["invitation"].(map[string]interface{})
+		return inv["code"].(string), inv["id"].(float64)
+	}
+
+	t.Run("Invite only", func(t *testing.T) {
+		setMode(utils.RegistrationInviteOnly)
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/auth/registration", "", "", nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, utils.RegistrationInviteOnly, result["mode"])
+
+		status, result = register("uninvited@example.com", "")
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrInvitationRequired, result["code"])
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/admin/invitations", adminToken, adminCookie, map[string]string{"email": adminEmail})
+		assert.Equal(t, 409, status, "existing users can't be invited")
+
+		code, _ := invite("invited@example.com", "parent")
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/auth/invitations/"+code, "", "", nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "invited@example.com", result["email"])
+
+		status, result = register("someone-else@example.com", code)
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrInvalidInvitation, result["code"])
+
+		status, result = register("Invited@example.com", code)
+		assert.Equal(t, 200, status)
+		user := result["user"].(map[string]interface{})
+		assert.Equal(t, utils.UserStatusActive, user["status"])
+		assert.Equal(t, true, user["email_verified"])
+
+		// Single use
+		status, _ = register("invited2@example.com", code)
+		assert.Equal(t, 400, status)
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/auth/invitations/"+code, "", "", nil)
+		assert.Equal(t, 404, status)
+	})
+
+	t.Run("Invitation sets the role", func(t *testing.T) {
+		code, _ := invite("invited-admin@example.com", "admin")
+		status, result := register("invited-admin@example.com", code)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "admin", result["user"].(map[string]interface{})["role"])
+	})
+
+	t.Run("Revoked invitations stop working", func(t *testing.T) {
+		code, id := invite("revoked@example.com", "parent")
+		status, _, _ := sendJSON(t, "DELETE", fmt.Sprintf("%s/api/admin/invitations/%d", server.URL, int(id)), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, result := register("revoked@example.com", code)
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrInvalidInvitation, result["code"])
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/admin/invitations?pending=true", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		for _, inv := range result["invitations"].([]interface{}) {
+			assert.NotEqual(t, "revoked@example.com", inv.(map[string]interface{})["email"])
+		}
+	})
+
+	t.Run("Approval required", func(t *testing.T) {
+		setMode(utils.RegistrationApproval)
+
+		email := "pending-parent@example.com"
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+		assert.Equal(t, utils.UserStatusPending, user.Status)
+
+		slotID := createSlot(server, adminToken, adminCookie, time.Now().AddDate(0, 0, 10).Format("2006-01-02"), 5)
+		childID := createChild(server, csrfToken, cookie, "Pending Kid", 4)
+		reserve := map[string]int{"slot_id": slotID, "child_id": childID}
+
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/parent/reserve", csrfToken, cookie, reserve)
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrAccountPending, result["code"])
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/admin/users?status=pending", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		pending := result["data"].([]interface{})
+		assert.Len(t, pending, 1)
+		assert.Equal(t, email, pending[0].(map[string]interface{})["email"])
+
+		status, _, _ = sendJSON(t, "POST", fmt.Sprintf("%s/api/admin/users/%d/activate", server.URL, user.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/parent/reserve", csrfToken, cookie, reserve)
+		assert.Equal(t, 200, status)
+	})
+}
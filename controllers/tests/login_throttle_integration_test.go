@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLockoutAndUnlock(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	email := "lockout@example.com"
+	password := "testpassword123"
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	registerAndLogin(server, email, password, initToken, initCookie)
+
+	login := func(pw string) (int, map[string]interface{}) {
+		token, cookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/login", token, cookie, map[string]string{"email": email, "password": pw})
+		return status, result
+	}
+
+	for i := 0; i < 5; i++ {
+		status, _ := login("wrongpassword")
+		assert.Equal(t, 401, status)
+	}
+
+	t.Run("Locked even with the right password", func(t *testing.T) {
+		status, result := login(password)
+		assert.Equal(t, 429, status)
+		assert.Equal(t, "RATE_LIMIT_EXCEEDED", result["code"])
+		retryAfter := result["details"].(map[string]interface{})["retry_after"].(float64)
+		assert.InDelta(t, (5 * time.Minute).Seconds(), retryAfter, 5)
+	})
+
+	t.Run("Blocked attempts are logged on the account", func(t *testing.T) {
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+		var blocked models.SecurityEvent
+		err := config.DB.Where("outcome = ? AND details = ?", utils.OutcomeBlocked, "login locked").First(&blocked).Error
+		if assert.NoError(t, err) && assert.NotNil(t, blocked.UserID) {
+			assert.Equal(t, user.ID, *blocked.UserID)
+		}
+	})
+
+	t.Run("Counters are persisted", func(t *testing.T) {
+		var entry models.LoginThrottle
+		err := config.DB.Where("key LIKE ?", "ipacct:"+email+":%").First(&entry).Error
+		assert.NoError(t, err)
+		assert.Equal(t, 5, entry.Failures)
+		assert.NotNil(t, entry.LockedUntil)
+	})
+
+	t.Run("Admin unlock", func(t *testing.T) {
+		adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+		adminToken, adminCookie := registerAndLogin(server, "lockout-admin@example.com", password, adminInit, adminInitCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", "lockout-admin@example.com").Update("role", "admin")
+
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+		status, _, _ := sendJSON(t, "POST", fmt.Sprintf("%s/api/admin/users/%d/unlock", server.URL, user.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, _ = login(password)
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Successful login resets the account counters", func(t *testing.T) {
+		var count int64
+		config.DB.Model(&models.LoginThrottle{}).Where("key = ?", "acct:"+email).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
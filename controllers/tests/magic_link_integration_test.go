@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"net/url"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLinkLogin(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "magic-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentEmail := "magic-parent@example.com"
+	registerAndLogin(server, parentEmail, "testpassword123", parentInit, parentInitCookie)
+	var parent models.User
+	config.DB.Where("email = ?", parentEmail).First(&parent)
+
+	setEnabled := func(enabled bool) {
+		status, _, _ := sendJSON(t, "PUT", server.URL+"/api/admin/settings", adminToken, adminCookie, map[string]interface{}{utils.SettingMagicLinkLogin: enabled})
+		assert.Equal(t, 200, status)
+	}
+	request := func(email string) (int, map[string]interface{}) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/magic-link", initToken, initCookie, map[string]string{"email": email})
+		return status, result
+	}
+	redeem := func(token string) (int, map[string]interface{}, string) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		return sendJSON(t, "POST", server.URL+"/api/auth/magic-link/verify", initToken, initCookie, map[string]string{"token": token})
+	}
+	issue := func(user models.User) string {
+		link, err := utils.IssueMagicLink(user, "127.0.0.1", time.Now())
+		assert.NoError(t, err)
+		parsed, _ := url.Parse(link)
+		return parsed.Query().Get("token")
+	}
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		status, result := request(parentEmail)
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrMagicLinkDisabled, result["code"])
+	})
+
+	setEnabled(true)
+	defer setEnabled(false)
+
+	t.Run("Same response for unknown accounts", func(t *testing.T) {
+		status, known := request(parentEmail)
+		assert.Equal(t, 200, status)
+		status, unknown := request("nobody-here@example.com")
+		assert.Equal(t, 200, status)
+		assert.Equal(t, known["message"], unknown["message"])
+
+		var count int64
+		config.DB.Model(&models.MagicLinkToken{}).Where("user_id = ?", parent.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+		var stored models.MagicLinkToken
+		config.DB.Where("user_id = ?", parent.ID).First(&stored)
+		assert.Len(t, stored.TokenHash, 64, "only the hash is stored")
+	})
+
+	t.Run("Link logs in once", func(t *testing.T) {
+		token := issue(parent)
+		status, result, cookie := redeem(token)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, parentEmail, result["user"].(map[string]interface{})["email"])
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/user/profile", "", cookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, result["user"].(map[string]interface{})["email_verified"])
+
+		status, result, _ = redeem(token)
+		assert.Equal(t, 400, status, "single use")
+		assert.Equal(t, utils.ErrInvalidToken, result["code"])
+	})
+
+	t.Run("Expired and replaced links fail", func(t *testing.T) {
+		first := issue(parent)
+		second := issue(parent)
+		status, _, _ := redeem(first)
+		assert.Equal(t, 400, status, "a new link replaces older ones")
+
+		config.DB.Model(&models.MagicLinkToken{}).Where("token_hash = ?", utils.HashToken(second)).Update("expires_at", time.Now().Add(-time.Minute))
+		status, _, _ = redeem(second)
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("Staff accounts can't use links", func(t *testing.T) {
+		var admin models.User
+		config.DB.Where("email = ?", adminEmail).First(&admin)
+		status, _, _ := redeem(issue(admin))
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("Link doesn't lift a lockout before the second factor", func(t *testing.T) {
+		email := "magic-2fa@example.com"
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", email).Updates(map[string]interface{}{"totp_secret": "JBSWY3DPEHPK3PXP", "totp_enabled": true})
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+
+		now := time.Now()
+		for i := 0; i < 5; i++ {
+			utils.RecordLoginFailure(&user, email, "127.0.0.1", now)
+		}
+		assert.Greater(t, utils.LoginLockedFor(email, "127.0.0.1", now), time.Duration(0))
+
+		status, result, _ := redeem(issue(user))
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, result["two_factor_required"])
+		assert.Greater(t, utils.LoginLockedFor(email, "127.0.0.1", time.Now()), time.Duration(0))
+	})
+
+	t.Run("Requests are rate limited", func(t *testing.T) {
+		email := "magic-limit@example.com"
+		var status int
+		for i := 0; i <= utils.RateLimitMagicLinkEmail.Burst; i++ {
+			status, _ = request(email)
+		}
+		assert.Equal(t, 429, status)
+	})
+}
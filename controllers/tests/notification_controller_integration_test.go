@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	csrfToken, cookie := registerAndLogin(server, "prefs@example.com", "testpassword123", initToken, initCookie)
+
+	// Defaults: email and in-app on, SMS off
+	getReq, _ := http.NewRequest("GET", server.URL+"/api/user/notification-preferences", nil)
+	getReq.Header.Set("Cookie", cookie)
+	getResp, err := http.DefaultClient.Do(getReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, getResp.StatusCode)
+	var getResult map[string]interface{}
+	if err := json.NewDecoder(getResp.Body).Decode(&getResult); err != nil {
+		t.Fatal(err)
+	}
+	events := getResult["preferences"].(map[string]interface{})["events"].(map[string]interface{})
+	approvals := events["approvals"].(map[string]interface{})
+	assert.Equal(t, true, approvals["email"])
+	assert.Equal(t, false, approvals["sms"])
+
+	// Update a flag and quiet hours
+	payload := map[string]interface{}{
+		"events":      map[string]interface{}{"announcements": map[string]bool{"email": false}},
+		"quiet_hours": map[string]string{"start": "22:00", "end": "07:00", "timezone": "UTC"},
+	}
+	body, _ := json.Marshal(payload)
+	putReq, _ := http.NewRequest("PUT", server.URL+"/api/user/notification-preferences", bytes.NewReader(body))
+	putReq.Header.Set("Content-Type", "application/json")
+	putReq.Header.Set("X-CSRF-Token", csrfToken)
+	putReq.Header.Set("Cookie", cookie)
+	putResp, err := http.DefaultClient.Do(putReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, putResp.StatusCode)
+	var putResult map[string]interface{}
+	if err := json.NewDecoder(putResp.Body).Decode(&putResult); err != nil {
+		t.Fatal(err)
+	}
+	prefs := putResult["preferences"].(map[string]interface{})
+	announcements := prefs["events"].(map[string]interface{})["announcements"].(map[string]interface{})
+	assert.Equal(t, false, announcements["email"])
+	assert.Equal(t, true, announcements["in_app"])
+	assert.Equal(t, "22:00", prefs["quiet_hours"].(map[string]interface{})["start"])
+
+	// Unknown channel is rejected
+	badBody, _ := json.Marshal(map[string]interface{}{"events": map[string]interface{}{"approvals": map[string]bool{"pigeon": true}}})
+	badReq, _ := http.NewRequest("PUT", server.URL+"/api/user/notification-preferences", bytes.NewReader(badBody))
+	badReq.Header.Set("Content-Type", "application/json")
+	badReq.Header.Set("X-CSRF-Token", csrfToken)
+	badReq.Header.Set("Cookie", cookie)
+	badResp, err := http.DefaultClient.Do(badReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, badResp.StatusCode)
+
+	// Invalid quiet hours are rejected
+	qhBody, _ := json.Marshal(map[string]interface{}{"quiet_hours": map[string]string{"start": "7pm"}})
+	qhReq, _ := http.NewRequest("PUT", server.URL+"/api/user/notification-preferences", bytes.NewReader(qhBody))
+	qhReq.Header.Set("Content-Type", "application/json")
+	qhReq.Header.Set("X-CSRF-Token", csrfToken)
+	qhReq.Header.Set("Cookie", cookie)
+	qhResp, err := http.DefaultClient.Do(qhReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, qhResp.StatusCode)
+}
+
+func TestNotificationInbox(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "inbox-admin@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+
+	var admin models.User
+	config.DB.Where("email = ?", email).First(&admin)
+
+	// Admin messages land in the recipient's inbox
+	msgBody, _ := json.Marshal(map[string]interface{}{"user_id": admin.ID, "title": "Hello", "body": "Welcome aboard"})
+	msgReq, _ := http.NewRequest("POST", server.URL+"/api/admin/messages", bytes.NewReader(msgBody))
+	msgReq.Header.Set("Content-Type", "application/json")
+	msgReq.Header.Set("X-CSRF-Token", csrfToken)
+	msgReq.Header.Set("Cookie", cookie)
+	msgResp, err := http.DefaultClient.Do(msgReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, msgResp.StatusCode)
+
+	countReq, _ := http.NewRequest("GET", server.URL+"/api/user/notifications/unread-count", nil)
+	countReq.Header.Set("Cookie", cookie)
+	countResp, err := http.DefaultClient.Do(countReq)
+	assert.NoError(t, err)
+	var countResult map[string]interface{}
+	if err := json.NewDecoder(countResp.Body).Decode(&countResult); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, float64(1), countResult["unread_count"])
+
+	listReq, _ := http.NewRequest("GET", server.URL+"/api/user/notifications?unread=true", nil)
+	listReq.Header.Set("Cookie", cookie)
+	listResp, err := http.DefaultClient.Do(listReq)
+	assert.NoError(t, err)
+	var listResult map[string]interface{}
+	if err := json.NewDecoder(listResp.Body).Decode(&listResult); err != nil {
+		t.Fatal(err)
+	}
+	items := listResult["data"].([]interface{})
+	if assert.Len(t, items, 1) {
+		item := items[0].(map[string]interface{})
+		assert.Equal(t, "Hello", item["title"])
+		assert.Equal(t, false, item["read"])
+
+		id := int(item["id"].(float64))
+		readReq, _ := http.NewRequest("PUT", server.URL+"/api/user/notifications/"+strconv.Itoa(id)+"/read", nil)
+		readReq.Header.Set("X-CSRF-Token", csrfToken)
+		readReq.Header.Set("Cookie", cookie)
+		readResp, err := http.DefaultClient.Do(readReq)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, readResp.StatusCode)
+	}
+
+	// Dashboard reflects the unread count
+	statsReq, _ := http.NewRequest("GET", server.URL+"/api/dashboard/stats", nil)
+	statsReq.Header.Set("Cookie", cookie)
+	statsResp, err := http.DefaultClient.Do(statsReq)
+	assert.NoError(t, err)
+	var statsResult map[string]interface{}
+	if err := json.NewDecoder(statsResp.Body).Decode(&statsResult); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, float64(0), statsResult["unread_notifications"])
+
+	// Someone else's notification can't be touched
+	otherReq, _ := http.NewRequest("PUT", server.URL+"/api/user/notifications/999999/read", nil)
+	otherReq.Header.Set("X-CSRF-Token", csrfToken)
+	otherReq.Header.Set("Cookie", cookie)
+	otherResp, err := http.DefaultClient.Do(otherReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, otherResp.StatusCode)
+}
+
+func TestQuietHoursDeferEmail(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "quiet@example.com"
+	registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+	var user models.User
+	config.DB.Where("email = ?", email).First(&user)
+	now := time.Now().UTC()
+	pref := utils.DefaultNotificationPreference(user.ID)
+	pref.QuietHoursStart = now.Add(-time.Hour).Format("15:04")
+	pref.QuietHoursEnd = now.Add(time.Hour).Format("15:04")
+	pref.Timezone = "UTC"
+	assert.NoError(t, config.DB.Create(&pref).Error)
+
+	var mailed []string
+	mailDown := false
+	utils.RegisterNotificationChannel(utils.ChannelEmail, func(u models.User, msg utils.NotificationMessage) error {
+		if mailDown {
+			return fmt.Errorf("smtp unavailable")
+		}
+		mailed = append(mailed, msg.Subject)
+		return nil
+	})
+	defer utils.RegisterNotificationChannel(utils.ChannelEmail, func(u models.User, msg utils.NotificationMessage) error {
+		return utils.SendMail(u.Email, msg.Subject, msg.Body)
+	})
+
+	// In-app goes out right away, the email waits for the end of quiet hours
+	assert.NoError(t, utils.NotifyUser(user, utils.NotificationMessage{Event: utils.NotifyMessages, Subject: "Late news", Body: "x"}))
+	assert.Empty(t, mailed)
+	var inApp int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID).Count(&inApp)
+	assert.Equal(t, int64(1), inApp)
+
+	assert.Equal(t, 0, utils.DeliverDeferredNotifications(now))
+
+	// A failed send keeps the email queued and tries again later
+	mailDown = true
+	assert.Equal(t, 0, utils.DeliverDeferredNotifications(now.Add(2*time.Hour)))
+	var held models.DeferredNotification
+	if assert.NoError(t, config.DB.Where("user_id = ?", user.ID).First(&held).Error) {
+		assert.Equal(t, 1, held.Attempts)
+		assert.True(t, held.DeliverAt.After(now.Add(2*time.Hour)))
+	}
+	assert.Equal(t, 0, utils.DeliverDeferredNotifications(now.Add(2*time.Hour)), "not before the backoff")
+
+	mailDown = false
+	assert.Equal(t, 1, utils.DeliverDeferredNotifications(now.Add(3*time.Hour)))
+	assert.Equal(t, []string{"Late news"}, mailed)
+
+	// Each held-back email is sent once
+	assert.Equal(t, 0, utils.DeliverDeferredNotifications(now.Add(4*time.Hour)))
+	assert.Len(t, mailed, 1)
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reservio/config"
+	"reservio/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubOIDCProvider is a minimal OpenID provider: discovery, JWKS and a token endpoint
+// that checks PKCE and issues RS256-signed ID tokens for grants created by authorize
+type stubOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+type stubGrant struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	p := &stubOIDCProvider{key: key, grants: map[string]stubGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "stub", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		p.mu.Lock()
+		grant, ok := p.grants[r.PostForm.Get("code")]
+		delete(p.grants, r.PostForm.Get("code"))
+		p.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "stub-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(t, grant.claims),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *stubOIDCProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "stub"))
+	assert.NoError(t, err)
+	payload, _ := json.Marshal(claims)
+	jws, err := signer.Sign(payload)
+	assert.NoError(t, err)
+	token, err := jws.CompactSerialize()
+	assert.NoError(t, err)
+	return token
+}
+
+// authorize plays the user approving the login at the provider and returns the callback query
+func (p *stubOIDCProvider) authorize(t *testing.T, authURL string, claims map[string]interface{}) url.Values {
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, "reservio", q.Get("client_id"))
+
+	full := map[string]interface{}{
+		"iss":   p.server.URL,
+		"aud":   "reservio",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+	code := "code-" + q.Get("state")[:8]
+	p.mu.Lock()
+	p.grants[code] = stubGrant{challenge: q.Get("code_challenge"), claims: full}
+	p.mu.Unlock()
+	return url.Values{"code": {code}, "state": {q.Get("state")}}
+}
+
+// ssoLogin runs the whole browser flow and returns the final redirect and session cookie
+func ssoLogin(t *testing.T, server *httptest.Server, provider *stubOIDCProvider, claims map[string]interface{}, tamper func(url.Values)) (*url.URL, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	resp, err := client.Get(server.URL + "/api/auth/oidc/login")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	var flowCookie string
+	for _, c := range resp.Cookies() {
+		if c.Name == "oidc" {
+			flowCookie = c.Name + "=" + c.Value
+		}
+	}
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), provider.server.URL+"/authorize"))
+
+	params := provider.authorize(t, resp.Header.Get("Location"), claims)
+	if tamper != nil {
+		tamper(params)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/api/auth/oidc/callback?"+params.Encode(), nil)
+	req.Header.Set("Cookie", flowCookie)
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	var sessionCookie string
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			sessionCookie = c.Name + "=" + c.Value
+		}
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	return location, sessionCookie
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newStubOIDCProvider(t)
+	defer provider.server.Close()
+	server := setupTestApp()
+	defer server.Close()
+
+	t.Setenv("OIDC_ISSUER", provider.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", "reservio")
+	t.Setenv("OIDC_CLIENT_SECRET", "stub-secret")
+	t.Setenv("OIDC_REDIRECT_URL", server.URL+"/api/auth/oidc/callback")
+	t.Setenv("OIDC_ADMIN_GROUPS", "staff")
+	t.Setenv("OIDC_AUTO_PROVISION", "true")
+	t.Setenv("OIDC_POST_LOGIN_URL", "http://frontend.example/app")
+
+	t.Run("Just-in-time provisioning with role mapping", func(t *testing.T) {
+		location, cookie := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub":            "staff-1",
+			"email":          "Teacher@Example.com",
+			"email_verified": true,
+			"given_name":     "Tereza",
+			"groups":         []string{"staff", "teachers"},
+		}, nil)
+		assert.Equal(t, "frontend.example", location.Host)
+		assert.Empty(t, location.Query().Get("sso_error"))
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/profile", "", cookie, nil)
+		assert.Equal(t, 200, status)
+		user := result["user"].(map[string]interface{})
+		assert.Equal(t, "teacher@example.com", user["email"])
+		assert.Equal(t, "admin", user["role"])
+		assert.Equal(t, "Tereza", user["first_name"])
+
+		// SSO-only accounts can't log in with a password
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": "teacher@example.com", "password": "anything123"})
+		assert.Equal(t, 401, status)
+	})
+
+	t.Run("Existing account is linked by verified email", func(t *testing.T) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		registerAndLogin(server, "linked@example.com", "testpassword123", initToken, initCookie)
+		var before models.User
+		config.DB.Where("email = ?", "linked@example.com").First(&before)
+
+		_, cookie := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub": "parent-7", "email": "linked@example.com", "email_verified": true,
+		}, nil)
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/profile", "", cookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(before.ID), result["user"].(map[string]interface{})["id"])
+		assert.Equal(t, "parent", result["user"].(map[string]interface{})["role"])
+
+		var after models.User
+		config.DB.First(&after, before.ID)
+		assert.Equal(t, "parent-7", *after.OIDCSubject)
+	})
+
+	t.Run("Unverified email is refused", func(t *testing.T) {
+		location, cookie := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub": "someone", "email": "unverified@example.com", "email_verified": false,
+		}, nil)
+		assert.Equal(t, "SSO_EMAIL_NOT_VERIFIED", location.Query().Get("sso_error"))
+		assert.Empty(t, cookie)
+	})
+
+	t.Run("Forged state is refused", func(t *testing.T) {
+		location, _ := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub": "staff-1", "email": "teacher@example.com", "email_verified": true,
+		}, func(params url.Values) { params.Set("state", "forged") })
+		assert.Equal(t, "SSO_FAILED", location.Query().Get("sso_error"))
+	})
+
+	t.Run("Wrong nonce is refused", func(t *testing.T) {
+		location, _ := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub": "staff-1", "email": "teacher@example.com", "email_verified": true, "nonce": "replayed",
+		}, nil)
+		assert.Equal(t, "SSO_FAILED", location.Query().Get("sso_error"))
+	})
+
+	t.Run("Unknown users without provisioning", func(t *testing.T) {
+		t.Setenv("OIDC_AUTO_PROVISION", "false")
+		location, _ := ssoLogin(t, server, provider, map[string]interface{}{
+			"sub": "new-person", "email": "new@example.com", "email_verified": true,
+		}, nil)
+		assert.Equal(t, "SSO_NO_ACCOUNT", location.Query().Get("sso_error"))
+	})
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/url"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "policy-user@example.com"
+	token, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+
+	t.Run("Breached passwords are rejected", func(t *testing.T) {
+		regToken, regCookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/register", regToken, regCookie, map[string]string{"email": "policy-breached@example.com", "password": "password123"})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrBreachedPassword, result["code"])
+	})
+
+	t.Run("Recent passwords can't be reused", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "PUT", server.URL+"/api/user/profile", token, cookie, map[string]string{"password": "testpassword123"})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrPasswordReused, result["code"])
+
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/user/profile", token, cookie, map[string]string{"password": "secondpassword123"})
+		assert.Equal(t, 200, status)
+
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+		link, err := utils.IssuePasswordReset(user, time.Now())
+		assert.NoError(t, err)
+		parsed, _ := url.Parse(link)
+		resetToken := parsed.Query().Get("token")
+
+		resetInit, resetCookie := getCSRFTokenAndCookie(server)
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/auth/reset-password", resetInit, resetCookie, map[string]string{"token": resetToken, "password": "testpassword123"})
+		assert.Equal(t, 400, status, "the previous password is in the history")
+		assert.Equal(t, utils.ErrPasswordReused, result["code"])
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/reset-password", resetInit, resetCookie, map[string]string{"token": resetToken, "password": "thirdpassword123"})
+		assert.Equal(t, 200, status, "a rejected password doesn't use up the link")
+	})
+
+	t.Run("Outdated hashes are upgraded on login", func(t *testing.T) {
+		old, _ := bcrypt.GenerateFromPassword([]byte("thirdpassword123"), bcrypt.MinCost)
+		config.DB.Model(&models.User{}).Where("email = ?", email).UpdateColumn("password", string(old))
+
+		loginToken, loginCookie := getCSRFTokenAndCookie(server)
+		status, _, _ := sendJSON(t, "POST", server.URL+"/api/auth/login", loginToken, loginCookie, map[string]string{"email": email, "password": "thirdpassword123"})
+		assert.Equal(t, 200, status)
+
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+		cost, err := bcrypt.Cost([]byte(user.Password))
+		assert.NoError(t, err)
+		assert.Equal(t, utils.PasswordHashCost(), cost)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("thirdpassword123")))
+	})
+}
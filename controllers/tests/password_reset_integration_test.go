@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/url"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetFlow(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "reset-flow@example.com"
+	registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+	var user models.User
+	config.DB.Where("email = ?", email).First(&user)
+
+	request := func(email string) (int, map[string]interface{}) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/request-reset", initToken, initCookie, map[string]string{"email": email})
+		return status, result
+	}
+	reset := func(token, password string) (int, map[string]interface{}) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/reset-password", initToken, initCookie, map[string]string{"token": token, "password": password})
+		return status, result
+	}
+	issue := func() string {
+		link, err := utils.IssuePasswordReset(user, time.Now())
+		assert.NoError(t, err)
+		parsed, _ := url.Parse(link)
+		return parsed.Query().Get("token")
+	}
+
+	t.Run("Same response for unknown accounts", func(t *testing.T) {
+		status, known := request(email)
+		assert.Equal(t, 200, status)
+		status, unknown := request("no-such-account@example.com")
+		assert.Equal(t, 200, status)
+		assert.Equal(t, known["message"], unknown["message"])
+
+		var stored models.PasswordResetToken
+		config.DB.Where("user_id = ?", user.ID).First(&stored)
+		assert.Len(t, stored.TokenHash, 64, "only the hash is stored")
+	})
+
+	t.Run("Token resets once", func(t *testing.T) {
+		token := issue()
+		status, _ := reset(utils.HashToken(token), "newpassword123")
+		assert.Equal(t, 400, status, "the stored hash isn't a usable token")
+
+		status, _ = reset(token, "newpassword123")
+		assert.Equal(t, 200, status)
+		status, result := reset(token, "anotherpassword123")
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrInvalidToken, result["code"])
+
+		loginToken, loginCookie := getCSRFTokenAndCookie(server)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/login", loginToken, loginCookie, map[string]string{"email": email, "password": "newpassword123"})
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Replaced and expired tokens fail", func(t *testing.T) {
+		first := issue()
+		second := issue()
+		status, _ := reset(first, "newpassword456")
+		assert.Equal(t, 400, status, "a new link replaces older ones")
+
+		config.DB.Model(&models.PasswordResetToken{}).Where("token = ?", utils.HashToken(second)).Update("expires_at", time.Now().Add(-time.Minute).Unix())
+		status, result := reset(second, "newpassword456")
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrTokenExpired, result["code"])
+	})
+
+	t.Run("Requests are rate limited", func(t *testing.T) {
+		target := "reset-limit@example.com"
+		var status int
+		var result map[string]interface{}
+		for i := 0; i <= utils.RateLimitPasswordResetEmail.Burst; i++ {
+			status, result = request(target)
+		}
+		assert.Equal(t, 429, status)
+		assert.Equal(t, "RATE_LIMIT_EXCEEDED", result["code"])
+	})
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"reservio/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitPolicies(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	_ = os.Setenv("RATE_LIMIT_ENABLED", "true")
+	defer func() { _ = os.Unsetenv("RATE_LIMIT_ENABLED") }()
+
+	get := func(path string) *http.Response {
+		resp, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Strict policy for auth endpoints", func(t *testing.T) {
+		for i := 0; i < utils.RateLimitAuth.Burst; i++ {
+			resp := get("/api/auth/registration")
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, "10", resp.Header.Get("RateLimit-Limit"))
+		}
+		resp := get("/api/auth/registration")
+		assert.Equal(t, 429, resp.StatusCode)
+		assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+		assert.Contains(t, resp.Header.Get("RateLimit-Policy"), `policy="auth"`)
+	})
+
+	t.Run("Relaxed policy for slot reads is separate", func(t *testing.T) {
+		resp := get("/api/slots")
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("RateLimit-Policy"), `policy="relaxed"`)
+	})
+
+	t.Run("Health checks are not limited", func(t *testing.T) {
+		resp := get("/health")
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+	})
+}
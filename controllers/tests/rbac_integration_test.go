@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"fmt"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleBasedAccess(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	login := func(email, role string) (string, string) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		csrf, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", role)
+		return csrf, cookie
+	}
+	adminToken, adminCookie := login("rbac-admin@example.com", utils.RoleAdmin)
+	teacherToken, teacherCookie := login("rbac-teacher@example.com", utils.RoleTeacher)
+	officeToken, officeCookie := login("rbac-office@example.com", utils.RoleOffice)
+	auditorToken, auditorCookie := login("rbac-auditor@example.com", utils.RoleAuditor)
+	parentToken, parentCookie := login("rbac-parent@example.com", utils.RoleParent)
+
+	t.Run("Route permissions", func(t *testing.T) {
+		cases := []struct {
+			name          string
+			csrf, cookie  string
+			method, path  string
+			payload       interface{}
+			expectAllowed bool
+		}{
+			{"parent has no staff access", parentToken, parentCookie, "GET", "/api/admin/reservations", nil, false},
+			{"teacher sees the roster", teacherToken, teacherCookie, "GET", "/api/admin/roster", nil, true},
+			{"teacher can't list users", teacherToken, teacherCookie, "GET", "/api/admin/users", nil, false},
+			{"teacher can't approve", teacherToken, teacherCookie, "PUT", "/api/admin/approve/999999", nil, false},
+			{"office lists reservations", officeToken, officeCookie, "GET", "/api/admin/reservations", nil, true},
+			{"office can't change settings", officeToken, officeCookie, "PUT", "/api/admin/settings", map[string]interface{}{}, false},
+			{"auditor reads users", auditorToken, auditorCookie, "GET", "/api/admin/users", nil, true},
+			{"auditor reads settings", auditorToken, auditorCookie, "GET", "/api/admin/settings", nil, true},
+			{"auditor can't create slots", auditorToken, auditorCookie, "POST", "/api/admin/slots", map[string]interface{}{"date": "2099-01-01", "capacity": 5}, false},
+		}
+		for _, tc := range cases {
+			status, _, _ := sendJSON(t, tc.method, server.URL+tc.path, tc.csrf, tc.cookie, tc.payload)
+			if tc.expectAllowed {
+				assert.Equal(t, 200, status, tc.name)
+			} else {
+				assert.Equal(t, 403, status, tc.name)
+			}
+		}
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/profile", teacherToken, teacherCookie, nil)
+		assert.Equal(t, 200, status)
+		perms := result["user"].(map[string]interface{})["permissions"].([]interface{})
+		assert.Contains(t, perms, utils.PermCheckIn)
+		assert.NotContains(t, perms, utils.PermUsersManage)
+	})
+
+	t.Run("Custom roles", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/admin/roles", adminToken, adminCookie, map[string]interface{}{
+			"name":        "front-desk",
+			"description": "Front desk",
+			"permissions": []string{utils.PermUsersView, utils.PermUsersManage},
+		})
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "front-desk", result["role"].(map[string]interface{})["name"])
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/admin/roles", adminToken, adminCookie, map[string]interface{}{"name": "teacher"})
+		assert.Equal(t, 400, status, "built-in roles can't be redefined")
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/admin/roles", adminToken, adminCookie, map[string]interface{}{"name": "x2", "permissions": []string{"bogus"}})
+		assert.Equal(t, 400, status)
+
+		deskToken, deskCookie := login("rbac-desk@example.com", "parent")
+		var desk models.User
+		config.DB.Where("email = ?", "rbac-desk@example.com").First(&desk)
+		status, _, _ = sendJSON(t, "PUT", fmt.Sprintf("%s/api/admin/users/%d/role", server.URL, desk.ID), adminToken, adminCookie, map[string]string{"role": "front-desk"})
+		assert.Equal(t, 200, status)
+		status, _, _ = sendJSON(t, "PUT", fmt.Sprintf("%s/api/admin/users/%d/role", server.URL, desk.ID), adminToken, adminCookie, map[string]string{"role": "nope"})
+		assert.Equal(t, 400, status)
+
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/users", deskToken, deskCookie, nil)
+		assert.Equal(t, 200, status)
+
+		// Managing users doesn't allow granting more than the role has
+		var parent models.User
+		config.DB.Where("email = ?", "rbac-parent@example.com").First(&parent)
+		status, _, _ = sendJSON(t, "PUT", fmt.Sprintf("%s/api/admin/users/%d/role", server.URL, parent.ID), deskToken, deskCookie, map[string]string{"role": utils.RoleAdmin})
+		assert.Equal(t, 403, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/admin/roles", deskToken, deskCookie, map[string]interface{}{"name": "escalate", "permissions": []string{utils.PermSettingsManage}})
+		assert.Equal(t, 403, status)
+		var admin models.User
+		config.DB.Where("email = ?", "rbac-admin@example.com").First(&admin)
+		status, _, _ = sendJSON(t, "DELETE", fmt.Sprintf("%s/api/admin/users/%d", server.URL, admin.ID), deskToken, deskCookie, nil)
+		assert.Equal(t, 403, status, "users with more permissions can't be deleted")
+
+		status, _, _ = sendJSON(t, "DELETE", server.URL+"/api/admin/roles/front-desk", adminToken, adminCookie, nil)
+		assert.Equal(t, 409, status, "role still assigned")
+
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/admin/roles/front-desk", adminToken, adminCookie, map[string]interface{}{"permissions": []string{utils.PermRosterView}})
+		assert.Equal(t, 200, status)
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/users", deskToken, deskCookie, nil)
+		assert.Equal(t, 403, status, "permission changes apply immediately")
+
+		config.DB.Model(&desk).Update("role", "parent")
+		status, _, _ = sendJSON(t, "DELETE", server.URL+"/api/admin/roles/front-desk", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Last admin can't be demoted", func(t *testing.T) {
+		var admin models.User
+		config.DB.Where("email = ?", "rbac-admin@example.com").First(&admin)
+		status, _, _ := sendJSON(t, "PUT", fmt.Sprintf("%s/api/admin/users/%d/role", server.URL, admin.ID), adminToken, adminCookie, map[string]string{"role": utils.RoleParent})
+		assert.Equal(t, 409, status)
+		status, _, _ = sendJSON(t, "DELETE", fmt.Sprintf("%s/api/admin/users/%d", server.URL, admin.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 409, status, "nobody can delete their own account")
+	})
+
+	t.Run("Roster and check-in", func(t *testing.T) {
+		today := time.Now().Format("2006-01-02")
+		slot := models.Slot{Date: today, Capacity: 10}
+		config.DB.Create(&slot)
+		childID := createChild(server, parentToken, parentCookie, "Ada", 5)
+		reservation := models.Reservation{ChildID: uint(childID), SlotID: slot.ID, Status: "approved"}
+		config.DB.Create(&reservation)
+		pending := models.Reservation{ChildID: uint(childID), SlotID: slot.ID, Status: "pending"}
+		config.DB.Create(&pending)
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/admin/roster?date="+today, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 200, status)
+		children := result["children"].([]interface{})
+		assert.Len(t, children, 1, "only approved reservations are on the roster")
+		assert.Equal(t, "Ada", children[0].(map[string]interface{})["child_name"])
+
+		checkIn := fmt.Sprintf("%s/api/admin/reservations/%d/check-in", server.URL, reservation.ID)
+		checkOut := fmt.Sprintf("%s/api/admin/reservations/%d/check-out", server.URL, reservation.ID)
+
+		status, _, _ = sendJSON(t, "POST", checkOut, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 400, status, "not checked in yet")
+		status, _, _ = sendJSON(t, "POST", checkIn, officeToken, officeCookie, nil)
+		assert.Equal(t, 403, status, "office can't check in")
+		status, _, _ = sendJSON(t, "POST", checkIn, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 200, status)
+		status, _, _ = sendJSON(t, "POST", checkIn, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 409, status)
+		status, _, _ = sendJSON(t, "POST", fmt.Sprintf("%s/api/admin/reservations/%d/check-in", server.URL, pending.ID), teacherToken, teacherCookie, nil)
+		assert.Equal(t, 400, status, "pending reservations can't be checked in")
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/admin/roster?date="+today, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), result["present"])
+
+		status, _, _ = sendJSON(t, "POST", checkOut, teacherToken, teacherCookie, nil)
+		assert.Equal(t, 200, status)
+		var stored models.Reservation
+		config.DB.First(&stored, reservation.ID)
+		assert.NotNil(t, stored.CheckedInAt)
+		assert.NotNil(t, stored.CheckedOutAt)
+		assert.NotNil(t, stored.CheckedInByID)
+	})
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func approvedReservation(t *testing.T, id int) {
+	assert.NoError(t, config.DB.Model(&models.Reservation{}).Where("id = ?", id).Update("status", "approved").Error)
+}
+
+func countReminderNotifications(userID uint) int64 {
+	var count int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND event = ?", userID, utils.NotifyReminders).Count(&count)
+	return count
+}
+
+func TestReservationReminders(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "reminder-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentToken, parentCookie := registerAndLogin(server, "reminder-parent@example.com", "testpassword123", parentInit, parentInitCookie)
+	otherInit, otherInitCookie := getCSRFTokenAndCookie(server)
+	otherToken, otherCookie := registerAndLogin(server, "reminder-other@example.com", "testpassword123", otherInit, otherInitCookie)
+	var parent, other models.User
+	config.DB.Where("email = ?", "reminder-parent@example.com").First(&parent)
+	config.DB.Where("email = ?", "reminder-other@example.com").First(&other)
+
+	date := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	slotID := createSlot(server, adminToken, adminCookie, date, 5)
+	deletedSlotID := createSlot(server, adminToken, adminCookie, date, 5)
+
+	due := createReservation(server, parentToken, parentCookie, slotID, createChild(server, parentToken, parentCookie, "Due", 4))
+	cancelled := createReservation(server, parentToken, parentCookie, slotID, createChild(server, parentToken, parentCookie, "Cancelled", 4))
+	onDeletedSlot := createReservation(server, parentToken, parentCookie, deletedSlotID, createChild(server, parentToken, parentCookie, "Deleted", 4))
+	undelivered := createReservation(server, otherToken, otherCookie, slotID, createChild(server, otherToken, otherCookie, "Later", 4))
+	for _, id := range []int{due, cancelled, onDeletedSlot, undelivered} {
+		approvedReservation(t, id)
+	}
+	config.DB.Delete(&models.Reservation{}, cancelled)
+	config.DB.Delete(&models.Slot{}, deletedSlotID)
+
+	// The other parent can't be reached for now, so their reminder must not be used up
+	config.DB.Delete(&other)
+
+	start, err := utils.SlotStartTime(models.Slot{Date: date})
+	assert.NoError(t, err)
+	now := start.Add(-time.Hour)
+
+	// Only the closest of the due offsets is sent, and only once
+	assert.Equal(t, 1, utils.SendDueReminders(now))
+	assert.Equal(t, 0, utils.SendDueReminders(now.Add(time.Minute)))
+	assert.Equal(t, int64(1), countReminderNotifications(parent.ID))
+
+	var reminders []models.ReservationReminder
+	config.DB.Where("reservation_id = ?", due).Order("\"offset\"").Find(&reminders)
+	if assert.Len(t, reminders, 2) {
+		assert.Equal(t, models.ReminderSkipped, reminders[0].Status)
+		assert.Equal(t, models.ReminderSent, reminders[1].Status)
+		assert.NotNil(t, reminders[1].SentAt)
+	}
+	var skippedRows int64
+	config.DB.Model(&models.ReservationReminder{}).Where("reservation_id IN ?", []int{cancelled, onDeletedSlot}).Count(&skippedRows)
+	assert.Equal(t, int64(0), skippedRows)
+
+	// The failed reminder is retried once the parent can be reached again
+	var pending int64
+	config.DB.Model(&models.ReservationReminder{}).Where("reservation_id = ? AND status = ?", undelivered, models.ReminderPending).Count(&pending)
+	assert.Equal(t, int64(0), pending)
+	config.DB.Unscoped().Model(&other).Update("deleted_at", nil)
+	assert.Equal(t, 1, utils.SendDueReminders(now.Add(2*time.Minute)))
+	assert.Equal(t, int64(1), countReminderNotifications(other.ID))
+	assert.Equal(t, 0, utils.SendDueReminders(now.Add(3*time.Minute)))
+}
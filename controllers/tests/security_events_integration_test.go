@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"fmt"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityEvents(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "security-admin@example.com"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, "testpassword123", adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	parentInit, parentInitCookie := getCSRFTokenAndCookie(server)
+	parentEmail := "security-parent@example.com"
+	registerAndLogin(server, parentEmail, "testpassword123", parentInit, parentInitCookie)
+	var parent models.User
+	config.DB.Where("email = ?", parentEmail).First(&parent)
+
+	login := func(password string) (int, string) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, _, cookie := sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": parentEmail, "password": password})
+		return status, cookie
+	}
+	eventTypes := func(result map[string]interface{}) []string {
+		types := []string{}
+		for _, item := range result["data"].([]interface{}) {
+			types = append(types, item.(map[string]interface{})["type"].(string))
+		}
+		return types
+	}
+
+	// Pretend the login at registration came from another address, so the next one is from a new IP
+	config.DB.Model(&models.SecurityEvent{}).Where("user_id = ?", parent.ID).Update("ip", "203.0.113.50")
+
+	for i := 0; i < 3; i++ {
+		status, _ := login("wrongpassword")
+		assert.Equal(t, 401, status)
+	}
+	status, parentCookie := login("testpassword123")
+	assert.Equal(t, 200, status)
+
+	t.Run("Users see their own activity", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/security-events", "", parentCookie, nil)
+		assert.Equal(t, 200, status)
+		types := eventTypes(result)
+		assert.Equal(t, utils.SecurityLogin, types[0])
+		assert.Contains(t, types, utils.SecurityLoginFailed)
+		assert.NotContains(t, types, utils.SecurityAlert)
+		first := result["data"].([]interface{})[0].(map[string]interface{})
+		assert.NotEmpty(t, first["ip"])
+		assert.Equal(t, utils.OutcomeSuccess, first["outcome"])
+	})
+
+	t.Run("Login from a new IP after failures raises an alert", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "GET", fmt.Sprintf("%s/api/admin/security-events?type=alert&user_id=%d", server.URL, parent.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		if data := result["data"].([]interface{}); assert.Len(t, data, 1) {
+			assert.Contains(t, data[0].(map[string]interface{})["details"], "after 3 failed attempts")
+		}
+	})
+
+	t.Run("Admins filter across users", func(t *testing.T) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": "nobody-security@example.com", "password": "whatever123"})
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/admin/security-events?type=login_failed&email=Nobody-Security@example.com", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		if data := result["data"].([]interface{}); assert.Len(t, data, 1) {
+			entry := data[0].(map[string]interface{})
+			assert.Nil(t, entry["user_id"])
+			assert.Equal(t, "unknown account", entry["details"])
+		}
+
+		status, result, _ = sendJSON(t, "GET", fmt.Sprintf("%s/api/admin/security-events?user_id=%d&outcome=failure&type=login_failed", server.URL, parent.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(3), result["pagination"].(map[string]interface{})["total"])
+
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/security-events?type=bogus", adminToken, adminCookie, nil)
+		assert.Equal(t, 400, status)
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/security-events?since=yesterday", adminToken, adminCookie, nil)
+		assert.Equal(t, 400, status)
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/admin/security-events", "", parentCookie, nil)
+		assert.Equal(t, 403, status)
+	})
+
+	t.Run("Role changes record the admin", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "PUT", fmt.Sprintf("%s/api/admin/users/%d/role", server.URL, parent.ID), adminToken, adminCookie, map[string]string{"role": utils.RoleTeacher})
+		assert.Equal(t, 200, status)
+
+		var event models.SecurityEvent
+		config.DB.Where("type = ? AND user_id = ?", utils.SecurityRoleChanged, parent.ID).First(&event)
+		assert.Equal(t, "parent → teacher", event.Details)
+		var admin models.User
+		config.DB.Where("email = ?", adminEmail).First(&admin)
+		if assert.NotNil(t, event.ActorID) {
+			assert.Equal(t, admin.ID, *event.ActorID)
+		}
+	})
+}
+
+func TestPasswordSprayAlert(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	ip := "198.51.100.77"
+	failure := func(i int) models.SecurityEvent {
+		return models.SecurityEvent{Type: utils.SecurityLoginFailed, Outcome: utils.OutcomeFailure, Email: fmt.Sprintf("spray-%d@example.com", i), IP: ip, CreatedAt: time.Now()}
+	}
+	alerts := func() int64 {
+		var count int64
+		config.DB.Model(&models.SecurityEvent{}).Where("type = ? AND ip = ?", utils.SecurityAlert, ip).Count(&count)
+		return count
+	}
+
+	// Failures recorded concurrently can step past the threshold without ever landing on it
+	for i := 0; i < 11; i++ {
+		event := failure(i)
+		assert.NoError(t, config.DB.Create(&event).Error)
+	}
+	assert.Equal(t, int64(0), alerts())
+
+	utils.RecordSecurityEvent(nil, failure(11))
+	assert.Equal(t, int64(1), alerts())
+
+	// Later failures within the window don't raise another alert
+	utils.RecordSecurityEvent(nil, failure(12))
+	utils.RecordSecurityEvent(nil, failure(13))
+	assert.Equal(t, int64(1), alerts())
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reservio/config"
+	"reservio/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loginDevice logs in from a "device" with its own cookie and User-Agent and returns
+// the CSRF token and session cookie
+func loginDevice(t *testing.T, server *httptest.Server, email, password, userAgent string) (string, string) {
+	initToken, cookie := getCSRFTokenAndCookie(server)
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req, _ := http.NewRequest("POST", server.URL+"/api/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", initToken)
+	req.Header.Set("User-Agent", userAgent)
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			cookie = c.Name + "=" + c.Value
+		}
+	}
+
+	// Fresh CSRF token for the logged-in session
+	getReq, _ := http.NewRequest("GET", server.URL+"/api/user/profile", nil)
+	getReq.Header.Set("Cookie", cookie)
+	getReq.Header.Set("User-Agent", userAgent)
+	getResp, err := http.DefaultClient.Do(getReq)
+	assert.NoError(t, err)
+	defer getResp.Body.Close()
+	for _, c := range getResp.Cookies() {
+		if c.Name == "session" {
+			cookie = c.Name + "=" + c.Value
+		}
+	}
+	return getResp.Header.Get("X-CSRF-Token"), cookie
+}
+
+func listSessions(t *testing.T, server *httptest.Server, csrfToken, cookie string) (int, []interface{}) {
+	status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/sessions", csrfToken, cookie, nil)
+	if status != 200 {
+		return status, nil
+	}
+	return status, result["sessions"].([]interface{})
+}
+
+func TestSessionManagement(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	email := "sessions@example.com"
+	password := "testpassword123"
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	registerAndLogin(server, email, password, initToken, initCookie)
+
+	laptopToken, laptopCookie := loginDevice(t, server, email, password, "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	_, phoneCookie := loginDevice(t, server, email, password, "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) Version/17.5 Safari/604.1")
+
+	var phoneID float64
+	t.Run("List sessions", func(t *testing.T) {
+		status, sessions := listSessions(t, server, laptopToken, laptopCookie)
+		assert.Equal(t, 200, status)
+		assert.Len(t, sessions, 3) // registration, laptop, phone
+
+		currents := 0
+		for _, raw := range sessions {
+			s := raw.(map[string]interface{})
+			if s["current"] == true {
+				currents++
+				assert.Equal(t, "Firefox on Linux", s["device"])
+			}
+			if s["device"] == "Safari on iOS" {
+				phoneID = s["id"].(float64)
+			}
+		}
+		assert.Equal(t, 1, currents)
+		assert.NotZero(t, phoneID)
+	})
+
+	t.Run("Revoke another session", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "DELETE", fmt.Sprintf("%s/api/user/sessions/%d", server.URL, int(phoneID)), laptopToken, laptopCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, _ = listSessions(t, server, "", phoneCookie)
+		assert.Equal(t, 401, status, "revoked session is logged out")
+
+		status, _, _ = sendJSON(t, "DELETE", fmt.Sprintf("%s/api/user/sessions/%d", server.URL, int(phoneID)), laptopToken, laptopCookie, nil)
+		assert.Equal(t, 404, status)
+	})
+
+	t.Run("Revoke other sessions", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "DELETE", server.URL+"/api/user/sessions", laptopToken, laptopCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), result["revoked"]) // the registration session
+
+		status, sessions := listSessions(t, server, laptopToken, laptopCookie)
+		assert.Equal(t, 200, status)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("Logout ends the tracked session", func(t *testing.T) {
+		tabToken, tabCookie := loginDevice(t, server, email, password, "curl/8.5.0")
+		status, _, _ := sendJSON(t, "POST", server.URL+"/api/auth/logout", tabToken, tabCookie, nil)
+		assert.Equal(t, 200, status)
+
+		var active int64
+		config.DB.Model(&models.UserSession{}).Where("user_agent = ? AND revoked_at IS NULL", "curl/8.5.0").Count(&active)
+		assert.Equal(t, int64(0), active)
+	})
+
+	t.Run("Admin revokes a user's sessions", func(t *testing.T) {
+		adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+		adminToken, adminCookie := registerAndLogin(server, "sessions-admin@example.com", password, adminInit, adminInitCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", "sessions-admin@example.com").Update("role", "admin")
+
+		var user models.User
+		config.DB.Where("email = ?", email).First(&user)
+
+		status, result, _ := sendJSON(t, "GET", fmt.Sprintf("%s/api/admin/users/%d/sessions", server.URL, user.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Len(t, result["sessions"], 1)
+
+		status, _, _ = sendJSON(t, "DELETE", fmt.Sprintf("%s/api/admin/users/%d/sessions", server.URL, user.ID), adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+
+		status, _ = listSessions(t, server, laptopToken, laptopCookie)
+		assert.Equal(t, 401, status)
+	})
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readSSEData returns the data of the next event on the stream
+func readSSEData(t *testing.T, reader *bufio.Reader) map[string]interface{} {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatal(err)
+			}
+			return data
+		}
+	}
+}
+
+func TestSlotAvailabilityStream(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "stream-admin@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+
+	date := time.Now().AddDate(0, 0, 5).Format("2006-01-02")
+	slotID := createSlot(server, csrfToken, cookie, date, 2)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(server.URL + "/api/slots/stream?slot_ids=" + strconv.Itoa(slotID))
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	snapshot := readSSEData(t, reader)
+	assert.Equal(t, "snapshot", snapshot["event"])
+	assert.Equal(t, float64(2), snapshot["available_slots"])
+
+	childID := createChild(server, csrfToken, cookie, "Streamy", 5)
+	createReservation(server, csrfToken, cookie, slotID, childID)
+
+	update := readSSEData(t, reader)
+	assert.Equal(t, "reservation.created", update["event"])
+	assert.Equal(t, float64(1), update["booked_slots"])
+	assert.Equal(t, float64(1), update["available_slots"])
+	assert.Equal(t, false, update["is_full"])
+
+	// Invalid filters are rejected
+	badResp, err := client.Get(server.URL + "/api/slots/stream?from=tomorrow")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, badResp.StatusCode)
+}
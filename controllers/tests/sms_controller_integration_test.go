@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"reservio/config"
+	"reservio/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRejectsInvalidPhone(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	csrfToken, cookie := getCSRFTokenAndCookie(server)
+
+	body, _ := json.Marshal(map[string]string{"email": "badphone@example.com", "password": "testpassword123", "phone": "call me"})
+	req, _ := http.NewRequest("POST", server.URL+"/api/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", csrfToken)
+	req.Header.Set("Cookie", cookie)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestSMSOptInAndInboundStop(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	csrfToken, cookie := registerAndLogin(server, "sms@example.com", "testpassword123", initToken, initCookie)
+
+	post := func(path string) *http.Response {
+		req, _ := http.NewRequest("POST", server.URL+path, nil)
+		req.Header.Set("X-CSRF-Token", csrfToken)
+		req.Header.Set("Cookie", cookie)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// No phone yet: opt-in is refused
+	assert.Equal(t, 400, post("/api/user/sms/opt-in").StatusCode)
+
+	// Phone is normalized to E.164 on profile update
+	profile, _ := json.Marshal(map[string]string{"phone": "00420 601 234 567"})
+	putReq, _ := http.NewRequest("PUT", server.URL+"/api/user/profile", bytes.NewReader(profile))
+	putReq.Header.Set("Content-Type", "application/json")
+	putReq.Header.Set("X-CSRF-Token", csrfToken)
+	putReq.Header.Set("Cookie", cookie)
+	putResp, err := http.DefaultClient.Do(putReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, putResp.StatusCode)
+
+	assert.Equal(t, 200, post("/api/user/sms/opt-in").StatusCode)
+	var user models.User
+	config.DB.Where("email = ?", "sms@example.com").First(&user)
+	assert.Equal(t, "+420601234567", user.Phone)
+	assert.True(t, user.SMSOptIn)
+
+	// Inbound STOP from the provider opts the number out
+	os.Setenv("SMS_INBOUND_TOKEN", "inbound-secret")
+	defer os.Unsetenv("SMS_INBOUND_TOKEN")
+	form := url.Values{"From": {"+420601234567"}, "Body": {" stop "}}
+	badResp, err := http.Post(server.URL+"/api/sms/inbound?token=wrong", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, badResp.StatusCode)
+	stopResp, err := http.Post(server.URL+"/api/sms/inbound?token=inbound-secret", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, stopResp.StatusCode)
+
+	config.DB.First(&user, user.ID)
+	assert.False(t, user.SMSOptIn)
+}
@@ -26,7 +26,7 @@ func setupTestApp() *httptest.Server {
 }
 
 func cleanupTestDB(db *gorm.DB) {
-	db.Exec("TRUNCATE TABLE users, children, reservations, slots RESTART IDENTITY CASCADE;")
+	db.Exec("TRUNCATE TABLE users, children, reservations, slots, notification_preferences, notifications, reservation_reminders, webhook_endpoints, webhook_deliveries, activity_events, announcements, announcement_attachments, announcement_receipts, recovery_codes, settings, api_tokens, invitations, user_sessions, login_throttles, roles, impersonations, impersonation_requests, magic_link_tokens, password_histories, security_events, deferred_notifications RESTART IDENTITY CASCADE;")
 }
 
 func getCSRFTokenAndCookie(server *httptest.Server) (string, string) {
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sendJSON sends a JSON request and returns the status, decoded body and updated session cookie
+func sendJSON(t *testing.T, method, url, csrfToken, cookie string, payload interface{}) (int, map[string]interface{}, string) {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, url, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", csrfToken)
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			cookie = c.Name + "=" + c.Value
+		}
+	}
+	var result map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result, cookie
+}
+
+func TestTwoFactorAuthentication(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	adminInit, adminInitCookie := getCSRFTokenAndCookie(server)
+	adminEmail := "2fa-admin@example.com"
+	password := "testpassword123"
+	adminToken, adminCookie := registerAndLogin(server, adminEmail, password, adminInit, adminInitCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role", "admin")
+
+	var secret string
+	var recoveryCodes []interface{}
+	step := utils.TOTPStep(time.Now())
+
+	t.Run("Enrollment", func(t *testing.T) {
+		status, result, _ := sendJSON(t, "POST", server.URL+"/api/user/2fa/enable", adminToken, adminCookie, map[string]string{"code": "123456"})
+		assert.Equal(t, 400, status, "enable before setup")
+
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/setup", adminToken, adminCookie, nil)
+		assert.Equal(t, 200, status)
+		secret = result["secret"].(string)
+		assert.Contains(t, result["otpauth_uri"], "otpauth://totp/")
+
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/enable", adminToken, adminCookie, map[string]string{"code": "000000"})
+		assert.Equal(t, 400, status)
+
+		code, _ := utils.TOTPCode(secret, step)
+		status, result, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/enable", adminToken, adminCookie, map[string]string{"code": code})
+		assert.Equal(t, 200, status)
+		recoveryCodes = result["recovery_codes"].([]interface{})
+		assert.Len(t, recoveryCodes, utils.RecoveryCodeCount)
+	})
+
+	t.Run("Login requires the second factor", func(t *testing.T) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, result, cookie := sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": adminEmail, "password": password})
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, result["two_factor_required"])
+
+		// Not logged in yet
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/user/profile", "", cookie, nil)
+		assert.Equal(t, 401, status)
+
+		status, result, cookie = sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"code": "000000"})
+		assert.Equal(t, 401, status)
+		assert.Equal(t, utils.ErrInvalidTwoFactor, result["code"])
+
+		// The code used for enrollment can't be replayed; the next one is accepted
+		used, _ := utils.TOTPCode(secret, step)
+		status, _, cookie = sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"code": used})
+		assert.Equal(t, 401, status)
+		next, _ := utils.TOTPCode(secret, step+1)
+		status, _, cookie = sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"code": next})
+		assert.Equal(t, 200, status)
+
+		status, _, _ = sendJSON(t, "GET", server.URL+"/api/user/profile", "", cookie, nil)
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Recovery codes work once", func(t *testing.T) {
+		code := recoveryCodes[0].(string)
+		for i, want := range []int{200, 401} {
+			initToken, initCookie := getCSRFTokenAndCookie(server)
+			_, _, cookie := sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": adminEmail, "password": password})
+			status, _, _ := sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"recovery_code": code})
+			assert.Equal(t, want, status, "attempt %d", i+1)
+		}
+	})
+
+	t.Run("Mandatory admin 2FA", func(t *testing.T) {
+		otherInit, otherInitCookie := getCSRFTokenAndCookie(server)
+		otherEmail := "2fa-other-admin@example.com"
+		otherToken, otherCookie := registerAndLogin(server, otherEmail, password, otherInit, otherInitCookie)
+		config.DB.Model(&models.User{}).Where("email = ?", otherEmail).Update("role", "admin")
+
+		// An admin without 2FA can't make it mandatory
+		status, result, _ := sendJSON(t, "PUT", server.URL+"/api/admin/settings", otherToken, otherCookie, map[string]interface{}{"require_admin_2fa": true})
+		assert.Equal(t, 400, status)
+		assert.Equal(t, utils.ErrTwoFactorRequired, result["code"])
+
+		status, result, _ = sendJSON(t, "PUT", server.URL+"/api/admin/settings", adminToken, adminCookie, map[string]interface{}{"require_admin_2fa": true})
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, result["settings"].(map[string]interface{})["require_admin_2fa"])
+
+		status, result, _ = sendJSON(t, "GET", server.URL+"/api/admin/users", "", otherCookie, nil)
+		assert.Equal(t, 403, status)
+		assert.Equal(t, utils.ErrTwoFactorRequired, result["code"])
+
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		_, result, _ = sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": otherEmail, "password": password})
+		assert.Equal(t, true, result["two_factor_setup_required"])
+
+		// 2FA can't be turned off while it is mandatory
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/disable", adminToken, adminCookie, map[string]string{"password": password, "recovery_code": recoveryCodes[1].(string)})
+		assert.Equal(t, 403, status)
+
+		status, _, _ = sendJSON(t, "PUT", server.URL+"/api/admin/settings", adminToken, adminCookie, map[string]interface{}{"require_admin_2fa": false})
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Disabling requires re-authentication", func(t *testing.T) {
+		status, _, _ := sendJSON(t, "POST", server.URL+"/api/user/2fa/disable", adminToken, adminCookie, map[string]string{"password": "wrongpassword", "recovery_code": recoveryCodes[1].(string)})
+		assert.Equal(t, 401, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/disable", adminToken, adminCookie, map[string]string{"password": password})
+		assert.Equal(t, 401, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/user/2fa/disable", adminToken, adminCookie, map[string]string{"password": password, "recovery_code": recoveryCodes[1].(string)})
+		assert.Equal(t, 200, status)
+
+		status, result, _ := sendJSON(t, "GET", server.URL+"/api/user/2fa", "", adminCookie, nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, false, result["enabled"])
+	})
+}
+
+func TestTwoFactorCodesCountTowardsLockout(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "2fa-lockout@example.com"
+	password := "testpassword123"
+	registerAndLogin(server, email, password, initToken, initCookie)
+	secret := "JBSWY3DPEHPK3PXP"
+	config.DB.Model(&models.User{}).Where("email = ?", email).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true})
+
+	login := func() (int, string) {
+		initToken, initCookie := getCSRFTokenAndCookie(server)
+		status, _, cookie := sendJSON(t, "POST", server.URL+"/api/auth/login", initToken, initCookie, map[string]string{"email": email, "password": password})
+		return status, cookie
+	}
+
+	// Logging in again with the right password doesn't reset the count of wrong codes
+	var cookie string
+	for i := 0; i < 5; i++ {
+		var status int
+		status, cookie = login()
+		assert.Equal(t, 200, status)
+		status, _, _ = sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"code": "000000"})
+		assert.Equal(t, 401, status, "attempt %d", i+1)
+	}
+
+	status, _ := login()
+	assert.Equal(t, 429, status)
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	status, result, _ := sendJSON(t, "POST", server.URL+"/api/auth/2fa/verify", "", cookie, map[string]string{"code": code})
+	assert.Equal(t, 429, status, "a pending login can't keep guessing while locked")
+	assert.Equal(t, utils.ErrLoginLocked, result["code"])
+}
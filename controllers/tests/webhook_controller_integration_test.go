@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reservio/config"
+	"reservio/models"
+	"reservio/utils"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeliveryAndReplay(t *testing.T) {
+	server := setupTestApp()
+	defer server.Close()
+
+	// Local receiver that records signed deliveries
+	var mu sync.Mutex
+	var received []string
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if !utils.VerifyWebhookSignature(secret, r.Header.Get(utils.WebhookSignatureHeader), body, time.Minute) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received = append(received, r.Header.Get(utils.WebhookEventHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	initToken, initCookie := getCSRFTokenAndCookie(server)
+	email := "webhook-admin@example.com"
+	csrfToken, cookie := registerAndLogin(server, email, "testpassword123", initToken, initCookie)
+	config.DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+
+	// Register the endpoint
+	createBody, _ := json.Marshal(map[string]interface{}{"url": receiver.URL, "events": []string{"slot.created"}})
+	createReq, _ := http.NewRequest("POST", server.URL+"/api/admin/webhooks", bytes.NewReader(createBody))
+	createReq.Header.Set("Content-Type", "application/json")
+	createReq.Header.Set("X-CSRF-Token", csrfToken)
+	createReq.Header.Set("Cookie", cookie)
+	createResp, err := http.DefaultClient.Do(createReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, createResp.StatusCode)
+	var createResult map[string]interface{}
+	if err := json.NewDecoder(createResp.Body).Decode(&createResult); err != nil {
+		t.Fatal(err)
+	}
+	webhook := createResult["webhook"].(map[string]interface{})
+	mu.Lock()
+	secret = webhook["secret"].(string)
+	mu.Unlock()
+	webhookID := int(webhook["id"].(float64))
+
+	// Unknown event types are rejected
+	badBody, _ := json.Marshal(map[string]interface{}{"url": receiver.URL, "events": []string{"slot.exploded"}})
+	badReq, _ := http.NewRequest("POST", server.URL+"/api/admin/webhooks", bytes.NewReader(badBody))
+	badReq.Header.Set("Content-Type", "application/json")
+	badReq.Header.Set("X-CSRF-Token", csrfToken)
+	badReq.Header.Set("Cookie", cookie)
+	badResp, err := http.DefaultClient.Do(badReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, badResp.StatusCode)
+
+	// Creating a slot queues a delivery; the worker isn't running in tests, so process it here
+	createSlot(server, csrfToken, cookie, "2030-01-15", 5)
+	utils.ProcessWebhookDeliveries(time.Now())
+
+	mu.Lock()
+	assert.Equal(t, []string{"slot.created"}, received)
+	mu.Unlock()
+
+	listReq, _ := http.NewRequest("GET", server.URL+"/api/admin/webhooks/"+strconv.Itoa(webhookID)+"/deliveries", nil)
+	listReq.Header.Set("Cookie", cookie)
+	listResp, err := http.DefaultClient.Do(listReq)
+	assert.NoError(t, err)
+	var listResult map[string]interface{}
+	if err := json.NewDecoder(listResp.Body).Decode(&listResult); err != nil {
+		t.Fatal(err)
+	}
+	deliveries := listResult["data"].([]interface{})
+	if !assert.Len(t, deliveries, 1) {
+		return
+	}
+	delivery := deliveries[0].(map[string]interface{})
+	assert.Equal(t, "succeeded", delivery["status"])
+	assert.Equal(t, float64(204), delivery["response_code"])
+
+	// Replay sends the same payload again
+	replayReq, _ := http.NewRequest("POST", server.URL+"/api/admin/webhooks/deliveries/"+strconv.Itoa(int(delivery["id"].(float64)))+"/replay", nil)
+	replayReq.Header.Set("X-CSRF-Token", csrfToken)
+	replayReq.Header.Set("Cookie", cookie)
+	replayResp, err := http.DefaultClient.Do(replayReq)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, replayResp.StatusCode)
+	utils.ProcessWebhookDeliveries(time.Now())
+
+	mu.Lock()
+	assert.Len(t, received, 2)
+	mu.Unlock()
+}
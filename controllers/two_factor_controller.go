@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// twoFactorRequest carries a TOTP code or a recovery code, plus the password when the
+// action requires re-authentication
+type twoFactorRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func currentUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Not authenticated", nil))
+		return user, false
+	}
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "User not found", map[string]interface{}{
+			"user_id": userID,
+		}))
+		return user, false
+	}
+	return user, true
+}
+
+// reauthenticate checks the password and second factor before sensitive 2FA changes
+func reauthenticate(user models.User, body twoFactorRequest) error {
+	if body.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)) != nil {
+		return utils.NewValidationError(utils.ErrUnauthorized, "Invalid password", nil)
+	}
+	if !utils.VerifyTwoFactor(user, body.Code, body.RecoveryCode, time.Now()) {
+		return utils.NewValidationError(utils.ErrInvalidTwoFactor, "Invalid two-factor code", nil)
+	}
+	return nil
+}
+
+// VerifyTwoFactorLogin completes a login for accounts with two-factor authentication,
+// after Login accepted the password. Accepts a TOTP "code" or a "recovery_code".
+func VerifyTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var body twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if body.Code == "" && body.RecoveryCode == "" {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "code or recovery_code is required", nil))
+		return
+	}
+
+	userID, ok := utils.PendingTwoFactorUser(r)
+	if !ok {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "No pending login, please log in again", nil))
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "No pending login, please log in again", nil))
+		return
+	}
+
+	// Wrong codes count against the same lockout as wrong passwords, so logging in again
+	// doesn't buy a fresh set of guesses
+	now := time.Now()
+	ip := utils.ClientIP(r)
+	if wait := utils.LoginLockedFor(user.Email, ip, now); wait > 0 {
+		retryAfter := int(wait.Round(time.Second).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError(utils.ErrLoginLocked, "Too many failed login attempts. Please try again later.", map[string]interface{}{
+			"retry_after": retryAfter,
+		}))
+		return
+	}
+
+	if !utils.VerifyTwoFactor(user, body.Code, body.RecoveryCode, now) {
+		utils.RecordLoginFailure(&user, user.Email, ip, now)
+		remaining := utils.FailPendingTwoFactor(w, r)
+		utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLoginFailed, utils.OutcomeFailure, &user, "wrong two-factor code"))
+		zap.L().Debug("Invalid two-factor code", zap.Uint("user_id", user.ID), zap.Int("attempts_remaining", remaining))
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrInvalidTwoFactor, "Invalid two-factor code", map[string]interface{}{
+			"attempts_remaining": remaining,
+		}))
+		return
+	}
+
+	utils.RecordLoginSuccess(user.Email, ip)
+	utils.CompleteTwoFactorLogin(w, r, user.ID)
+	utils.RecordSecurityEvent(r, utils.SecurityEventFor(utils.SecurityLogin, utils.OutcomeSuccess, &user, "two-factor"))
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":                  "Logged in successfully",
+		"recovery_codes_remaining": utils.RemainingRecoveryCodes(user.ID),
+		"user": map[string]interface{}{
+			"id":              user.ID,
+			"email":           user.Email,
+			"role":            user.Role,
+			"first_name":      user.FirstName,
+			"last_name":       user.LastName,
+			"phone":           user.Phone,
+			"profile_picture": user.ProfilePicture,
+		},
+	})
+}
+
+// GetTwoFactorStatus returns whether two-factor authentication is enabled for the current user
+func GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	status := map[string]interface{}{
+		"enabled":    user.TOTPEnabled,
+		"enabled_at": user.TOTPEnabledAt,
+		"required":   utils.TwoFactorRequired(user),
+	}
+	if user.TOTPEnabled {
+		status["recovery_codes_remaining"] = utils.RemainingRecoveryCodes(user.ID)
+	}
+	utils.RespondWithSuccess(w, status)
+}
+
+// SetupTwoFactor starts enrollment: it generates a new secret and returns it with the
+// otpauth:// URI for authenticator apps. Nothing changes at login until EnableTwoFactor.
+func SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidInput, "Two-factor authentication is already enabled", nil))
+		return
+	}
+
+	secret := utils.GenerateTOTPSecret()
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(secret, user.Email),
+		"message":     "Add the secret to your authenticator app, then confirm with a code",
+	})
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator app and returns
+// the recovery codes (shown only this once)
+func EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if user.TOTPEnabled {
+		utils.RespondWithValidationError(w, http.StatusConflict, utils.NewValidationError(utils.ErrInvalidInput, "Two-factor authentication is already enabled", nil))
+		return
+	}
+	if user.TOTPSecret == "" {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Start two-factor setup first", nil))
+		return
+	}
+
+	now := time.Now()
+	step, valid := utils.ValidateTOTP(user.TOTPSecret, body.Code, now, user.TOTPLastStep)
+	if !valid {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidTwoFactor, "Invalid two-factor code", nil))
+		return
+	}
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":    true,
+		"totp_enabled_at": now,
+		"totp_last_step":  step,
+	}).Error; err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+	codes, err := utils.ReplaceRecoveryCodes(user.ID)
+	if err != nil {
+		zap.L().Error("Failed to create recovery codes", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create recovery codes")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns two-factor authentication off. It requires the password and a
+// current code (or recovery code), and is refused when the user's role requires 2FA.
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if !user.TOTPEnabled {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Two-factor authentication is not enabled", nil))
+		return
+	}
+	if utils.TwoFactorRequired(user) {
+		utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrTwoFactorRequired, "Two-factor authentication is mandatory for your role", nil))
+		return
+	}
+	if err := reauthenticate(user, body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, err.(utils.ValidationError))
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":    false,
+		"totp_enabled_at": nil,
+		"totp_secret":     "",
+		"totp_last_step":  0,
+	}).Error; err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	config.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+
+	if err := utils.NotifyUser(user, utils.NotificationMessage{
+		Event:   utils.NotifyAccount,
+		Subject: "Two-factor authentication disabled",
+		Body:    "Two-factor authentication was turned off for your account. If this wasn't you, reset your password and contact us.",
+	}); err != nil {
+		zap.L().Warn("Failed to send 2FA disabled notice", zap.Error(err))
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after re-authentication
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var body twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if !user.TOTPEnabled {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Two-factor authentication is not enabled", nil))
+		return
+	}
+	if err := reauthenticate(user, body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusUnauthorized, err.(utils.ValidationError))
+		return
+	}
+
+	codes, err := utils.ReplaceRecoveryCodes(user.ID)
+	if err != nil {
+		zap.L().Error("Failed to create recovery codes", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create recovery codes")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reservio/config"
+	"reservio/middleware"
+	"reservio/models"
+	"reservio/utils"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func webhookResponse(endpoint models.WebhookEndpoint) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          endpoint.ID,
+		"url":         endpoint.URL,
+		"events":      strings.Split(endpoint.Events, ","),
+		"description": endpoint.Description,
+		"active":      endpoint.Active,
+		"created_at":  endpoint.CreatedAt,
+	}
+}
+
+func webhookDeliveryResponse(d models.WebhookDelivery) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              d.ID,
+		"endpoint_id":     d.EndpointID,
+		"event_id":        d.EventID,
+		"event":           d.Event,
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"response_code":   d.ResponseCode,
+		"response_body":   d.ResponseBody,
+		"error":           d.Error,
+		"next_attempt_at": d.NextAttemptAt,
+		"delivered_at":    d.DeliveredAt,
+		"replay_of_id":    d.ReplayOfID,
+		"created_at":      d.CreatedAt,
+	}
+}
+
+func findWebhook(w http.ResponseWriter, r *http.Request) (models.WebhookEndpoint, bool) {
+	var endpoint models.WebhookEndpoint
+	idStr := mux.Vars(r)["id"]
+	webhookID, err := utils.ParseUint(idStr)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid webhook ID", map[string]interface{}{
+			"webhook_id": idStr,
+		}))
+		return endpoint, false
+	}
+	if err := config.DB.First(&endpoint, webhookID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Webhook not found", map[string]interface{}{
+			"webhook_id": webhookID,
+		}))
+		return endpoint, false
+	}
+	return endpoint, true
+}
+
+// ListWebhooks returns all registered webhook endpoints (admin only)
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	var endpoints []models.WebhookEndpoint
+	if err := config.DB.Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		zap.L().Error("Failed to get webhooks", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve webhooks")
+		return
+	}
+
+	data := []map[string]interface{}{}
+	for _, endpoint := range endpoints {
+		data = append(data, webhookResponse(endpoint))
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"webhooks": data,
+	})
+}
+
+// CreateWebhook registers a new endpoint. The signing secret is only returned here
+// (and when rotated), so the caller must store it.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(uint)
+	var body struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Description string   `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if err := utils.ValidateWebhookURL(body.URL); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid webhook data")
+		}
+		return
+	}
+	if err := utils.ValidateWebhookEvents(body.Events); err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid webhook data")
+		}
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		URL:         body.URL,
+		Secret:      utils.GenerateWebhookSecret(),
+		Events:      strings.Join(body.Events, ","),
+		Description: body.Description,
+		Active:      true,
+		CreatedByID: userID,
+	}
+	if err := config.DB.Create(&endpoint).Error; err != nil {
+		zap.L().Error("Failed to create webhook", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	resp := webhookResponse(endpoint)
+	resp["secret"] = endpoint.Secret
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Webhook created successfully",
+		"webhook": resp,
+	})
+}
+
+// UpdateWebhook changes an endpoint's URL, events, description or active flag.
+// Set rotate_secret to issue a new signing secret.
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := findWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		URL          *string  `json:"url"`
+		Events       []string `json:"events"`
+		Description  *string  `json:"description"`
+		Active       *bool    `json:"active"`
+		RotateSecret bool     `json:"rotate_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid JSON input", nil))
+		return
+	}
+	if body.URL != nil {
+		if err := utils.ValidateWebhookURL(*body.URL); err != nil {
+			if validationErr, ok := err.(utils.ValidationError); ok {
+				utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+			} else {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid webhook data")
+			}
+			return
+		}
+		endpoint.URL = *body.URL
+	}
+	if body.Events != nil {
+		if err := utils.ValidateWebhookEvents(body.Events); err != nil {
+			if validationErr, ok := err.(utils.ValidationError); ok {
+				utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+			} else {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid webhook data")
+			}
+			return
+		}
+		endpoint.Events = strings.Join(body.Events, ",")
+	}
+	if body.Description != nil {
+		endpoint.Description = *body.Description
+	}
+	if body.Active != nil {
+		endpoint.Active = *body.Active
+	}
+	if body.RotateSecret {
+		endpoint.Secret = utils.GenerateWebhookSecret()
+	}
+
+	if err := config.DB.Save(&endpoint).Error; err != nil {
+		zap.L().Error("Failed to update webhook", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	resp := webhookResponse(endpoint)
+	if body.RotateSecret {
+		resp["secret"] = endpoint.Secret
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message": "Webhook updated successfully",
+		"webhook": resp,
+	})
+}
+
+// DeleteWebhook removes an endpoint; its pending deliveries fail on their next attempt
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := findWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := config.DB.Delete(&endpoint).Error; err != nil {
+		zap.L().Error("Failed to delete webhook", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":    "Webhook deleted successfully",
+		"webhook_id": endpoint.ID,
+	})
+}
+
+// ListWebhookDeliveries returns the delivery log of an endpoint, newest first (paginated).
+// Optional status filter: pending, succeeded or failed.
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := findWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	page, perPage, err := utils.ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("per_page"))
+	if err != nil {
+		if validationErr, ok := err.(utils.ValidationError); ok {
+			utils.RespondWithValidationError(w, http.StatusBadRequest, validationErr)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		}
+		return
+	}
+
+	query := config.DB.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	offset := (page - 1) * perPage
+	if err := query.Order("created_at DESC").Offset(offset).Limit(perPage).Find(&deliveries).Error; err != nil {
+		zap.L().Error("Failed to get webhook deliveries", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	data := []map[string]interface{}{}
+	for _, d := range deliveries {
+		data = append(data, webhookDeliveryResponse(d))
+	}
+
+	utils.RespondWithPaginatedData(w, data, page, perPage, int(total))
+}
+
+// ReplayWebhookDelivery queues a delivery again with its original payload
+func ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	deliveryID, err := utils.ParseUint(idStr)
+	if err != nil {
+		utils.RespondWithValidationError(w, http.StatusBadRequest, utils.NewValidationError(utils.ErrInvalidInput, "Invalid delivery ID", map[string]interface{}{
+			"delivery_id": idStr,
+		}))
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := config.DB.First(&original, deliveryID).Error; err != nil {
+		utils.RespondWithValidationError(w, http.StatusNotFound, utils.NewValidationError(utils.ErrNotFound, "Delivery not found", map[string]interface{}{
+			"delivery_id": deliveryID,
+		}))
+		return
+	}
+
+	replay, err := utils.ReplayWebhookDelivery(original)
+	if err != nil {
+		zap.L().Error("Failed to replay webhook delivery", zap.Error(err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to replay delivery")
+		return
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"message":  "Delivery queued for replay",
+		"delivery": webhookDeliveryResponse(replay),
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"reservio/config"
 	"reservio/models"
@@ -19,9 +20,78 @@ type ContextKey string
 
 const UserIDKey ContextKey = "user_id"
 
-// Protected middleware checks for a valid session and user_id
+// RoleKey holds the user's role on staff routes (set by StaffOnly)
+const RoleKey ContextKey = "role"
+
+// ImpersonationKey holds the models.Impersonation while an admin acts as another user
+const ImpersonationKey ContextKey = "impersonation"
+
+// APITokenKey holds the models.APIToken when the request authenticated with a Bearer token
+const APITokenKey ContextKey = "api_token"
+
+// APITokenFromContext returns the API token a request authenticated with, if any
+func APITokenFromContext(r *http.Request) (models.APIToken, bool) {
+	token, ok := r.Context().Value(APITokenKey).(models.APIToken)
+	return token, ok
+}
+
+// ImpersonationFromContext returns the impersonation the request runs under, if any
+func ImpersonationFromContext(r *http.Request) (models.Impersonation, bool) {
+	imp, ok := r.Context().Value(ImpersonationKey).(models.Impersonation)
+	return imp, ok
+}
+
+// serveImpersonated runs the request as the impersonated user, flags the response with
+// X-Impersonated-By and adds the request to the impersonation's audit trail
+func serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, imp models.Impersonation) {
+	ctx := context.WithValue(r.Context(), UserIDKey, imp.UserID)
+	ctx = context.WithValue(ctx, ImpersonationKey, imp)
+	w.Header().Set("X-Impersonated-By", strconv.FormatUint(uint64(imp.AdminID), 10))
+	w.Header().Set("X-Impersonation-ID", strconv.FormatUint(uint64(imp.ID), 10))
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rw, r.WithContext(ctx))
+	utils.RecordImpersonationRequest(imp, r, rw.status)
+}
+
+// authenticateBearer resolves an "Authorization: Bearer" token to its user. Tokens of
+// deleted users are rejected.
+func authenticateBearer(r *http.Request, raw string) (models.APIToken, bool) {
+	token, ok := utils.AuthenticateAPIToken(raw, utils.ClientIP(r), time.Now())
+	if !ok {
+		return token, false
+	}
+	var usr models.User
+	if err := config.DB.Select("id").First(&usr, token.UserID).Error; err != nil {
+		return token, false
+	}
+	return token, true
+}
+
+// Protected middleware checks for a valid session and user_id, or a valid Bearer API token
 func Protected(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// API clients authenticate with a personal access token instead of the session cookie
+		if raw, ok := utils.BearerToken(r); ok {
+			token, ok := authenticateBearer(r, raw)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Invalid or expired token", nil))
+				return
+			}
+			if !utils.APITokenAllowsMethod(token, r.Method) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+				utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrInsufficientScope, "Token scope does not allow this request", map[string]interface{}{
+					"scopes": strings.Split(token.Scopes, ","),
+				}))
+				return
+			}
+			zap.L().Debug("Authenticated with API token", zap.Uint("user_id", token.UserID), zap.Uint("token_id", token.ID))
+			ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+			ctx = context.WithValue(ctx, APITokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		session, _ := config.Store.Get(r, "session")
 		zap.L().Debug("Protected session", zap.Any("values", session.Values))
 		idStr, ok := session.Values["user_id"].(string)
@@ -52,14 +122,29 @@ func Protected(next http.Handler) http.Handler {
 				return
 			}
 		}
+		// The session may have been revoked from another device or by an admin
+		if !utils.CheckSession(w, r, session, id) {
+			zap.L().Debug("Session revoked", zap.Uint("user_id", id))
+			utils.ClearSession(w, r)
+			utils.RespondWithValidationError(w, http.StatusUnauthorized, utils.NewValidationError(utils.ErrUnauthorized, "Session expired, please log in again", nil))
+			return
+		}
+		// An admin impersonating a parent is served as that parent
+		if imp, ok := utils.ActiveImpersonation(w, r, session, id, time.Now()); ok {
+			zap.L().Debug("Authenticated as impersonated user", zap.Uint("admin_id", id), zap.Uint("user_id", imp.UserID))
+			serveImpersonated(w, r, next, imp)
+			return
+		}
 		zap.L().Debug("Authenticated", zap.Uint("user_id", id))
 		ctx := context.WithValue(r.Context(), UserIDKey, id)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// AdminOnly middleware checks if the user is an admin
-func AdminOnly(next http.Handler) http.Handler {
+// StaffOnly middleware admits users whose role grants at least one permission (with
+// two-factor authentication enabled, when the require_admin_2fa setting is on). The role
+// is stored in the context for RequirePermission.
+func StaffOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		userID, ok := ctx.Value(UserIDKey).(uint)
@@ -69,15 +154,101 @@ func AdminOnly(next http.Handler) http.Handler {
 		}
 		var user models.User
 		if err := config.DB.First(&user, userID).Error; err != nil {
-			zap.L().Debug("AdminOnly user not found", zap.Uint("user_id", userID))
+			zap.L().Debug("StaffOnly user not found", zap.Uint("user_id", userID))
 			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrForbidden, "Forbidden", nil))
 			return
 		}
-		if user.Role != "admin" {
-			zap.L().Debug("AdminOnly forbidden role", zap.Uint("user_id", userID), zap.String("role", user.Role))
+		if !utils.IsStaffRole(user.Role) {
+			zap.L().Debug("StaffOnly forbidden role", zap.Uint("user_id", userID), zap.String("role", user.Role))
 			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrForbidden, "Forbidden", nil))
 			return
 		}
+		if token, ok := APITokenFromContext(r); ok && !utils.APITokenHasScope(token, utils.ScopeAdmin) {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrInsufficientScope, "Token lacks the admin scope", nil))
+			return
+		}
+		if !user.TOTPEnabled && utils.TwoFactorRequired(user) {
+			zap.L().Debug("StaffOnly two-factor not enabled", zap.Uint("user_id", userID))
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrTwoFactorRequired, "Two-factor authentication must be enabled for staff accounts", nil))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, RoleKey, user.Role)))
+	})
+}
+
+// RequirePermission wraps a handler so only users whose role grants the permission reach
+// it. It runs after StaffOnly, which puts the role in the context.
+func RequirePermission(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(RoleKey).(string)
+		if !utils.HasPermission(role, permission) {
+			zap.L().Debug("RequirePermission denied", zap.String("role", role), zap.String("permission", permission))
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrForbidden, "Your role doesn't allow this action", map[string]interface{}{
+				"permission": permission,
+			}))
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// SessionOnly rejects requests authenticated with an API token, for account security
+// endpoints (managing tokens or two-factor) that need an interactive login. An admin's
+// impersonation is not the user's own login either. Use after Protected.
+func SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APITokenFromContext(r); ok {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrForbidden, "This endpoint can't be used with an API token", nil))
+			return
+		}
+		NotImpersonating(next).ServeHTTP(w, r)
+	})
+}
+
+// NotImpersonating blocks sensitive account actions while an admin impersonates the user.
+// Use after Protected.
+func NotImpersonating(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ImpersonationFromContext(r); ok {
+			utils.RespondWithValidationError(w, http.StatusForbidden, utils.NewValidationError(utils.ErrImpersonationForbidden, "This action isn't available while impersonating a user", nil))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OptionalAuth adds the user ID to the context when the request carries a valid session,
+// and otherwise lets the request through anonymously (for public endpoints whose
+// response depends on who is asking).
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if raw, ok := utils.BearerToken(r); ok {
+			if token, ok := authenticateBearer(r, raw); ok && utils.APITokenAllowsMethod(token, r.Method) {
+				ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+				ctx = context.WithValue(ctx, APITokenKey, token)
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		session, _ := config.Store.Get(r, "session")
+		idStr, _ := session.Values["user_id"].(string)
+		id64, err := strconv.ParseUint(idStr, 10, 64)
+		if idStr == "" || err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		svStr, _ := session.Values["session_version"].(string)
+		var usr models.User
+		if err := config.DB.Select("session_version").First(&usr, uint(id64)).Error; err != nil || svStr != strconv.Itoa(usr.SessionVersion) || !utils.SessionActive(session, uint(id64)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if imp, ok := utils.ActiveImpersonation(w, r, session, uint(id64), time.Now()); ok {
+			serveImpersonated(w, r, next, imp)
+			return
+		}
+		ctx := context.WithValue(r.Context(), UserIDKey, uint(id64))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
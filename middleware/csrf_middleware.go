@@ -22,6 +22,12 @@ func generateCSRFToken() string {
 
 func CSRFMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Bearer tokens are never sent automatically by browsers, so token requests can't be forged
+		if _, ok := APITokenFromContext(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		session, _ := config.Store.Get(r, "session")
 		token, _ := session.Values["csrf_token"].(string)
 		expiry, _ := session.Values["csrf_token_expiry"].(int64)
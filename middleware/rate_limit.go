@@ -1,78 +1,76 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
 	"os"
-	"sync"
+	"strings"
+	"time"
 
 	"reservio/utils"
-
-	"golang.org/x/time/rate"
 )
 
-// RateLimiter stores rate limiters per IP
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	r        rate.Limit
-	b        int
+// rateLimitRule assigns a policy to requests whose path starts with Prefix (and whose
+// method is Method, when set). The first matching rule wins.
+type rateLimitRule struct {
+	Method string
+	Prefix string
+	Policy utils.RateLimitPolicy
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		r:        r,
-		b:        b,
-	}
+var rateLimitRules = []rateLimitRule{
+	{Prefix: "/api/auth/", Policy: utils.RateLimitAuth},
+	{Method: http.MethodGet, Prefix: "/api/slots", Policy: utils.RateLimitRelaxed},
+	{Method: http.MethodGet, Prefix: "/api/announcements/feed.", Policy: utils.RateLimitRelaxed},
+	{Prefix: "/api/", Policy: utils.RateLimitDefault},
+	{Prefix: "/uploads/", Policy: utils.RateLimitDefault},
 }
 
-// GetLimiter returns the rate limiter for the provided IP address
-func (rl *RateLimiter) GetLimiter(ip string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	limiter, exists := rl.limiters[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.r, rl.b)
-		rl.limiters[ip] = limiter
+// RateLimitPolicyFor returns the policy for a request; false for unlimited paths such as
+// /health and /metrics
+func RateLimitPolicyFor(r *http.Request) (utils.RateLimitPolicy, bool) {
+	for _, rule := range rateLimitRules {
+		if rule.Method != "" && rule.Method != r.Method {
+			continue
+		}
+		if strings.HasPrefix(r.URL.Path, rule.Prefix) {
+			return rule.Policy, true
+		}
 	}
-
-	return limiter
+	return utils.RateLimitPolicy{}, false
 }
 
-// Cleanup removes old limiters (call periodically)
-func (rl *RateLimiter) Cleanup() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	// In a production app, you'd implement cleanup logic here
-	// For now, we'll keep all limiters in memory
+// rateLimitEnabled is off in test mode and CI unless RATE_LIMIT_ENABLED=true
+func rateLimitEnabled() bool {
+	if enabled := os.Getenv("RATE_LIMIT_ENABLED"); enabled != "" {
+		return enabled == "true"
+	}
+	return os.Getenv("TEST_MODE") != "1" && os.Getenv("CI") != "true"
 }
 
-// Global rate limiter instance
-var globalLimiter = NewRateLimiter(5, 20) // 5 requests per second, burst of 20
-
-// RateLimitMiddleware applies rate limiting per IP address
+// RateLimitMiddleware limits requests per client IP under the policy of the route group,
+// shared across instances through Redis, and reports the quota in RateLimit-* headers
 func RateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip rate limiting in test mode or CI
-		if os.Getenv("TEST_MODE") == "1" || os.Getenv("CI") == "true" {
+		if !rateLimitEnabled() {
 			next.ServeHTTP(w, r)
 			return
 		}
-
-		// Get client IP
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = forwarded
+		policy, ok := RateLimitPolicyFor(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		// Get rate limiter for this IP
-		limiter := globalLimiter.GetLimiter(ip)
-
-		// Check if request is allowed
-		if !limiter.Allow() {
-			utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Rate limit exceeded. Please try again later.", nil))
+		result := utils.AllowRequest(policy, utils.ClientIP(r), time.Now())
+		for name, value := range utils.RateLimitHeaders(policy, result) {
+			w.Header().Set(name, value)
+		}
+		if !result.Allowed {
+			utils.RespondWithValidationError(w, http.StatusTooManyRequests, utils.NewValidationError("RATE_LIMIT_EXCEEDED", "Rate limit exceeded. Please try again later.", map[string]interface{}{
+				"policy":      policy.Name,
+				"retry_after": int(math.Ceil(result.RetryAfter.Seconds())),
+			}))
 			return
 		}
 
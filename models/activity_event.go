@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ActivityEvent is a domain event recorded for the admin activity feed.
+// The auto-increment ID orders the feed and is the resume point for reconnecting clients.
+type ActivityEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventID   string    `gorm:"size:64;uniqueIndex" json:"event_id"`
+	Type      string    `gorm:"size:100;index" json:"type"`
+	Data      string    `gorm:"type:text" json:"data"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -1,15 +1,40 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
-// Announcement represents a public announcement posted by an admin.
-// CreatedAt is used as the published date.
+	"gorm.io/gorm"
+)
+
+// Announcement represents an announcement posted by an admin.
 // AuthorID references the User (admin) who created it.
 // If Author record is deleted, announcements remain with NULL author.
+//
+// Status is "draft" or "published". A published announcement is visible from PublishAt
+// (NULL = immediately; CreatedAt is then the published date) until ExpiresAt (NULL = never).
+// Audience is "all", "parents", "admins", "slot" (parents with a reservation in
+// AudienceSlotID) or "dates" (parents with a reservation between AudienceFrom and
+// AudienceTo, inclusive YYYY-MM-DD). NotifiedAt records when subscribers were notified.
+// Content is Markdown; responses also carry it rendered to sanitized HTML.
+// With RequiresAck set, recipients are asked to acknowledge it (see AnnouncementReceipt).
 type Announcement struct {
 	gorm.Model
 	Title    string `gorm:"size:200" json:"title"`
 	Content  string `gorm:"type:text" json:"content"`
 	AuthorID uint   `json:"author_id"`
 	Author   *User  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"author,omitempty"`
+
+	Status         string     `gorm:"size:20;index;default:published" json:"status"`
+	PublishAt      *time.Time `gorm:"index" json:"publish_at"`
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at"`
+	Pinned         bool       `json:"pinned"`
+	Urgent         bool       `json:"urgent"`
+	RequiresAck    bool       `json:"requires_ack"`
+	Audience       string     `gorm:"size:20;default:all" json:"audience"`
+	AudienceSlotID *uint      `json:"audience_slot_id"`
+	AudienceFrom   string     `gorm:"size:10" json:"audience_from"`
+	AudienceTo     string     `gorm:"size:10" json:"audience_to"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+
+	Attachments []AnnouncementAttachment `json:"attachments,omitempty"`
 }
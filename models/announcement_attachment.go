@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// AnnouncementAttachment is a file (menu, permission slip...) attached to an announcement.
+// Filename is the stored name inside the attachment directory; OriginalName is what the
+// admin uploaded and is used for downloads.
+type AnnouncementAttachment struct {
+	gorm.Model
+	AnnouncementID uint   `gorm:"index" json:"announcement_id"`
+	Filename       string `gorm:"size:255" json:"-"`
+	OriginalName   string `gorm:"size:255" json:"original_name"`
+	ContentType    string `gorm:"size:100" json:"content_type"`
+	Size           int64  `json:"size"`
+	UploadedByID   uint   `json:"uploaded_by_id"`
+}
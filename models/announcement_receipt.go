@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// AnnouncementReceipt tracks whether a user has read and, for announcements that
+// require it, acknowledged an announcement. A missing row means unread.
+type AnnouncementReceipt struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	AnnouncementID uint       `gorm:"uniqueIndex:idx_announcement_receipt" json:"announcement_id"`
+	UserID         uint       `gorm:"uniqueIndex:idx_announcement_receipt;index" json:"user_id"`
+	ReadAt         *time.Time `json:"read_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
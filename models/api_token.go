@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// APIToken is a personal access token for scripts and API clients. Only the SHA-256 hash
+// of the token is stored; Prefix keeps its first characters so users can tell tokens apart.
+// Scopes is a comma-separated list ("read", "write", "admin").
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `gorm:"size:100" json:"name"`
+	Prefix     string     `gorm:"size:16" json:"prefix"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:100" json:"scopes"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DeferredNotification is an email or SMS held back by the recipient's quiet hours.
+// It is sent once DeliverAt has passed and deleted only after it was delivered; Attempts
+// counts the tries so far.
+type DeferredNotification struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index"`
+	Channel   string    `gorm:"size:20"`
+	Event     string    `gorm:"size:50"`
+	Subject   string    `gorm:"size:200"`
+	Body      string    `gorm:"type:text"`
+	DeliverAt time.Time `gorm:"index"`
+	Attempts  int
+	CreatedAt time.Time
+}
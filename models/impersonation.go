@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Impersonation is a support session in which an admin acts as a parent account. It lives
+// on the admin's own login session and ends at EndedAt or ExpiresAt, whichever is first.
+type Impersonation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	AdminID   uint       `gorm:"index" json:"admin_id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Reason    string     `gorm:"size:500" json:"reason"`
+	IP        string     `gorm:"size:64" json:"ip"`
+	UserAgent string     `gorm:"size:255" json:"user_agent"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+}
+
+// ImpersonationRequest is one request made during an impersonation (the audit trail)
+type ImpersonationRequest struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ImpersonationID uint      `gorm:"index" json:"impersonation_id"`
+	Method          string    `gorm:"size:10" json:"method"`
+	Path            string    `gorm:"size:2048" json:"path"`
+	Status          int       `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+}
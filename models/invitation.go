@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Invitation lets someone register with a given email and role. Only the SHA-256 hash
+// of the code is stored; a code works once, until ExpiresAt, unless revoked.
+type Invitation struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Email        string     `gorm:"size:255;index" json:"email"`
+	Role         string     `gorm:"size:20" json:"role"`
+	CodeHash     string     `gorm:"size:64;uniqueIndex" json:"-"`
+	InvitedByID  uint       `json:"invited_by_id"`
+	ExpiresAt    time.Time  `gorm:"index" json:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	AcceptedByID *uint      `json:"accepted_by_id"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginThrottle counts failed logins for one throttle key (an account, a source IP or
+// both) when Redis isn't available. Rows are stale once ExpiresAt passes and are purged
+// periodically.
+type LoginThrottle struct {
+	Key         string `gorm:"primaryKey;size:320"`
+	Failures    int    `gorm:"not null;default:0"`
+	LockedUntil *time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MagicLinkToken is a single-use passwordless login link. Only the SHA-256 hash of the
+// token is stored; it works once, until ExpiresAt.
+type MagicLinkToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex"`
+	IP        string    `gorm:"size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
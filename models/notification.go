@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification is an entry in a user's in-app inbox.
+// ReadAt is NULL while the notification is unread.
+type Notification struct {
+	gorm.Model
+	UserID uint       `gorm:"index" json:"user_id"`
+	Event  string     `gorm:"size:50" json:"event"`
+	Title  string     `gorm:"size:200" json:"title"`
+	Body   string     `gorm:"type:text" json:"body"`
+	ReadAt *time.Time `gorm:"index" json:"read_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "gorm.io/gorm"
+
+// NotificationPreference stores, per user, which channels each kind of
+// notification may use and an optional quiet-hours window.
+// A user without a row gets the defaults from utils.DefaultNotificationPreference.
+// QuietHoursStart/End are "HH:MM" in the user's Timezone (IANA name, empty = server local).
+type NotificationPreference struct {
+	gorm.Model
+	UserID uint `gorm:"uniqueIndex" json:"user_id"`
+
+	ApprovalsEmail bool `json:"approvals_email"`
+	ApprovalsInApp bool `json:"approvals_in_app"`
+	ApprovalsSMS   bool `json:"approvals_sms"`
+
+	RemindersEmail bool `json:"reminders_email"`
+	RemindersInApp bool `json:"reminders_in_app"`
+	RemindersSMS   bool `json:"reminders_sms"`
+
+	AnnouncementsEmail bool `json:"announcements_email"`
+	AnnouncementsInApp bool `json:"announcements_in_app"`
+	AnnouncementsSMS   bool `json:"announcements_sms"`
+
+	MessagesEmail bool `json:"messages_email"`
+	MessagesInApp bool `json:"messages_in_app"`
+	MessagesSMS   bool `json:"messages_sms"`
+
+	QuietHoursStart string `gorm:"size:5" json:"quiet_hours_start"`
+	QuietHoursEnd   string `gorm:"size:5" json:"quiet_hours_end"`
+	Timezone        string `gorm:"size:64" json:"timezone"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps the bcrypt hash of a password the user had before, so recent
+// passwords can't be set again. Only the newest entries (PASSWORD_HISTORY) are kept.
+type PasswordHistory struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"index"`
+	PasswordHash string `gorm:"size:60"`
+	CreatedAt    time.Time
+}
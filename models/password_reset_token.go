@@ -3,10 +3,11 @@ package models
 import "gorm.io/gorm"
 
 // PasswordResetToken represents a single-use, time-limited token for resetting a user password.
-// Tokens are deleted after use or when expired to prevent reuse.
+// Only the SHA-256 hash of the emailed token is stored (in the "token" column, so tokens
+// issued before hashing simply stop matching). Tokens are deleted after use or when expired.
 type PasswordResetToken struct {
 	gorm.Model
 	UserID    uint   `gorm:"index"`
-	Token     string `gorm:"uniqueIndex;size:191"` // size 191 works with most MySQL indexes; fine for Postgres too
-	ExpiresAt int64  `gorm:"index"`                // Unix timestamp (seconds)
+	TokenHash string `gorm:"column:token;uniqueIndex;size:191"` // size 191 works with most MySQL indexes; fine for Postgres too
+	ExpiresAt int64  `gorm:"index"`                             // Unix timestamp (seconds)
 }
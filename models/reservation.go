@@ -1,10 +1,19 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Reservation struct {
 	gorm.Model
 	ChildID uint   `gorm:"index"`
 	SlotID  uint   `gorm:"index"`
 	Status  string // "pending", "approved", "rejected"
+	// CheckedInAt and CheckedOutAt record attendance on the day; CheckedInByID is the
+	// staff member who checked the child in
+	CheckedInAt   *time.Time
+	CheckedOutAt  *time.Time
+	CheckedInByID *uint
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Reminder claim states
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderSkipped = "skipped"
+)
+
+// ReservationReminder records the reminder for one offset (e.g. "24h") of a reservation.
+// The unique index makes the claim atomic across app instances. A claim stays pending
+// until the reminder was delivered; SentAt is only set once it was.
+type ReservationReminder struct {
+	ID            uint       `gorm:"primarykey"`
+	ReservationID uint       `gorm:"uniqueIndex:idx_reservation_reminder"`
+	Offset        string     `gorm:"size:20;uniqueIndex:idx_reservation_reminder"`
+	Status        string     `gorm:"size:20;index;default:sent"`
+	ClaimedAt     time.Time  `gorm:"index"`
+	SentAt        *time.Time `gorm:"index"`
+}
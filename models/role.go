@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Role is a custom role defined by admins. Permissions is a comma-separated list of
+// permission names; the built-in roles (see utils/permissions.go) are not stored.
+type Role struct {
+	Name        string    `gorm:"primaryKey;size:20" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Permissions string    `gorm:"type:text" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
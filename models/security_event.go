@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SecurityEvent is one entry of the security log: a login, failed login, password or role
+// change and the like. UserID is nil for failed logins with an unknown email; ActorID is
+// set when someone other than the user (an admin) made the change.
+type SecurityEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"size:50;index" json:"type"`
+	Outcome   string    `gorm:"size:20;index" json:"outcome"`
+	UserID    *uint     `gorm:"index" json:"user_id"`
+	ActorID   *uint     `json:"actor_id,omitempty"`
+	Email     string    `gorm:"size:255;index" json:"email"`
+	IP        string    `gorm:"size:64;index" json:"ip"`
+	UserAgent string    `gorm:"size:512" json:"user_agent"`
+	Details   string    `gorm:"size:500" json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
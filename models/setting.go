@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Setting is a runtime-configurable application setting changed by admins.
+// Values are stored as strings; see utils/settings.go for the known keys.
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
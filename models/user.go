@@ -1,16 +1,40 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
 	Email          string `gorm:"uniqueIndex" json:"email"`
 	Password       string
-	Role           string  // "parent" or "admin"
+	Role           string  // built-in role ("parent", "admin", ...) or a custom Role name
 	Children       []Child `gorm:"foreignKey:ParentID"`
 	SessionVersion int     `gorm:"default:1"`
 	FirstName      string  `json:"first_name"`
 	LastName       string  `json:"last_name"`
-	Phone          string  `json:"phone"`
+	Phone          string  `json:"phone"` // E.164, e.g. +420601234567
 	ProfilePicture string  `json:"profile_picture"`
+	// Status is "active", or "pending" while a self-registered account awaits admin approval
+	Status string `gorm:"size:20;default:active;index" json:"status"`
+	// SMSOptIn records consent to receive SMS on Phone; changing the number clears it
+	SMSOptIn   bool       `json:"sms_opt_in"`
+	SMSOptInAt *time.Time `json:"sms_opt_in_at"`
+	// PendingEmail holds a requested new address until it is confirmed; Email only changes then
+	EmailVerified      bool       `json:"email_verified"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	PendingEmail       string     `json:"pending_email"`
+	VerificationSentAt *time.Time `json:"-"`
+	// TOTPSecret is set during enrollment; two-factor login only applies once TOTPEnabled.
+	// TOTPLastStep is the last accepted time step, so a code can't be used twice.
+	TOTPSecret    string     `gorm:"size:64" json:"-"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-"`
+	// OIDCIssuer and OIDCSubject link the account to a single sign-on identity (NULL when
+	// not linked). SSO-only accounts have an empty Password and can't use password login.
+	OIDCIssuer  *string `gorm:"size:255;uniqueIndex:idx_user_oidc" json:"-"`
+	OIDCSubject *string `gorm:"size:255;uniqueIndex:idx_user_oidc" json:"-"`
 }
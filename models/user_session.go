@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserSession tracks a login session (one browser or device). The session cookie
+// carries a random session ID; only its SHA-256 hash is stored. Revoking the record
+// ends the session, whichever session store is in use.
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	SIDHash    string     `gorm:"size:64;uniqueIndex" json:"-"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent"`
+	IP         string     `gorm:"size:64" json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is an admin-registered URL that receives signed event payloads.
+// Events is a comma-separated list of event types ("*" subscribes to everything).
+// Secret signs each payload (HMAC-SHA256) and is only shown when created or rotated.
+type WebhookEndpoint struct {
+	gorm.Model
+	URL         string `gorm:"size:500" json:"url"`
+	Secret      string `gorm:"size:100" json:"-"`
+	Events      string `gorm:"type:text" json:"events"`
+	Description string `gorm:"size:200" json:"description"`
+	Active      bool   `json:"active"`
+	CreatedByID uint   `json:"created_by_id"`
+}
+
+// WebhookDelivery is one attempt-tracked delivery of an event to an endpoint.
+// Status is "pending" until it succeeds or runs out of attempts ("succeeded"/"failed").
+// Replays create a new delivery pointing at the original through ReplayOfID.
+type WebhookDelivery struct {
+	gorm.Model
+	EndpointID    uint       `gorm:"index" json:"endpoint_id"`
+	EventID       string     `gorm:"size:64;index" json:"event_id"`
+	Event         string     `gorm:"size:100" json:"event"`
+	Payload       string     `gorm:"type:text" json:"payload"`
+	Status        string     `gorm:"size:20;index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `gorm:"type:text" json:"response_body"`
+	Error         string     `gorm:"type:text" json:"error"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	ReplayOfID    *uint      `json:"replay_of_id"`
+}
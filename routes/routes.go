@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"reservio/controllers"
 	"reservio/middleware"
+	"reservio/utils"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -31,17 +32,26 @@ func SetupRouter() *mux.Router {
 
 	auth := api.PathPrefix("/auth").Subrouter()
 	auth.HandleFunc("/register", controllers.Register).Methods("POST")
+	auth.HandleFunc("/registration", controllers.GetRegistrationInfo).Methods("GET")
+	auth.HandleFunc("/invitations/{code}", controllers.GetInvitation).Methods("GET")
 	auth.HandleFunc("/login", controllers.Login).Methods("POST")
+	auth.HandleFunc("/2fa/verify", controllers.VerifyTwoFactorLogin).Methods("POST")
+	auth.HandleFunc("/oidc/login", controllers.OIDCLogin).Methods("GET")
+	auth.HandleFunc("/oidc/callback", controllers.OIDCCallback).Methods("GET")
 	auth.HandleFunc("/logout", controllers.Logout).Methods("POST")
+	auth.HandleFunc("/magic-link", controllers.RequestMagicLink).Methods("POST")
+	auth.HandleFunc("/magic-link/verify", controllers.LoginWithMagicLink).Methods("POST")
 	auth.HandleFunc("/request-reset", controllers.RequestPasswordReset).Methods("POST")
 	auth.HandleFunc("/reset-password", controllers.ResetPassword).Methods("POST")
+	auth.HandleFunc("/verify-email", controllers.VerifyEmail).Methods("POST")
 
 	// Session refresh (protected)
 	refresh := api.PathPrefix("/auth").Subrouter()
 	refresh.Use(middleware.Protected)
 	refresh.Use(middleware.CSRFMiddleware)
 	refresh.HandleFunc("/refresh", controllers.RefreshSession).Methods("POST")
-	refresh.HandleFunc("/logout-all", controllers.LogoutAll).Methods("POST")
+	refresh.Handle("/logout-all", middleware.NotImpersonating(http.HandlerFunc(controllers.LogoutAll))).Methods("POST")
+	refresh.HandleFunc("/csrf", controllers.GetCSRFToken).Methods("GET")
 
 	parent := api.PathPrefix("/parent").Subrouter()
 	parent.Use(middleware.Protected)
@@ -55,25 +65,62 @@ func SetupRouter() *mux.Router {
 	parent.HandleFunc("/children/{id}", controllers.EditChild).Methods("PUT")
 	parent.HandleFunc("/children/{id}", controllers.DeleteChild).Methods("DELETE")
 
-	// Admin routes: all require Protected + AdminOnly middleware
+	// Staff routes: all require Protected + StaffOnly middleware, and each declares the
+	// permission it needs (see utils/permissions.go)
 	admin := api.PathPrefix("/admin").Subrouter()
 	admin.Use(middleware.Protected)
-	admin.Use(middleware.AdminOnly)
+	admin.Use(middleware.StaffOnly)
 	admin.Use(middleware.CSRFMiddleware)
-	admin.HandleFunc("/slots", controllers.CreateSlot).Methods("POST")
-	admin.HandleFunc("/slots", controllers.ListSlots).Methods("GET")
-	admin.HandleFunc("/approve/{id}", controllers.ApproveReservation).Methods("PUT")
-	admin.HandleFunc("/reject/{id}", controllers.RejectReservation).Methods("PUT")
-	admin.HandleFunc("/reservations", controllers.GetReservationsByStatus).Methods("GET")
-	admin.HandleFunc("/users", controllers.ListUsers).Methods("GET")
-	admin.HandleFunc("/users/{id}", controllers.DeleteUser).Methods("DELETE")
-	admin.HandleFunc("/users/{id}/role", controllers.UpdateUserRole).Methods("PUT")
-	admin.HandleFunc("/announcements", controllers.CreateAnnouncement).Methods("POST")
-	admin.HandleFunc("/announcements/{id}", controllers.UpdateAnnouncement).Methods("PUT")
-	admin.HandleFunc("/announcements/{id}", controllers.DeleteAnnouncement).Methods("DELETE")
-	admin.HandleFunc("/children", controllers.ListChildrenWithParents).Methods("GET")
-	admin.HandleFunc("/slots/{id}", controllers.UpdateSlot).Methods("PUT")
-	admin.HandleFunc("/slots/{id}", controllers.DeleteSlot).Methods("DELETE")
+	can := func(permission string, handler http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(permission, handler)
+	}
+	admin.Handle("/slots", can(utils.PermSlotsManage, controllers.CreateSlot)).Methods("POST")
+	admin.Handle("/slots", can(utils.PermReservationsView, controllers.ListSlots)).Methods("GET")
+	admin.Handle("/approve/{id}", can(utils.PermReservationsApprove, controllers.ApproveReservation)).Methods("PUT")
+	admin.Handle("/reject/{id}", can(utils.PermReservationsApprove, controllers.RejectReservation)).Methods("PUT")
+	admin.Handle("/reservations", can(utils.PermReservationsView, controllers.GetReservationsByStatus)).Methods("GET")
+	admin.Handle("/users", can(utils.PermUsersView, controllers.ListUsers)).Methods("GET")
+	admin.Handle("/users/{id}", can(utils.PermUsersManage, controllers.DeleteUser)).Methods("DELETE")
+	admin.Handle("/users/{id}/role", can(utils.PermUsersManage, controllers.UpdateUserRole)).Methods("PUT")
+	admin.Handle("/users/{id}/activate", can(utils.PermUsersManage, controllers.ActivateUser)).Methods("POST")
+	admin.Handle("/users/{id}/unlock", can(utils.PermUsersManage, controllers.UnlockUserLogin)).Methods("POST")
+	admin.Handle("/users/{id}/sessions", can(utils.PermUsersView, controllers.ListUserSessions)).Methods("GET")
+	admin.Handle("/users/{id}/sessions", can(utils.PermUsersManage, controllers.RevokeAllUserSessions)).Methods("DELETE")
+	admin.Handle("/users/{id}/impersonate", middleware.SessionOnly(can(utils.PermUsersImpersonate, controllers.StartImpersonation))).Methods("POST")
+	admin.Handle("/impersonations", can(utils.PermUsersView, controllers.ListImpersonations)).Methods("GET")
+	admin.Handle("/impersonations/{id}/requests", can(utils.PermUsersView, controllers.ListImpersonationRequests)).Methods("GET")
+	admin.Handle("/invitations", can(utils.PermUsersView, controllers.ListInvitations)).Methods("GET")
+	admin.Handle("/invitations", can(utils.PermUsersManage, controllers.CreateInvitation)).Methods("POST")
+	admin.Handle("/invitations/{id}", can(utils.PermUsersManage, controllers.RevokeInvitation)).Methods("DELETE")
+	admin.Handle("/announcements", can(utils.PermAnnouncementsView, controllers.ListAllAnnouncements)).Methods("GET")
+	admin.Handle("/announcements", can(utils.PermAnnouncementsManage, controllers.CreateAnnouncement)).Methods("POST")
+	admin.Handle("/announcements/{id}", can(utils.PermAnnouncementsManage, controllers.UpdateAnnouncement)).Methods("PUT")
+	admin.Handle("/announcements/{id}", can(utils.PermAnnouncementsManage, controllers.DeleteAnnouncement)).Methods("DELETE")
+	admin.Handle("/announcements/{id}/receipts", can(utils.PermAnnouncementsView, controllers.GetAnnouncementReceipts)).Methods("GET")
+	admin.Handle("/announcements/{id}/remind", can(utils.PermAnnouncementsManage, controllers.RemindAnnouncement)).Methods("POST")
+	admin.Handle("/announcements/{id}/attachments", can(utils.PermAnnouncementsManage, controllers.UploadAnnouncementAttachment)).Methods("POST")
+	admin.Handle("/announcements/{id}/attachments/{attachment_id}", can(utils.PermAnnouncementsManage, controllers.DeleteAnnouncementAttachment)).Methods("DELETE")
+	admin.Handle("/children", can(utils.PermRosterView, controllers.ListChildrenWithParents)).Methods("GET")
+	admin.Handle("/slots/{id}", can(utils.PermSlotsManage, controllers.UpdateSlot)).Methods("PUT")
+	admin.Handle("/slots/{id}", can(utils.PermSlotsManage, controllers.DeleteSlot)).Methods("DELETE")
+	admin.Handle("/messages", can(utils.PermMessagesSend, controllers.SendAdminMessage)).Methods("POST")
+	admin.Handle("/webhooks", can(utils.PermWebhooksView, controllers.ListWebhooks)).Methods("GET")
+	admin.Handle("/webhooks", can(utils.PermWebhooksManage, controllers.CreateWebhook)).Methods("POST")
+	admin.Handle("/webhooks/{id}", can(utils.PermWebhooksManage, controllers.UpdateWebhook)).Methods("PUT")
+	admin.Handle("/webhooks/{id}", can(utils.PermWebhooksManage, controllers.DeleteWebhook)).Methods("DELETE")
+	admin.Handle("/webhooks/{id}/deliveries", can(utils.PermWebhooksView, controllers.ListWebhookDeliveries)).Methods("GET")
+	admin.Handle("/webhooks/deliveries/{id}/replay", can(utils.PermWebhooksManage, controllers.ReplayWebhookDelivery)).Methods("POST")
+	admin.Handle("/activity/ws", can(utils.PermActivityView, controllers.ActivityFeed)).Methods("GET")
+	admin.Handle("/security-events", can(utils.PermActivityView, controllers.ListSecurityEvents)).Methods("GET")
+	admin.Handle("/settings", can(utils.PermSettingsView, controllers.GetSettings)).Methods("GET")
+	admin.Handle("/settings", can(utils.PermSettingsManage, controllers.UpdateSettings)).Methods("PUT")
+	admin.Handle("/roles", can(utils.PermUsersView, controllers.ListRoles)).Methods("GET")
+	admin.Handle("/roles", can(utils.PermUsersManage, controllers.CreateRole)).Methods("POST")
+	admin.Handle("/roles/{name}", can(utils.PermUsersManage, controllers.UpdateRole)).Methods("PUT")
+	admin.Handle("/roles/{name}", can(utils.PermUsersManage, controllers.DeleteRole)).Methods("DELETE")
+	admin.Handle("/roster", can(utils.PermRosterView, controllers.GetRoster)).Methods("GET")
+	admin.Handle("/reservations/{id}/check-in", can(utils.PermCheckIn, controllers.CheckInReservation)).Methods("POST")
+	admin.Handle("/reservations/{id}/check-out", can(utils.PermCheckIn, controllers.CheckOutReservation)).Methods("POST")
 
 	user := api.PathPrefix("/user").Subrouter()
 	user.Use(middleware.Protected)
@@ -81,14 +128,51 @@ func SetupRouter() *mux.Router {
 	user.HandleFunc("/profile", controllers.GetProfile).Methods("GET")
 	user.HandleFunc("/profile", controllers.UpdateProfile).Methods("PUT")
 	user.HandleFunc("/profile-picture", controllers.UploadProfilePicture).Methods("POST")
-
-	// 📌 Important: register the calendar endpoint BEFORE the generic /slots/{id}
+	user.Handle("/email/verification", middleware.NotImpersonating(http.HandlerFunc(controllers.ResendEmailVerification))).Methods("POST")
+	user.Handle("/email/pending", middleware.NotImpersonating(http.HandlerFunc(controllers.CancelEmailChange))).Methods("DELETE")
+	user.HandleFunc("/notification-preferences", controllers.GetNotificationPreferences).Methods("GET")
+	user.Handle("/notification-preferences", middleware.NotImpersonating(http.HandlerFunc(controllers.UpdateNotificationPreferences))).Methods("PUT")
+	user.HandleFunc("/notifications", controllers.ListNotifications).Methods("GET")
+	user.HandleFunc("/notifications/unread-count", controllers.GetUnreadNotificationCount).Methods("GET")
+	user.Handle("/notifications/read-all", middleware.NotImpersonating(http.HandlerFunc(controllers.MarkAllNotificationsRead))).Methods("POST")
+	user.Handle("/notifications/{id}/read", middleware.NotImpersonating(http.HandlerFunc(controllers.MarkNotificationRead))).Methods("PUT")
+	user.Handle("/notifications/{id}/unread", middleware.NotImpersonating(http.HandlerFunc(controllers.MarkNotificationUnread))).Methods("PUT")
+	user.HandleFunc("/announcements/unread-count", controllers.GetUnreadAnnouncementCount).Methods("GET")
+	user.Handle("/announcements/{id}/read", middleware.NotImpersonating(http.HandlerFunc(controllers.MarkAnnouncementRead))).Methods("POST")
+	user.Handle("/announcements/{id}/acknowledge", middleware.NotImpersonating(http.HandlerFunc(controllers.AcknowledgeAnnouncement))).Methods("POST")
+	user.HandleFunc("/announcements/{id}/attachments/{attachment_id}", controllers.DownloadAnnouncementAttachment).Methods("GET")
+	// Account security endpoints need an interactive login, not an API token
+	user.Handle("/2fa", middleware.SessionOnly(http.HandlerFunc(controllers.GetTwoFactorStatus))).Methods("GET")
+	user.Handle("/2fa/setup", middleware.SessionOnly(http.HandlerFunc(controllers.SetupTwoFactor))).Methods("POST")
+	user.Handle("/2fa/enable", middleware.SessionOnly(http.HandlerFunc(controllers.EnableTwoFactor))).Methods("POST")
+	user.Handle("/2fa/disable", middleware.SessionOnly(http.HandlerFunc(controllers.DisableTwoFactor))).Methods("POST")
+	user.Handle("/2fa/recovery-codes", middleware.SessionOnly(http.HandlerFunc(controllers.RegenerateRecoveryCodes))).Methods("POST")
+	user.Handle("/tokens", middleware.SessionOnly(http.HandlerFunc(controllers.ListAPITokens))).Methods("GET")
+	user.Handle("/tokens", middleware.SessionOnly(http.HandlerFunc(controllers.CreateAPIToken))).Methods("POST")
+	user.Handle("/tokens/{id}", middleware.SessionOnly(http.HandlerFunc(controllers.RevokeAPIToken))).Methods("DELETE")
+	user.Handle("/sessions", middleware.SessionOnly(http.HandlerFunc(controllers.ListSessions))).Methods("GET")
+	user.Handle("/sessions", middleware.SessionOnly(http.HandlerFunc(controllers.RevokeOtherSessions))).Methods("DELETE")
+	user.Handle("/sessions/{id}", middleware.SessionOnly(http.HandlerFunc(controllers.RevokeSession))).Methods("DELETE")
+	user.Handle("/security-events", middleware.SessionOnly(http.HandlerFunc(controllers.ListMySecurityEvents))).Methods("GET")
+	user.Handle("/sms/opt-in", middleware.NotImpersonating(http.HandlerFunc(controllers.OptInSMS))).Methods("POST")
+	user.Handle("/sms/opt-out", middleware.NotImpersonating(http.HandlerFunc(controllers.OptOutSMS))).Methods("POST")
+	user.HandleFunc("/impersonation", controllers.GetImpersonation).Methods("GET")
+	user.HandleFunc("/impersonation", controllers.StopImpersonation).Methods("DELETE")
+
+	// 📌 Important: register the calendar and stream endpoints BEFORE the generic /slots/{id}
 	// otherwise the {id} wildcard would absorb the word "calendar" and we'd
 	// get a 400 Bad Request parsing error.
 	api.HandleFunc("/slots/calendar", controllers.ListSlotsCalendar).Methods("GET")
+	api.HandleFunc("/slots/stream", controllers.StreamSlotAvailability).Methods("GET")
 	api.HandleFunc("/slots", controllers.ListSlots).Methods("GET")
 	api.HandleFunc("/slots/{id}", controllers.GetSlot).Methods("GET")
-	api.HandleFunc("/announcements", controllers.ListAnnouncements).Methods("GET")
+	// Public, but the listing depends on the (optional) session user's role and reservations
+	api.HandleFunc("/announcements/feed.atom", controllers.AnnouncementAtomFeed).Methods("GET")
+	api.HandleFunc("/announcements/feed.rss", controllers.AnnouncementRSSFeed).Methods("GET")
+	api.Handle("/announcements", middleware.OptionalAuth(http.HandlerFunc(controllers.ListAnnouncements))).Methods("GET")
+
+	// Replies forwarded by the SMS provider (authenticated with SMS_INBOUND_TOKEN)
+	api.HandleFunc("/sms/inbound", controllers.HandleInboundSMS).Methods("POST")
 
 	// Dashboard stats (protected)
 	dashboard := api.PathPrefix("/dashboard").Subrouter()
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+// activityChannel is the Redis pub/sub channel that fans recorded activity out to every app instance
+const activityChannel = "reservio:activity"
+
+// activityResumeLimit caps how many missed events are replayed to a reconnecting client
+const activityResumeLimit = 500
+
+// activitySeenLimit is how many delivered entry IDs a feed connection remembers
+const activitySeenLimit = 1000
+
+// ActivityRetention is how long feed entries are kept for resuming clients
+const ActivityRetention = 30 * 24 * time.Hour
+
+// ActivityEntry is one item of the admin activity feed
+type ActivityEntry struct {
+	ID        uint                   `json:"id"`
+	EventID   string                 `json:"event_id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+func activityEntry(event models.ActivityEvent) ActivityEntry {
+	entry := ActivityEntry{ID: event.ID, EventID: event.EventID, Type: event.Type, CreatedAt: event.CreatedAt}
+	_ = json.Unmarshal([]byte(event.Data), &entry.Data)
+	return entry
+}
+
+// ParseActivityTypes validates a comma-separated list of event types; empty means all
+func ParseActivityTypes(raw string) (map[string]bool, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	types := strings.Split(raw, ",")
+	for i := range types {
+		types[i] = strings.TrimSpace(types[i])
+	}
+	if err := ValidateWebhookEvents(types); err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for _, t := range types {
+		if t == "*" {
+			return nil, nil
+		}
+		set[t] = true
+	}
+	return set, nil
+}
+
+// ActivitySubscription receives new feed entries on C. Types filters by event type
+// (nil means all) and can be changed with SetTypes while subscribed.
+type ActivitySubscription struct {
+	C     chan ActivityEntry
+	mu    sync.RWMutex
+	types map[string]bool
+}
+
+// SetTypes replaces the subscribed event types (nil means all)
+func (s *ActivitySubscription) SetTypes(types map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.types = types
+}
+
+// Wants reports whether the subscriber is interested in an event type
+func (s *ActivitySubscription) Wants(eventType string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.types == nil || s.types[eventType]
+}
+
+var activitySubscribers = struct {
+	sync.RWMutex
+	subs map[*ActivitySubscription]struct{}
+}{subs: map[*ActivitySubscription]struct{}{}}
+
+// SubscribeActivity registers a live feed subscriber on this instance
+func SubscribeActivity(types map[string]bool) *ActivitySubscription {
+	sub := &ActivitySubscription{C: make(chan ActivityEntry, 64), types: types}
+	activitySubscribers.Lock()
+	activitySubscribers.subs[sub] = struct{}{}
+	activitySubscribers.Unlock()
+	return sub
+}
+
+// UnsubscribeActivity removes a feed subscriber
+func UnsubscribeActivity(sub *ActivitySubscription) {
+	activitySubscribers.Lock()
+	delete(activitySubscribers.subs, sub)
+	activitySubscribers.Unlock()
+}
+
+// dispatchActivity hands an entry to this instance's subscribers; full buffers drop it
+// (the client can resume from its last seen ID after reconnecting)
+func dispatchActivity(entry ActivityEntry) {
+	activitySubscribers.RLock()
+	defer activitySubscribers.RUnlock()
+	for sub := range activitySubscribers.subs {
+		if !sub.Wants(entry.Type) {
+			continue
+		}
+		select {
+		case sub.C <- entry:
+		default:
+			zap.L().Debug("Dropping activity entry for slow subscriber", zap.Uint("activity_id", entry.ID))
+		}
+	}
+}
+
+// ActivitySeen tracks which entries a feed connection already delivered. Entries can
+// arrive out of ID order (IDs are taken by concurrent transactions and relayed between
+// instances), so rather than a high-water mark it remembers the IDs above the resume
+// point, forgetting the oldest ones after activitySeenLimit.
+type ActivitySeen struct {
+	floor uint
+	ids   map[uint]struct{}
+	order []uint
+}
+
+// NewActivitySeen treats every entry up to and including floor as delivered
+func NewActivitySeen(floor uint) *ActivitySeen {
+	return &ActivitySeen{floor: floor, ids: map[uint]struct{}{}}
+}
+
+// Add records an entry ID and reports whether it was new
+func (s *ActivitySeen) Add(id uint) bool {
+	if id <= s.floor {
+		return false
+	}
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.ids[id] = struct{}{}
+	s.order = append(s.order, id)
+	if len(s.order) > activitySeenLimit {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+	return true
+}
+
+// ActivitySince returns recorded entries after lastID (oldest first), limited to the
+// most recent activityResumeLimit matches
+func ActivitySince(lastID uint, types map[string]bool) ([]ActivityEntry, error) {
+	query := config.DB.Where("id > ?", lastID)
+	if types != nil {
+		list := make([]string, 0, len(types))
+		for t := range types {
+			list = append(list, t)
+		}
+		query = query.Where("type IN ?", list)
+	}
+	var events []models.ActivityEvent
+	if err := query.Order("id DESC").Limit(activityResumeLimit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]ActivityEntry, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		entries = append(entries, activityEntry(events[i]))
+	}
+	return entries, nil
+}
+
+// PurgeActivityEvents deletes feed entries older than ActivityRetention and returns how many
+func PurgeActivityEvents(now time.Time) int64 {
+	result := config.DB.Where("created_at < ?", now.Add(-ActivityRetention)).Delete(&models.ActivityEvent{})
+	if result.Error != nil {
+		zap.L().Warn("Failed to purge activity events", zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
+
+// recordActivity stores a domain event in the feed and pushes it to every instance
+func recordActivity(event DomainEvent) {
+	if config.DB == nil {
+		return
+	}
+	data, _ := json.Marshal(event.Data)
+	record := models.ActivityEvent{EventID: event.ID, Type: event.Type, Data: string(data), CreatedAt: event.OccurredAt}
+	if err := config.DB.Create(&record).Error; err != nil {
+		zap.L().Error("Failed to record activity", zap.String("event", event.Type), zap.Error(err))
+		return
+	}
+	entry := activityEntry(record)
+	payload, _ := json.Marshal(entry)
+	publishToInstances(activityChannel, payload, func([]byte) { dispatchActivity(entry) })
+}
+
+func init() {
+	SubscribeEvents(recordActivity)
+}
+
+// StartActivityRelay forwards feed entries recorded by any instance to this instance's
+// subscribers until ctx is cancelled. It does nothing without Redis.
+func StartActivityRelay(ctx context.Context) {
+	relayFromInstances(ctx, activityChannel, func(payload []byte) {
+		var entry ActivityEntry
+		if err := json.Unmarshal(payload, &entry); err == nil {
+			dispatchActivity(entry)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseActivityTypes(t *testing.T) {
+	types, err := ParseActivityTypes("reservation.created, user.registered")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{EventReservationCreated: true, EventUserRegistered: true}, types)
+
+	all, err := ParseActivityTypes("")
+	assert.NoError(t, err)
+	assert.Nil(t, all)
+	all, err = ParseActivityTypes("*")
+	assert.NoError(t, err)
+	assert.Nil(t, all)
+
+	_, err = ParseActivityTypes("reservation.exploded")
+	assert.Error(t, err)
+}
+
+func TestDispatchActivity(t *testing.T) {
+	sub := SubscribeActivity(map[string]bool{EventSlotCreated: true})
+	defer UnsubscribeActivity(sub)
+
+	dispatchActivity(ActivityEntry{ID: 1, Type: EventUserRegistered})
+	assert.Len(t, sub.C, 0)
+
+	dispatchActivity(ActivityEntry{ID: 2, Type: EventSlotCreated})
+	assert.Equal(t, uint(2), (<-sub.C).ID)
+
+	sub.SetTypes(nil)
+	dispatchActivity(ActivityEntry{ID: 3, Type: EventUserRegistered})
+	assert.Equal(t, uint(3), (<-sub.C).ID)
+}
+
+func TestActivitySeen(t *testing.T) {
+	seen := NewActivitySeen(5)
+	assert.False(t, seen.Add(5))
+	assert.True(t, seen.Add(8))
+	// An entry arriving after a higher ID is still delivered, once
+	assert.True(t, seen.Add(7))
+	assert.False(t, seen.Add(7))
+	assert.False(t, seen.Add(8))
+
+	for id := uint(100); id < 100+activitySeenLimit; id++ {
+		seen.Add(id)
+	}
+	assert.Len(t, seen.ids, activitySeenLimit)
+}
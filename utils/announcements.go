@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Announcement statuses
+const (
+	AnnouncementDraft     = "draft"
+	AnnouncementPublished = "published"
+)
+
+// Announcement audiences
+const (
+	AudienceAll     = "all"
+	AudienceParents = "parents"
+	AudienceAdmins  = "admins"
+	AudienceSlot    = "slot"
+	AudienceDates   = "dates"
+)
+
+// AnnouncementAudiences lists every valid audience
+var AnnouncementAudiences = []string{AudienceAll, AudienceParents, AudienceAdmins, AudienceSlot, AudienceDates}
+
+// parentReservationsSQL selects reservations (not rejected) of the parent given as the
+// single placeholder, joined with their slots
+const parentReservationsSQL = `SELECT 1 FROM reservations r
+	JOIN children c ON c.id = r.child_id AND c.deleted_at IS NULL
+	JOIN slots s ON s.id = r.slot_id AND s.deleted_at IS NULL
+	WHERE c.parent_id = ? AND r.deleted_at IS NULL AND r.status <> 'rejected'`
+
+// ParseAnnouncementTime parses an RFC 3339 timestamp; an empty string means "not set"
+func ParseAnnouncementTime(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, NewValidationError(ErrInvalidDate, "Invalid timestamp. Use RFC 3339, e.g. 2025-01-31T08:00:00Z", map[string]interface{}{
+			"field": field,
+			"value": value,
+		})
+	}
+	return &t, nil
+}
+
+// ValidateAnnouncement checks the status, schedule and audience of an announcement
+func ValidateAnnouncement(ann models.Announcement) error {
+	if ann.Status != AnnouncementDraft && ann.Status != AnnouncementPublished {
+		return NewValidationError(ErrInvalidStatus, "Invalid status. Must be 'draft' or 'published'", map[string]interface{}{
+			"field": "status",
+			"value": ann.Status,
+		})
+	}
+	if ann.PublishAt != nil && ann.ExpiresAt != nil && !ann.ExpiresAt.After(*ann.PublishAt) {
+		return NewValidationError(ErrInvalidDate, "expires_at must be after publish_at", map[string]interface{}{
+			"publish_at": ann.PublishAt,
+			"expires_at": ann.ExpiresAt,
+		})
+	}
+
+	switch ann.Audience {
+	case AudienceAll, AudienceParents, AudienceAdmins:
+	case AudienceSlot:
+		if ann.AudienceSlotID == nil {
+			return NewValidationError(ErrInvalidInput, "audience_slot_id is required for the slot audience", map[string]interface{}{
+				"field": "audience_slot_id",
+			})
+		}
+		if err := NewBusinessLogicValidator().ValidateSlotExists(*ann.AudienceSlotID); err != nil {
+			return err
+		}
+	case AudienceDates:
+		for field, value := range map[string]string{"audience_from": ann.AudienceFrom, "audience_to": ann.AudienceTo} {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return NewValidationError(ErrInvalidDate, "Invalid date format. Use YYYY-MM-DD", map[string]interface{}{
+					"field":  field,
+					"value":  value,
+					"format": "YYYY-MM-DD",
+				})
+			}
+		}
+		if ann.AudienceFrom > ann.AudienceTo {
+			return NewValidationError(ErrInvalidDate, "audience_from must not be after audience_to", map[string]interface{}{
+				"audience_from": ann.AudienceFrom,
+				"audience_to":   ann.AudienceTo,
+			})
+		}
+	default:
+		return NewValidationError(ErrInvalidInput, "Invalid audience", map[string]interface{}{
+			"field":           "audience",
+			"value":           ann.Audience,
+			"valid_audiences": AnnouncementAudiences,
+		})
+	}
+	return nil
+}
+
+// AnnouncementIsLive reports whether a published announcement is inside its visibility window
+func AnnouncementIsLive(ann models.Announcement, now time.Time) bool {
+	return ann.Status != AnnouncementDraft &&
+		(ann.PublishAt == nil || !ann.PublishAt.After(now)) &&
+		(ann.ExpiresAt == nil || ann.ExpiresAt.After(now))
+}
+
+// LiveAnnouncements restricts a query to published announcements inside their
+// visibility window. Announcements created before scheduling existed may have a NULL
+// (or empty) status and audience; they count as published to everyone.
+func LiveAnnouncements(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("COALESCE(announcements.status, '') <> ?", AnnouncementDraft).
+		Where("announcements.publish_at IS NULL OR announcements.publish_at <= ?", now).
+		Where("announcements.expires_at IS NULL OR announcements.expires_at > ?", now)
+}
+
+// AnnouncementsVisibleTo restricts a query to the live announcements a viewer may see.
+// viewer is nil for anonymous visitors, who only see announcements for everyone.
+// Staff see every live announcement.
+func AnnouncementsVisibleTo(db *gorm.DB, viewer *models.User, now time.Time) *gorm.DB {
+	db = LiveAnnouncements(db, now)
+	switch {
+	case viewer == nil:
+		return db.Where("COALESCE(announcements.audience, '') IN ?", []string{"", AudienceAll})
+	case IsStaffRole(viewer.Role):
+		return db
+	default:
+		return db.Where(
+			config.DB.Where("COALESCE(announcements.audience, '') IN ?", []string{"", AudienceAll, AudienceParents}).
+				Or("announcements.audience = ? AND EXISTS ("+parentReservationsSQL+" AND s.id = announcements.audience_slot_id)", AudienceSlot, viewer.ID).
+				Or("announcements.audience = ? AND EXISTS ("+parentReservationsSQL+" AND s.date BETWEEN announcements.audience_from AND announcements.audience_to)", AudienceDates, viewer.ID),
+		)
+	}
+}
+
+// AnnouncementRecipients returns the users an announcement is addressed to
+func AnnouncementRecipients(ann models.Announcement) ([]models.User, error) {
+	var users []models.User
+	query := config.DB.Model(&models.User{})
+	switch ann.Audience {
+	case AudienceParents:
+		query = query.Where("role = ?", "parent")
+	case AudienceAdmins:
+		query = query.Where("role IN ?", StaffRoles())
+	case AudienceSlot, AudienceDates:
+		reservations := config.DB.Table("reservations r").
+			Select("c.parent_id").
+			Joins("JOIN children c ON c.id = r.child_id AND c.deleted_at IS NULL").
+			Joins("JOIN slots s ON s.id = r.slot_id AND s.deleted_at IS NULL").
+			Where("r.deleted_at IS NULL AND r.status <> 'rejected'")
+		if ann.Audience == AudienceSlot {
+			reservations = reservations.Where("s.id = ?", ann.AudienceSlotID)
+		} else {
+			reservations = reservations.Where("s.date BETWEEN ? AND ?", ann.AudienceFrom, ann.AudienceTo)
+		}
+		query = query.Where("id IN (?)", reservations)
+	}
+	err := query.Find(&users).Error
+	return users, err
+}
+
+// NotifyAnnouncement notifies an announcement's audience once. The notified_at claim keeps
+// the scheduler and the admin handlers (or several instances) from sending it twice.
+// It returns false when there was nothing to send.
+func NotifyAnnouncement(ann models.Announcement, now time.Time) bool {
+	if !AnnouncementIsLive(ann, now) {
+		return false
+	}
+	claim := config.DB.Model(&models.Announcement{}).
+		Where("id = ? AND notified_at IS NULL", ann.ID).
+		Update("notified_at", now)
+	if claim.Error != nil || claim.RowsAffected != 1 {
+		return false
+	}
+
+	users, err := AnnouncementRecipients(ann)
+	if err != nil {
+		zap.L().Error("Failed to load announcement recipients", zap.Uint("announcement_id", ann.ID), zap.Error(err))
+		return false
+	}
+	msg := NotificationMessage{Event: NotifyAnnouncements, Subject: ann.Title, Body: ann.Content, Urgent: ann.Urgent}
+	for _, user := range users {
+		_ = NotifyUser(user, msg)
+	}
+	return true
+}
+
+// PublishDueAnnouncements notifies the audience of scheduled announcements whose
+// publish_at has passed and returns how many were sent. Announcements without a
+// publish_at are notified by the admin handlers when they are published.
+func PublishDueAnnouncements(now time.Time) int {
+	var due []models.Announcement
+	err := LiveAnnouncements(config.DB, now).
+		Where("announcements.notified_at IS NULL AND announcements.publish_at IS NOT NULL").
+		Find(&due).Error
+	if err != nil {
+		zap.L().Error("Failed to load scheduled announcements", zap.Error(err))
+		return 0
+	}
+	sent := 0
+	for _, ann := range due {
+		if NotifyAnnouncement(ann, now) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// StartAnnouncementScheduler runs PublishDueAnnouncements every ANNOUNCEMENT_INTERVAL
+// (default 1m) until ctx is cancelled. It is safe to run on several instances at once.
+func StartAnnouncementScheduler(ctx context.Context) {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+	interval, err := time.ParseDuration(getenvDefault("ANNOUNCEMENT_INTERVAL", "1m"))
+	if err != nil || interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n := PublishDueAnnouncements(time.Now()); n > 0 {
+			zap.L().Info("Published scheduled announcements", zap.Int("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// MarkAnnouncementRead records that a user has read an announcement and, with acknowledge,
+// that they acknowledged it. Earlier timestamps are kept.
+func MarkAnnouncementRead(announcementID, userID uint, acknowledge bool, now time.Time) (models.AnnouncementReceipt, error) {
+	receipt := models.AnnouncementReceipt{AnnouncementID: announcementID, UserID: userID}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&receipt).Error; err != nil {
+		return receipt, err
+	}
+	config.DB.Model(&models.AnnouncementReceipt{}).
+		Where("announcement_id = ? AND user_id = ? AND read_at IS NULL", announcementID, userID).
+		Update("read_at", now)
+	if acknowledge {
+		config.DB.Model(&models.AnnouncementReceipt{}).
+			Where("announcement_id = ? AND user_id = ? AND acknowledged_at IS NULL", announcementID, userID).
+			Update("acknowledged_at", now)
+	}
+	err := config.DB.Where("announcement_id = ? AND user_id = ?", announcementID, userID).First(&receipt).Error
+	return receipt, err
+}
+
+// AnnouncementReceipts returns a user's receipts for the given announcements, by announcement ID
+func AnnouncementReceipts(userID uint, announcementIDs []uint) map[uint]models.AnnouncementReceipt {
+	receipts := map[uint]models.AnnouncementReceipt{}
+	if len(announcementIDs) == 0 {
+		return receipts
+	}
+	var rows []models.AnnouncementReceipt
+	config.DB.Where("user_id = ? AND announcement_id IN ?", userID, announcementIDs).Find(&rows)
+	for _, row := range rows {
+		receipts[row.AnnouncementID] = row
+	}
+	return receipts
+}
+
+// CountUnreadAnnouncements returns how many live announcements visible to the user are
+// unread, and how many that require acknowledgement are not yet acknowledged
+func CountUnreadAnnouncements(user models.User) (unread, unacknowledged int64) {
+	now := time.Now()
+	AnnouncementsVisibleTo(config.DB.Model(&models.Announcement{}), &user, now).
+		Where("NOT EXISTS (SELECT 1 FROM announcement_receipts ar WHERE ar.announcement_id = announcements.id AND ar.user_id = ? AND ar.read_at IS NOT NULL)", user.ID).
+		Count(&unread)
+	AnnouncementsVisibleTo(config.DB.Model(&models.Announcement{}), &user, now).
+		Where("announcements.requires_ack = ?", true).
+		Where("NOT EXISTS (SELECT 1 FROM announcement_receipts ar WHERE ar.announcement_id = announcements.id AND ar.user_id = ? AND ar.acknowledged_at IS NOT NULL)", user.ID).
+		Count(&unacknowledged)
+	return unread, unacknowledged
+}
+
+// AnnouncementReceiptStatus is one recipient's line in the acknowledgement report
+type AnnouncementReceiptStatus struct {
+	User           models.User
+	ReadAt         *time.Time
+	AcknowledgedAt *time.Time
+}
+
+// AnnouncementReceiptReport lists every recipient of an announcement with their receipt
+func AnnouncementReceiptReport(ann models.Announcement) ([]AnnouncementReceiptStatus, error) {
+	users, err := AnnouncementRecipients(ann)
+	if err != nil {
+		return nil, err
+	}
+	var rows []models.AnnouncementReceipt
+	if err := config.DB.Where("announcement_id = ?", ann.ID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byUser := map[uint]models.AnnouncementReceipt{}
+	for _, row := range rows {
+		byUser[row.UserID] = row
+	}
+	report := make([]AnnouncementReceiptStatus, 0, len(users))
+	for _, user := range users {
+		receipt := byUser[user.ID]
+		report = append(report, AnnouncementReceiptStatus{User: user, ReadAt: receipt.ReadAt, AcknowledgedAt: receipt.AcknowledgedAt})
+	}
+	return report, nil
+}
+
+// RemindAnnouncementRecipients re-sends an announcement to recipients who haven't
+// acknowledged it (or, when no acknowledgement is required, haven't read it) and
+// returns how many were reminded
+func RemindAnnouncementRecipients(ann models.Announcement) (int, error) {
+	report, err := AnnouncementReceiptReport(ann)
+	if err != nil {
+		return 0, err
+	}
+	subject := "Reminder: " + ann.Title
+	if ann.RequiresAck {
+		subject = "Please acknowledge: " + ann.Title
+	}
+	msg := NotificationMessage{Event: NotifyAnnouncements, Subject: subject, Body: ann.Content, Urgent: ann.Urgent}
+	reminded := 0
+	for _, status := range report {
+		if (ann.RequiresAck && status.AcknowledgedAt != nil) || (!ann.RequiresAck && status.ReadAt != nil) {
+			continue
+		}
+		_ = NotifyUser(status.User, msg)
+		reminded++
+	}
+	return reminded, nil
+}
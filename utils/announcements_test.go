@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncementIsLive(t *testing.T) {
+	now := time.Date(2030, 1, 10, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.True(t, AnnouncementIsLive(models.Announcement{Status: AnnouncementPublished}, now))
+	assert.True(t, AnnouncementIsLive(models.Announcement{}, now), "legacy rows without a status are published")
+	assert.False(t, AnnouncementIsLive(models.Announcement{Status: AnnouncementDraft}, now))
+	assert.False(t, AnnouncementIsLive(models.Announcement{Status: AnnouncementPublished, PublishAt: &future}, now))
+	assert.True(t, AnnouncementIsLive(models.Announcement{Status: AnnouncementPublished, PublishAt: &past, ExpiresAt: &future}, now))
+	assert.False(t, AnnouncementIsLive(models.Announcement{Status: AnnouncementPublished, ExpiresAt: &past}, now))
+}
+
+func TestValidateAnnouncement(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	assert.NoError(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementPublished, Audience: AudienceParents}))
+	assert.NoError(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementDraft, Audience: AudienceDates, AudienceFrom: "2030-01-01", AudienceTo: "2030-01-31"}))
+
+	assert.Error(t, ValidateAnnouncement(models.Announcement{Status: "archived", Audience: AudienceAll}))
+	assert.Error(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementPublished, Audience: "everyone"}))
+	assert.Error(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementPublished, Audience: AudienceSlot}))
+	assert.Error(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementPublished, Audience: AudienceDates, AudienceFrom: "2030-02-01", AudienceTo: "2030-01-01"}))
+	assert.Error(t, ValidateAnnouncement(models.Announcement{Status: AnnouncementPublished, Audience: AudienceAll, PublishAt: &later, ExpiresAt: &now}))
+}
+
+func TestParseAnnouncementTime(t *testing.T) {
+	ts, err := ParseAnnouncementTime("publish_at", "2030-01-10T08:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, 2030, ts.Year())
+
+	ts, err = ParseAnnouncementTime("publish_at", "")
+	assert.NoError(t, err)
+	assert.Nil(t, ts)
+
+	_, err = ParseAnnouncementTime("publish_at", "tomorrow")
+	assert.Error(t, err)
+}
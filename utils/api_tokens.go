@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+// API token scopes. "read" allows GET requests, "write" allows every method and "admin"
+// is additionally required for the admin API.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// ErrInsufficientScope is returned when a token's scopes don't cover the request
+const ErrInsufficientScope = "INSUFFICIENT_SCOPE"
+
+// APITokenScopes lists the valid scopes
+var APITokenScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+const (
+	// apiTokenPrefix marks Reservio tokens so secret scanners can recognise them
+	apiTokenPrefix = "rsv_"
+	// DefaultAPITokenDays and MaxAPITokenDays bound token lifetimes
+	DefaultAPITokenDays = 90
+	MaxAPITokenDays     = 365
+	// MaxAPITokensPerUser limits active tokens per user
+	MaxAPITokensPerUser = 20
+	// lastUsedPrecision avoids a database write on every request
+	lastUsedPrecision = time.Minute
+)
+
+// GenerateAPIToken returns a new random token and its display prefix
+func GenerateAPIToken() (token, prefix string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token = apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, token[:len(apiTokenPrefix)+6]
+}
+
+// ValidateAPITokenScopes checks the requested scopes are known and allowed for the role
+func ValidateAPITokenScopes(scopes []string, role string) error {
+	if len(scopes) == 0 {
+		return NewValidationError(ErrInvalidInput, "At least one scope is required", map[string]interface{}{
+			"field":        "scopes",
+			"valid_scopes": APITokenScopes,
+		})
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, valid := range APITokenScopes {
+			if scope == valid {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return NewValidationError(ErrInvalidInput, "Unknown scope", map[string]interface{}{
+				"field":        "scopes",
+				"value":        scope,
+				"valid_scopes": APITokenScopes,
+			})
+		}
+		if scope == ScopeAdmin && !IsStaffRole(role) {
+			return NewValidationError(ErrForbidden, "Only staff can create tokens with the admin scope", map[string]interface{}{
+				"field": "scopes",
+			})
+		}
+	}
+	return nil
+}
+
+// APITokenHasScope reports whether a token carries a scope
+func APITokenHasScope(token models.APIToken, scope string) bool {
+	for _, s := range strings.Split(token.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokenAllowsMethod reports whether a token's scopes permit the HTTP method
+func APITokenAllowsMethod(token models.APIToken, method string) bool {
+	if APITokenHasScope(token, ScopeWrite) {
+		return true
+	}
+	return (method == http.MethodGet || method == http.MethodHead) && APITokenHasScope(token, ScopeRead)
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// AuthenticateAPIToken looks up an active, unexpired token and records its use
+func AuthenticateAPIToken(raw, ip string, now time.Time) (models.APIToken, bool) {
+	var token models.APIToken
+	if !strings.HasPrefix(raw, apiTokenPrefix) || config.DB == nil {
+		return token, false
+	}
+	if err := config.DB.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", HashToken(raw), now).First(&token).Error; err != nil {
+		return token, false
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedPrecision || token.LastUsedIP != ip {
+		config.DB.Model(&models.APIToken{}).Where("id = ?", token.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip})
+	}
+	return token, true
+}
+
+// RevokeUserAPITokens revokes every active token of the user and returns how many
+func RevokeUserAPITokens(userID uint, now time.Time) int64 {
+	result := config.DB.Model(&models.APIToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now)
+	if result.Error != nil {
+		zap.L().Warn("Failed to revoke API tokens", zap.Uint("user_id", userID), zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
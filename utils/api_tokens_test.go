@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIToken(t *testing.T) {
+	token, prefix := GenerateAPIToken()
+	assert.True(t, strings.HasPrefix(token, "rsv_"))
+	assert.True(t, strings.HasPrefix(token, prefix))
+	assert.Len(t, prefix, 10)
+	other, _ := GenerateAPIToken()
+	assert.NotEqual(t, token, other)
+}
+
+func TestValidateAPITokenScopes(t *testing.T) {
+	assert.NoError(t, ValidateAPITokenScopes([]string{ScopeRead}, "parent"))
+	assert.NoError(t, ValidateAPITokenScopes([]string{ScopeRead, ScopeAdmin}, "admin"))
+	assert.Error(t, ValidateAPITokenScopes(nil, "parent"))
+	assert.Error(t, ValidateAPITokenScopes([]string{"delete"}, "parent"))
+	assert.Error(t, ValidateAPITokenScopes([]string{ScopeAdmin}, "parent"))
+}
+
+func TestAPITokenAllowsMethod(t *testing.T) {
+	read := models.APIToken{Scopes: "read"}
+	assert.True(t, APITokenAllowsMethod(read, "GET"))
+	assert.False(t, APITokenAllowsMethod(read, "POST"))
+	assert.False(t, APITokenHasScope(read, ScopeAdmin))
+
+	write := models.APIToken{Scopes: "read,write"}
+	assert.True(t, APITokenAllowsMethod(write, "DELETE"))
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	_, ok := BearerToken(r)
+	assert.False(t, ok)
+
+	r.Header.Set("Authorization", "Bearer rsv_abc")
+	token, ok := BearerToken(r)
+	assert.True(t, ok)
+	assert.Equal(t, "rsv_abc", token)
+
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	_, ok = BearerToken(r)
+	assert.False(t, ok)
+}
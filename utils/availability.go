@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+// availabilityChannel is the Redis pub/sub channel that fans availability updates
+// out to every app instance
+const availabilityChannel = "reservio:availability"
+
+// AvailabilityUpdate is pushed to live subscribers whenever a slot's availability may
+// have changed. Field names match GetSlotAvailability.
+type AvailabilityUpdate struct {
+	Event          string `json:"event"`
+	SlotID         uint   `json:"slot_id"`
+	Date           string `json:"date"`
+	TotalCapacity  int    `json:"total_capacity"`
+	BookedSlots    int64  `json:"booked_slots"`
+	AvailableSlots int    `json:"available_slots"`
+	IsFull         bool   `json:"is_full"`
+	Deleted        bool   `json:"deleted,omitempty"`
+}
+
+// AvailabilityFilter selects which updates a subscriber receives: specific slots
+// and/or an inclusive date range (YYYY-MM-DD). An empty filter matches everything.
+type AvailabilityFilter struct {
+	SlotIDs map[uint]bool
+	From    string
+	To      string
+}
+
+// Matches reports whether an update passes the filter
+func (f AvailabilityFilter) Matches(update AvailabilityUpdate) bool {
+	if len(f.SlotIDs) > 0 && !f.SlotIDs[update.SlotID] {
+		return false
+	}
+	if f.From != "" && update.Date < f.From {
+		return false
+	}
+	if f.To != "" && update.Date > f.To {
+		return false
+	}
+	return true
+}
+
+// ParseAvailabilityFilter builds a filter from the slot_ids, from and to query parameters
+func ParseAvailabilityFilter(slotIDs, from, to string) (AvailabilityFilter, error) {
+	filter := AvailabilityFilter{From: from, To: to}
+	if slotIDs != "" {
+		filter.SlotIDs = map[uint]bool{}
+		for _, raw := range strings.Split(slotIDs, ",") {
+			id, err := ParseUint(strings.TrimSpace(raw))
+			if err != nil {
+				return filter, NewValidationError(ErrInvalidInput, "Invalid slot ID", map[string]interface{}{
+					"field": "slot_ids",
+					"value": raw,
+				})
+			}
+			filter.SlotIDs[id] = true
+		}
+	}
+	for field, value := range map[string]string{"from": from, "to": to} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return filter, NewValidationError(ErrInvalidDate, "Invalid date format. Use YYYY-MM-DD", map[string]interface{}{
+				"field":  field,
+				"value":  value,
+				"format": "YYYY-MM-DD",
+			})
+		}
+	}
+	if from != "" && to != "" && from > to {
+		return filter, NewValidationError(ErrInvalidDate, "from must not be after to", map[string]interface{}{
+			"from": from,
+			"to":   to,
+		})
+	}
+	return filter, nil
+}
+
+// AvailabilitySubscription receives matching updates on C until it is unsubscribed.
+// Done is closed when the server shuts down so long-lived streams can end.
+type AvailabilitySubscription struct {
+	C      chan AvailabilityUpdate
+	Done   <-chan struct{}
+	filter AvailabilityFilter
+}
+
+var (
+	availabilityShutdown     = make(chan struct{})
+	availabilityShutdownOnce sync.Once
+)
+
+// CloseAvailabilityStreams signals every live subscriber to finish (register with
+// http.Server.RegisterOnShutdown, as Shutdown does not interrupt open streams)
+func CloseAvailabilityStreams() {
+	availabilityShutdownOnce.Do(func() { close(availabilityShutdown) })
+}
+
+var availabilitySubscribers = struct {
+	sync.RWMutex
+	subs map[*AvailabilitySubscription]struct{}
+}{subs: map[*AvailabilitySubscription]struct{}{}}
+
+// SubscribeAvailability registers a live subscriber on this instance
+func SubscribeAvailability(filter AvailabilityFilter) *AvailabilitySubscription {
+	sub := &AvailabilitySubscription{C: make(chan AvailabilityUpdate, 32), Done: availabilityShutdown, filter: filter}
+	availabilitySubscribers.Lock()
+	availabilitySubscribers.subs[sub] = struct{}{}
+	availabilitySubscribers.Unlock()
+	return sub
+}
+
+// UnsubscribeAvailability removes a subscriber; C is not closed so pending reads don't panic
+func UnsubscribeAvailability(sub *AvailabilitySubscription) {
+	availabilitySubscribers.Lock()
+	delete(availabilitySubscribers.subs, sub)
+	availabilitySubscribers.Unlock()
+}
+
+// dispatchAvailability delivers an update to this instance's subscribers. Slow
+// subscribers whose buffer is full miss the update rather than blocking others.
+func dispatchAvailability(update AvailabilityUpdate) {
+	availabilitySubscribers.RLock()
+	defer availabilitySubscribers.RUnlock()
+	for sub := range availabilitySubscribers.subs {
+		if !sub.filter.Matches(update) {
+			continue
+		}
+		select {
+		case sub.C <- update:
+		default:
+			zap.L().Debug("Dropping availability update for slow subscriber", zap.Uint("slot_id", update.SlotID))
+		}
+	}
+}
+
+// SlotAvailability computes the current availability of a slot
+func SlotAvailability(slot models.Slot) AvailabilityUpdate {
+	var booked int64
+	config.DB.Model(&models.Reservation{}).Where("slot_id = ? AND status != 'rejected'", slot.ID).Count(&booked)
+	available := slot.Capacity - int(booked)
+	if available < 0 {
+		available = 0
+	}
+	return AvailabilityUpdate{
+		SlotID:         slot.ID,
+		Date:           slot.Date,
+		TotalCapacity:  slot.Capacity,
+		BookedSlots:    booked,
+		AvailableSlots: available,
+		IsFull:         available == 0,
+	}
+}
+
+// PublishAvailability sends an update to subscribers on every instance. With Redis
+// configured it goes through pub/sub (and comes back to this instance via the relay);
+// otherwise, or if Redis is unreachable, it is dispatched locally.
+func PublishAvailability(update AvailabilityUpdate) {
+	payload, _ := json.Marshal(update)
+	publishToInstances(availabilityChannel, payload, func([]byte) { dispatchAvailability(update) })
+}
+
+// publishAvailabilityForEvent turns reservation and slot domain events into availability updates
+func publishAvailabilityForEvent(event DomainEvent) {
+	if config.DB == nil {
+		return
+	}
+	var slotID uint
+	switch event.Type {
+	case EventReservationCreated, EventReservationApproved, EventReservationRejected, EventReservationCancelled:
+		if reservation, ok := event.Data["reservation"].(map[string]interface{}); ok {
+			slotID, _ = reservation["slot_id"].(uint)
+		}
+	case EventSlotCreated, EventSlotUpdated:
+		if slot, ok := event.Data["slot"].(map[string]interface{}); ok {
+			slotID, _ = slot["id"].(uint)
+		}
+	case EventSlotDeleted:
+		if slot, ok := event.Data["slot"].(map[string]interface{}); ok {
+			id, _ := slot["id"].(uint)
+			date, _ := slot["date"].(string)
+			PublishAvailability(AvailabilityUpdate{Event: event.Type, SlotID: id, Date: date, IsFull: true, Deleted: true})
+		}
+		return
+	default:
+		return
+	}
+	if slotID == 0 {
+		return
+	}
+
+	var slot models.Slot
+	if err := config.DB.First(&slot, slotID).Error; err != nil {
+		return
+	}
+	update := SlotAvailability(slot)
+	update.Event = event.Type
+	PublishAvailability(update)
+}
+
+func init() {
+	SubscribeEvents(publishAvailabilityForEvent)
+}
+
+// StartAvailabilityRelay forwards updates published on Redis by any instance to this
+// instance's subscribers until ctx is cancelled. It does nothing without Redis.
+func StartAvailabilityRelay(ctx context.Context) {
+	relayFromInstances(ctx, availabilityChannel, func(payload []byte) {
+		var update AvailabilityUpdate
+		if err := json.Unmarshal(payload, &update); err == nil {
+			dispatchAvailability(update)
+		}
+	})
+}
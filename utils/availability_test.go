@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAvailabilityFilter(t *testing.T) {
+	filter, err := ParseAvailabilityFilter("1, 3", "2030-01-01", "2030-01-31")
+	assert.NoError(t, err)
+	assert.True(t, filter.Matches(AvailabilityUpdate{SlotID: 3, Date: "2030-01-15"}))
+	assert.False(t, filter.Matches(AvailabilityUpdate{SlotID: 2, Date: "2030-01-15"}))
+	assert.False(t, filter.Matches(AvailabilityUpdate{SlotID: 1, Date: "2030-02-01"}))
+
+	all, err := ParseAvailabilityFilter("", "", "")
+	assert.NoError(t, err)
+	assert.True(t, all.Matches(AvailabilityUpdate{SlotID: 42, Date: "2031-06-01"}))
+
+	_, err = ParseAvailabilityFilter("abc", "", "")
+	assert.Error(t, err)
+	_, err = ParseAvailabilityFilter("", "01/01/2030", "")
+	assert.Error(t, err)
+	_, err = ParseAvailabilityFilter("", "2030-02-01", "2030-01-01")
+	assert.Error(t, err)
+}
+
+func TestDispatchAvailability(t *testing.T) {
+	matching := SubscribeAvailability(AvailabilityFilter{SlotIDs: map[uint]bool{7: true}})
+	defer UnsubscribeAvailability(matching)
+	other := SubscribeAvailability(AvailabilityFilter{SlotIDs: map[uint]bool{8: true}})
+	defer UnsubscribeAvailability(other)
+
+	dispatchAvailability(AvailabilityUpdate{SlotID: 7, AvailableSlots: 2})
+
+	select {
+	case update := <-matching.C:
+		assert.Equal(t, 2, update.AvailableSlots)
+	default:
+		t.Fatal("expected an update for slot 7")
+	}
+	assert.Len(t, other.C, 0)
+}
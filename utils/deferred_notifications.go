@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// deferredNotificationBatch caps how many held-back notifications one run sends
+	deferredNotificationBatch = 200
+	// deferredNotificationLease is how long a claimed notification stays hidden from other runs
+	deferredNotificationLease = 5 * time.Minute
+	// deferredNotificationBackoff is the wait after a failed attempt, multiplied by the attempt count
+	deferredNotificationBackoff = 5 * time.Minute
+	// deferredNotificationMaxAttempts is how often a notification is tried before it is dropped
+	deferredNotificationMaxAttempts = 10
+)
+
+// deferNotification stores an email or SMS to be sent at deliverAt
+func deferNotification(userID uint, channel string, msg NotificationMessage, deliverAt time.Time) error {
+	return config.DB.Create(&models.DeferredNotification{
+		UserID:    userID,
+		Channel:   channel,
+		Event:     msg.Event,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+		DeliverAt: deliverAt,
+	}).Error
+}
+
+// DeliverDeferredNotifications sends the notifications held back by quiet hours whose
+// delivery time has come and returns how many were sent. A channel the user switched off in
+// the meantime is skipped; a user whose quiet hours moved gets the message after the new
+// window ends.
+func DeliverDeferredNotifications(now time.Time) int {
+	var due []models.DeferredNotification
+	if err := config.DB.Where("deliver_at <= ?", now).Order("deliver_at, id").Limit(deferredNotificationBatch).Find(&due).Error; err != nil {
+		zap.L().Error("Failed to load deferred notifications", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, item := range due {
+		if deliverDeferredNotification(item, now) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// deliverDeferredNotification claims one held-back notification and sends it. The claim
+// (bumping attempts and leasing deliver_at) keeps other instances from sending it at the
+// same time. The row is only deleted once the message was sent or is no longer wanted;
+// failures put it back with a backoff, up to deferredNotificationMaxAttempts.
+func deliverDeferredNotification(item models.DeferredNotification, now time.Time) bool {
+	claim := config.DB.Model(&models.DeferredNotification{}).
+		Where("id = ? AND attempts = ?", item.ID, item.Attempts).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "deliver_at": now.Add(deferredNotificationLease)})
+	if claim.Error != nil || claim.RowsAffected != 1 {
+		return false
+	}
+	item.Attempts++
+
+	retry := func(err error) bool {
+		if item.Attempts >= deferredNotificationMaxAttempts {
+			zap.L().Error("Giving up on deferred notification", zap.Uint("user_id", item.UserID), zap.String("channel", item.Channel), zap.Error(err))
+			config.DB.Delete(&models.DeferredNotification{}, item.ID)
+			return false
+		}
+		zap.L().Warn("Deferred notification delivery failed", zap.Uint("user_id", item.UserID), zap.String("channel", item.Channel), zap.Error(err))
+		config.DB.Model(&models.DeferredNotification{}).Where("id = ?", item.ID).
+			Update("deliver_at", now.Add(time.Duration(item.Attempts)*deferredNotificationBackoff))
+		return false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, item.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			config.DB.Delete(&models.DeferredNotification{}, item.ID)
+			return false
+		}
+		return retry(err)
+	}
+	pref, err := GetNotificationPreference(user.ID)
+	if err != nil {
+		return retry(err)
+	}
+	if !NotificationChannelEnabled(pref, item.Event, item.Channel) {
+		config.DB.Delete(&models.DeferredNotification{}, item.ID)
+		return false
+	}
+	if InQuietHours(pref, now) {
+		// Waiting for quiet hours to end isn't a failed attempt
+		config.DB.Model(&models.DeferredNotification{}).Where("id = ?", item.ID).
+			Updates(map[string]interface{}{"attempts": item.Attempts - 1, "deliver_at": QuietHoursEnd(pref, now)})
+		return false
+	}
+	sender := notificationSender(item.Channel)
+	if sender == nil {
+		return retry(errors.New("no sender for channel " + item.Channel))
+	}
+	if err := sender(user, NotificationMessage{Event: item.Event, Subject: item.Subject, Body: item.Body}); err != nil {
+		return retry(err)
+	}
+	config.DB.Delete(&models.DeferredNotification{}, item.ID)
+	return true
+}
+
+// StartDeferredNotificationSender runs DeliverDeferredNotifications every minute until
+// ctx is cancelled. It is safe to run on several instances at once.
+func StartDeferredNotificationSender(ctx context.Context) {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		if n := DeliverDeferredNotifications(time.Now()); n > 0 {
+			zap.L().Info("Sent notifications held back by quiet hours", zap.Int("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"reservio/models"
+)
+
+const (
+	// PurposeEmailVerification marks email verification tokens
+	PurposeEmailVerification = "email_verify"
+	// ErrEmailNotVerified is returned when the policy requires a verified email
+	ErrEmailNotVerified = "EMAIL_NOT_VERIFIED"
+	// verificationResendInterval limits how often a user can ask for a new email
+	verificationResendInterval = time.Minute
+)
+
+// EmailVerificationTTL is how long verification links work (EMAIL_VERIFICATION_TTL, default 24h)
+func EmailVerificationTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 24 * time.Hour
+}
+
+// FrontendURL builds a link to a front-end page (FRONTEND_URL, default http://localhost:3000)
+func FrontendURL(path string, query url.Values) string {
+	link := strings.TrimRight(getenvDefault("FRONTEND_URL", "http://localhost:3000"), "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// EmailVerificationToken creates a verification token for the address
+func EmailVerificationToken(userID uint, email string, now time.Time) string {
+	return SignToken(SignedToken{
+		Purpose: PurposeEmailVerification,
+		UserID:  userID,
+		Email:   strings.ToLower(email),
+		Expires: now.Add(EmailVerificationTTL()).Unix(),
+	})
+}
+
+// SendEmailVerification emails a verification link to the address (the account email on
+// registration, the pending address on an email change). It goes straight to the
+// address, not through notification preferences.
+func SendEmailVerification(user models.User, email string, now time.Time) error {
+	link := FrontendURL("/verify-email", url.Values{"token": {EmailVerificationToken(user.ID, email, now)}})
+	body := "Please confirm your email address for Reservio: " + link +
+		"\n\nThe link is valid for " + EmailVerificationTTL().String() + ". If you didn't request this, ignore this email."
+	return SendMail(email, "Confirm your email address", body)
+}
+
+// CanResendVerification reports whether enough time passed since the last verification email
+func CanResendVerification(user models.User, now time.Time) bool {
+	return user.VerificationSentAt == nil || now.Sub(*user.VerificationSentAt) >= verificationResendInterval
+}
+
+// EmailVerificationRequired reports whether the policy blocks this user from reserving
+func EmailVerificationRequired(user models.User) bool {
+	return !user.EmailVerified && SettingBool(SettingRequireVerifiedEmail)
+}
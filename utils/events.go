@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"reservio/models"
+)
+
+// Domain event types published by the controllers
+const (
+	EventReservationCreated    = "reservation.created"
+	EventReservationApproved   = "reservation.approved"
+	EventReservationRejected   = "reservation.rejected"
+	EventReservationCancelled  = "reservation.cancelled"
+	EventReservationCheckedIn  = "reservation.checked_in"
+	EventReservationCheckedOut = "reservation.checked_out"
+	EventSlotCreated           = "slot.created"
+	EventSlotUpdated           = "slot.updated"
+	EventSlotDeleted           = "slot.deleted"
+	EventUserRegistered        = "user.registered"
+)
+
+// DomainEventTypes lists every event type that can be published
+var DomainEventTypes = []string{
+	EventReservationCreated, EventReservationApproved, EventReservationRejected, EventReservationCancelled,
+	EventReservationCheckedIn, EventReservationCheckedOut,
+	EventSlotCreated, EventSlotUpdated, EventSlotDeleted,
+	EventUserRegistered,
+}
+
+// DomainEvent is something that happened in the system that other parts
+// (webhooks, live feeds) may react to.
+type DomainEvent struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	OccurredAt time.Time              `json:"created_at"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// EventHandler reacts to a published domain event. Handlers run synchronously
+// on the publishing instance, so they must be quick.
+type EventHandler func(DomainEvent)
+
+var eventHandlers = struct {
+	sync.RWMutex
+	list []EventHandler
+}{}
+
+// SubscribeEvents registers a handler for every published domain event
+func SubscribeEvents(handler EventHandler) {
+	eventHandlers.Lock()
+	defer eventHandlers.Unlock()
+	eventHandlers.list = append(eventHandlers.list, handler)
+}
+
+// PublishEvent hands a new domain event to every subscribed handler
+func PublishEvent(eventType string, data map[string]interface{}) DomainEvent {
+	event := DomainEvent{ID: newEventID(), Type: eventType, OccurredAt: time.Now().UTC(), Data: data}
+
+	eventHandlers.RLock()
+	handlers := append([]EventHandler(nil), eventHandlers.list...)
+	eventHandlers.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return event
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ReservationEventData is the event payload describing a reservation
+func ReservationEventData(reservation models.Reservation) map[string]interface{} {
+	return map[string]interface{}{
+		"reservation": map[string]interface{}{
+			"id":       reservation.ID,
+			"child_id": reservation.ChildID,
+			"slot_id":  reservation.SlotID,
+			"status":   reservation.Status,
+		},
+	}
+}
+
+// SlotEventData is the event payload describing a slot
+func SlotEventData(slot models.Slot) map[string]interface{} {
+	return map[string]interface{}{
+		"slot": map[string]interface{}{
+			"id":       slot.ID,
+			"date":     slot.Date,
+			"capacity": slot.Capacity,
+		},
+	}
+}
+
+// UserEventData is the event payload describing a user (never includes credentials)
+func UserEventData(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"user": map[string]interface{}{
+			"id":         user.ID,
+			"email":      user.Email,
+			"role":       user.Role,
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+		},
+	}
+}
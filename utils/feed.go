@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"reservio/models"
+)
+
+// FeedInfo describes an announcement feed. BaseURL is the public site address entry
+// links point to; SelfURL is the feed's own address.
+type FeedInfo struct {
+	Title   string
+	BaseURL string
+	SelfURL string
+}
+
+// FeedTitle is the title of the announcement feeds (FEED_TITLE, default "Reservio announcements")
+func FeedTitle() string {
+	return getenvDefault("FEED_TITLE", "Reservio announcements")
+}
+
+// PublicURL returns the externally visible base URL of the site: PUBLIC_BASE_URL when
+// set, otherwise derived from the request (honouring X-Forwarded-Proto)
+func PublicURL(r *http.Request) string {
+	if base := getenvDefault("PUBLIC_BASE_URL", ""); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// FeedLastModified returns the newest UpdatedAt of the announcements (zero if none)
+func FeedLastModified(announcements []models.Announcement) time.Time {
+	var latest time.Time
+	for _, a := range announcements {
+		if a.UpdatedAt.After(latest) {
+			latest = a.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// FeedETag returns a strong ETag for a rendered feed body
+func FeedETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func announcementPublishedAt(a models.Announcement) time.Time {
+	if a.PublishAt != nil {
+		return *a.PublishAt
+	}
+	return a.CreatedAt
+}
+
+func announcementLink(info FeedInfo, a models.Announcement) string {
+	return fmt.Sprintf("%s/announcements/%d", info.BaseURL, a.ID)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Content   atomText    `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// BuildAtomFeed renders announcements (newest first) as an Atom 1.0 feed. Entry
+// timestamps come from UpdatedAt, so edits show up in feed readers.
+func BuildAtomFeed(info FeedInfo, announcements []models.Announcement) ([]byte, error) {
+	updated := FeedLastModified(announcements)
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	feed := atomFeed{
+		Title:   info.Title,
+		ID:      info.SelfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: info.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: info.BaseURL, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomPerson{Name: info.Title},
+	}
+	for _, a := range announcements {
+		link := announcementLink(info, a)
+		entry := atomEntry{
+			Title:     a.Title,
+			ID:        link,
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: announcementPublishedAt(a).UTC().Format(time.RFC3339),
+			Updated:   a.UpdatedAt.UTC().Format(time.RFC3339),
+			Content:   atomText{Type: "html", Body: RenderMarkdown(a.Content)},
+		}
+		if a.Author != nil && a.Author.FirstName != "" {
+			entry.Author = &atomPerson{Name: strings.TrimSpace(a.Author.FirstName + " " + a.Author.LastName)}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshalFeed(feed)
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	SelfLink      atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// BuildRSSFeed renders announcements (newest first) as an RSS 2.0 feed
+func BuildRSSFeed(info FeedInfo, announcements []models.Announcement) ([]byte, error) {
+	channel := rssChannel{
+		Title:       info.Title,
+		Link:        info.BaseURL,
+		Description: info.Title,
+		SelfLink:    atomLink{Href: info.SelfURL, Rel: "self", Type: "application/rss+xml"},
+	}
+	if updated := FeedLastModified(announcements); !updated.IsZero() {
+		channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, a := range announcements {
+		link := announcementLink(info, a)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       a.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     announcementPublishedAt(a).UTC().Format(time.RFC1123Z),
+			Description: RenderMarkdown(a.Content),
+		})
+	}
+	return marshalFeed(rssFeed{Version: "2.0", Channel: channel})
+}
+
+func marshalFeed(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var feedInfo = FeedInfo{
+	Title:   "Reservio announcements",
+	BaseURL: "https://reservio.example",
+	SelfURL: "https://reservio.example/api/announcements/feed.atom",
+}
+
+func feedAnnouncements() []models.Announcement {
+	created := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	publish := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	return []models.Announcement{
+		{
+			Model:     gorm.Model{ID: 2, CreatedAt: created, UpdatedAt: time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)},
+			Title:     "Closed on Friday",
+			Content:   "**No** care on Friday <script>x</script>",
+			PublishAt: &publish,
+		},
+		{
+			Model:   gorm.Model{ID: 1, CreatedAt: created, UpdatedAt: created},
+			Title:   "Welcome & hello",
+			Content: "First post",
+		},
+	}
+}
+
+func TestBuildAtomFeed(t *testing.T) {
+	body, err := BuildAtomFeed(feedInfo, feedAnnouncements())
+	assert.NoError(t, err)
+
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Content   string `xml:"content"`
+		} `xml:"entry"`
+	}
+	assert.NoError(t, xml.Unmarshal(body, &feed))
+	assert.Equal(t, "2026-03-05T10:00:00Z", feed.Updated)
+	assert.Len(t, feed.Entries, 2)
+	assert.Equal(t, "https://reservio.example/announcements/2", feed.Entries[0].ID)
+	assert.Equal(t, "2026-03-02T09:00:00Z", feed.Entries[0].Published)
+	assert.Equal(t, "2026-03-05T10:00:00Z", feed.Entries[0].Updated)
+	assert.Contains(t, feed.Entries[0].Content, "<strong>No</strong>")
+	assert.NotContains(t, feed.Entries[0].Content, "<script")
+	assert.Equal(t, "Welcome & hello", feed.Entries[1].Title)
+}
+
+func TestBuildRSSFeed(t *testing.T) {
+	body, err := BuildRSSFeed(feedInfo, feedAnnouncements())
+	assert.NoError(t, err)
+
+	var feed struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	assert.NoError(t, xml.Unmarshal(body, &feed))
+	assert.Equal(t, "2.0", feed.Version)
+	assert.Equal(t, "Thu, 05 Mar 2026 10:00:00 +0000", feed.Channel.LastBuildDate)
+	assert.Len(t, feed.Channel.Items, 2)
+	assert.Equal(t, "https://reservio.example/announcements/2", feed.Channel.Items[0].GUID)
+	assert.Equal(t, "Mon, 02 Mar 2026 09:00:00 +0000", feed.Channel.Items[0].PubDate)
+	assert.Contains(t, string(body), `<link xmlns="http://www.w3.org/2005/Atom" href="https://reservio.example/api/announcements/feed.atom" rel="self"`)
+}
+
+func TestBuildAtomFeedEmpty(t *testing.T) {
+	body, err := BuildAtomFeed(feedInfo, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "<updated>1970-01-01T00:00:00Z</updated>")
+}
+
+func TestFeedETag(t *testing.T) {
+	assert.Equal(t, FeedETag([]byte("a")), FeedETag([]byte("a")))
+	assert.NotEqual(t, FeedETag([]byte("a")), FeedETag([]byte("b")))
+}
+
+func TestPublicURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/announcements/feed.atom", nil)
+	r.Host = "api.example"
+	assert.Equal(t, "http://api.example", PublicURL(r))
+
+	r.Header.Set("X-Forwarded-Proto", "https")
+	assert.Equal(t, "https://api.example", PublicURL(r))
+
+	t.Setenv("PUBLIC_BASE_URL", "https://reservio.example/")
+	assert.Equal(t, "https://reservio.example", PublicURL(r))
+}
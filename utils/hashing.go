@@ -9,7 +9,6 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"reservio/config"
@@ -19,39 +18,6 @@ import (
 	"go.uber.org/zap"
 )
 
-// Brute-force login attempt tracker (in-memory, can be replaced with Redis)
-type LoginAttempt struct {
-	Count      int
-	LastFailed int64
-}
-
-var loginAttempts = struct {
-	sync.Mutex
-	m map[string]LoginAttempt
-}{m: make(map[string]LoginAttempt)}
-
-func IncrementLoginAttempt(email string) int {
-	loginAttempts.Lock()
-	defer loginAttempts.Unlock()
-	la := loginAttempts.m[email]
-	la.Count++
-	la.LastFailed = time.Now().Unix()
-	loginAttempts.m[email] = la
-	return la.Count
-}
-
-func ResetLoginAttempt(email string) {
-	loginAttempts.Lock()
-	defer loginAttempts.Unlock()
-	delete(loginAttempts.m, email)
-}
-
-func GetLoginAttempt(email string) LoginAttempt {
-	loginAttempts.Lock()
-	defer loginAttempts.Unlock()
-	return loginAttempts.m[email]
-}
-
 // Session helpers for gorilla/sessions
 func SetSession(w http.ResponseWriter, r *http.Request, userID uint) {
 	session, _ := config.Store.Get(r, "session")
@@ -68,6 +34,15 @@ func SetSession(w http.ResponseWriter, r *http.Request, userID uint) {
 		}
 	}
 
+	// Keep the tracked session when renewing it, otherwise start a new one
+	now := time.Now()
+	if config.DB != nil {
+		if _, ok := findSession(session, userID, now); !ok {
+			revokeCurrentSession(session)
+			trackSession(r, session, userID, now)
+		}
+	}
+
 	// Ensure we have a CSRF token for this new/updated session
 	token, _ := session.Values["csrf_token"].(string)
 	if token == "" {
@@ -86,7 +61,7 @@ func SetSession(w http.ResponseWriter, r *http.Request, userID uint) {
 
 	session.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   3600,
+		MaxAge:   SessionMaxAge,
 		HttpOnly: true,
 		Secure:   secureCookie,
 		SameSite: http.SameSiteStrictMode,
@@ -99,17 +74,35 @@ func SetSession(w http.ResponseWriter, r *http.Request, userID uint) {
 	w.Header().Set("X-CSRF-Token", token)
 }
 
+// SessionUserID returns the user ID stored in the session cookie, without checking
+// whether the session is still valid
+func SessionUserID(r *http.Request) (uint, bool) {
+	session, _ := config.Store.Get(r, "session")
+	idStr, _ := session.Values["user_id"].(string)
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
 func ClearSession(w http.ResponseWriter, r *http.Request) {
 	session, _ := config.Store.Get(r, "session")
+	revokeCurrentSession(session)
+	endImpersonation(session, time.Now())
 	delete(session.Values, "user_id")
+	delete(session.Values, sessionIDKey)
 	session.Options.MaxAge = -1
 	if err := session.Save(r, w); err != nil {
 		zap.L().Warn("ClearSession save error", zap.Error(err))
 	}
 }
 
-// Invalidate current session (global logout requires tracking all user sessions)
-func InvalidateAllUserSessions(w http.ResponseWriter, r *http.Request) {
+// InvalidateAllUserSessions ends every tracked session of the user, revokes their personal
+// access tokens and clears the current session
+func InvalidateAllUserSessions(w http.ResponseWriter, r *http.Request, userID uint) {
+	RevokeUserSessions(userID, 0)
+	RevokeUserAPITokens(userID, time.Now())
 	ClearSession(w, r)
 }
 
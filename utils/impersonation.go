@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"github.com/gorilla/sessions"
+	"go.uber.org/zap"
+)
+
+const (
+	// ImpersonationTTL is how long an impersonation lasts unless the admin ends it sooner
+	ImpersonationTTL = 30 * time.Minute
+	// ErrImpersonationForbidden is returned for actions that can't be taken while impersonating
+	ErrImpersonationForbidden = "IMPERSONATION_FORBIDDEN"
+	// impersonationKey is the session value holding the active impersonation ID
+	impersonationKey = "impersonation_id"
+	// maxImpersonationReason is the longest reason stored for an impersonation
+	maxImpersonationReason = 500
+)
+
+// ValidateImpersonation checks that the admin may impersonate the target: only other,
+// non-staff accounts can be impersonated, and a reason is required for the audit trail
+func ValidateImpersonation(admin, target models.User, reason string) error {
+	if admin.ID == target.ID {
+		return NewValidationError(ErrInvalidInput, "You can't impersonate yourself", nil)
+	}
+	if IsStaffRole(target.Role) {
+		return NewValidationError(ErrForbidden, "Only parent accounts can be impersonated", map[string]interface{}{
+			"role": target.Role,
+		})
+	}
+	if reason == "" || len(reason) > maxImpersonationReason {
+		return NewValidationError(ErrInvalidInput, "A reason of at most 500 characters is required", map[string]interface{}{
+			"field": "reason",
+		})
+	}
+	return nil
+}
+
+// StartImpersonation records a new impersonation and attaches it to the admin's session,
+// ending any impersonation the session already had
+func StartImpersonation(w http.ResponseWriter, r *http.Request, admin, target models.User, reason string, now time.Time) (models.Impersonation, error) {
+	session, _ := config.Store.Get(r, "session")
+	endImpersonation(session, now)
+
+	ua := r.UserAgent()
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	imp := models.Impersonation{
+		AdminID:   admin.ID,
+		UserID:    target.ID,
+		Reason:    reason,
+		IP:        ClientIP(r),
+		UserAgent: ua,
+		StartedAt: now,
+		ExpiresAt: now.Add(ImpersonationTTL),
+	}
+	if err := config.DB.Create(&imp).Error; err != nil {
+		return imp, err
+	}
+	session.Values[impersonationKey] = strconv.FormatUint(uint64(imp.ID), 10)
+	if err := session.Save(r, w); err != nil {
+		return imp, err
+	}
+	zap.L().Info("Impersonation started", zap.Uint("admin_id", admin.ID), zap.Uint("user_id", target.ID), zap.Uint("impersonation_id", imp.ID))
+	return imp, nil
+}
+
+// ActiveImpersonation returns the impersonation of the admin's session, if one is running.
+// Impersonations that ended, expired or whose admin lost the permission are detached.
+func ActiveImpersonation(w http.ResponseWriter, r *http.Request, session *sessions.Session, adminID uint, now time.Time) (models.Impersonation, bool) {
+	var imp models.Impersonation
+	idStr, _ := session.Values[impersonationKey].(string)
+	if idStr == "" || config.DB == nil {
+		return imp, false
+	}
+	err := config.DB.Where("id = ? AND admin_id = ? AND ended_at IS NULL AND expires_at > ?", idStr, adminID, now).First(&imp).Error
+	if err == nil {
+		var admin models.User
+		if config.DB.Select("role").First(&admin, adminID).Error == nil && HasPermission(admin.Role, PermUsersImpersonate) {
+			return imp, true
+		}
+	}
+	endImpersonation(session, now)
+	if err := session.Save(r, w); err != nil {
+		zap.L().Warn("Failed to detach impersonation", zap.Error(err))
+	}
+	return imp, false
+}
+
+// EndImpersonation ends the impersonation of the request's session
+func EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	session, _ := config.Store.Get(r, "session")
+	endImpersonation(session, time.Now())
+	if err := session.Save(r, w); err != nil {
+		zap.L().Warn("EndImpersonation save error", zap.Error(err))
+	}
+}
+
+// endImpersonation marks the session's impersonation as ended and removes it from the session
+func endImpersonation(session *sessions.Session, now time.Time) {
+	idStr, _ := session.Values[impersonationKey].(string)
+	if idStr == "" {
+		return
+	}
+	delete(session.Values, impersonationKey)
+	if config.DB == nil {
+		return
+	}
+	config.DB.Model(&models.Impersonation{}).
+		Where("id = ? AND ended_at IS NULL", idStr).
+		Update("ended_at", now)
+}
+
+// RecordImpersonationRequest adds a request made during an impersonation to its audit trail
+func RecordImpersonationRequest(imp models.Impersonation, r *http.Request, status int) {
+	path := r.URL.RequestURI()
+	if len(path) > 2048 {
+		path = path[:2048]
+	}
+	entry := models.ImpersonationRequest{ImpersonationID: imp.ID, Method: r.Method, Path: path, Status: status}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		zap.L().Error("Failed to record impersonated request", zap.Uint("impersonation_id", imp.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateImpersonation(t *testing.T) {
+	admin := models.User{Role: RoleAdmin}
+	admin.ID = 1
+	parent := models.User{Role: RoleParent}
+	parent.ID = 2
+	teacher := models.User{Role: RoleTeacher}
+	teacher.ID = 3
+
+	assert.NoError(t, ValidateImpersonation(admin, parent, "Ticket #42: reservation missing"))
+	assert.Error(t, ValidateImpersonation(admin, parent, ""), "reason required")
+	assert.Error(t, ValidateImpersonation(admin, parent, strings.Repeat("x", 501)))
+	assert.Error(t, ValidateImpersonation(admin, admin, "self"))
+
+	err := ValidateImpersonation(admin, teacher, "staff")
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrForbidden, err.(ValidationError).Code)
+	}
+}
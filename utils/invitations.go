@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"gorm.io/gorm"
+)
+
+// Registration modes (the registration_mode setting)
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationApproval   = "approval_required"
+)
+
+// User account statuses
+const (
+	UserStatusActive  = "active"
+	UserStatusPending = "pending"
+)
+
+// Invitation error codes
+const (
+	ErrInvitationRequired = "INVITATION_REQUIRED"
+	ErrInvalidInvitation  = "INVALID_INVITATION"
+	ErrAccountPending     = "ACCOUNT_PENDING"
+)
+
+const (
+	DefaultInvitationDays   = 14
+	MaxInvitationDays       = 90
+	invitationCodeBytes     = 24
+	invitationCodeMinLength = 16
+)
+
+// RegistrationModes lists the valid registration_mode values
+var RegistrationModes = []string{RegistrationOpen, RegistrationInviteOnly, RegistrationApproval}
+
+// RegistrationMode returns the current registration mode
+func RegistrationMode() string {
+	return SettingString(SettingRegistrationMode)
+}
+
+// NewUserStatus is the status of a new self-registered account: pending in
+// approval_required mode unless an admin invited them
+func NewUserStatus(invited bool) string {
+	if !invited && RegistrationMode() == RegistrationApproval {
+		return UserStatusPending
+	}
+	return UserStatusActive
+}
+
+// GenerateInvitationCode returns a new random invitation code
+func GenerateInvitationCode() string {
+	b := make([]byte, invitationCodeBytes)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// InvitationLink is the front-end registration page for an invitation code
+func InvitationLink(code string) string {
+	return FrontendURL("/register", url.Values{"invite": {code}})
+}
+
+// InvitationActive reports whether the invitation can still be used
+func InvitationActive(inv models.Invitation, now time.Time) bool {
+	return inv.AcceptedAt == nil && inv.RevokedAt == nil && now.Before(inv.ExpiresAt)
+}
+
+// FindInvitation looks up a usable invitation by its code
+func FindInvitation(code string, now time.Time) (models.Invitation, error) {
+	var inv models.Invitation
+	invalid := NewValidationError(ErrInvalidInvitation, "Invitation is invalid or has expired", nil)
+	if len(code) < invitationCodeMinLength {
+		return inv, invalid
+	}
+	if err := config.DB.Where("code_hash = ?", HashToken(code)).First(&inv).Error; err != nil {
+		return inv, invalid
+	}
+	if !InvitationActive(inv, now) {
+		return inv, invalid
+	}
+	return inv, nil
+}
+
+// FindInvitationForEmail returns a usable invitation for the address, if there is one
+func FindInvitationForEmail(email string, now time.Time) (models.Invitation, bool) {
+	var inv models.Invitation
+	err := config.DB.Where("LOWER(email) = LOWER(?) AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, now).
+		Order("created_at DESC").First(&inv).Error
+	return inv, err == nil
+}
+
+// AcceptInvitation marks the invitation used by userID. The conditional update makes
+// sure a code is only accepted once, even by concurrent registrations.
+func AcceptInvitation(tx *gorm.DB, inv models.Invitation, userID uint, now time.Time) error {
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", inv.ID, now).
+		Updates(map[string]interface{}{"accepted_at": now, "accepted_by_id": userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NewValidationError(ErrInvalidInvitation, "Invitation is invalid or has expired", nil)
+	}
+	return nil
+}
+
+// SendInvitation emails the registration link to the invited address
+func SendInvitation(inv models.Invitation, code string) error {
+	body := "You have been invited to create a Reservio account.\n\nRegister here: " + InvitationLink(code) +
+		"\n\nThe invitation is for " + inv.Email + " and is valid until " + inv.ExpiresAt.Format("2006-01-02") + "."
+	return SendMail(inv.Email, "Your Reservio invitation", body)
+}
+
+// InvitationMatchesEmail reports whether the email is the invited address
+func InvitationMatchesEmail(inv models.Invitation, email string) bool {
+	return strings.EqualFold(strings.TrimSpace(inv.Email), strings.TrimSpace(email))
+}
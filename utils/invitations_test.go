@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitationActive(t *testing.T) {
+	now := time.Now()
+	inv := models.Invitation{ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, InvitationActive(inv, now))
+	assert.False(t, InvitationActive(inv, now.Add(2*time.Hour)), "expired")
+
+	accepted := inv
+	accepted.AcceptedAt = &now
+	assert.False(t, InvitationActive(accepted, now))
+
+	revoked := inv
+	revoked.RevokedAt = &now
+	assert.False(t, InvitationActive(revoked, now))
+}
+
+func TestInvitationCodes(t *testing.T) {
+	a, b := GenerateInvitationCode(), GenerateInvitationCode()
+	assert.NotEqual(t, a, b)
+	assert.GreaterOrEqual(t, len(a), invitationCodeMinLength)
+	assert.Contains(t, InvitationLink(a), "/register?invite="+a)
+
+	inv := models.Invitation{Email: "Parent@Example.com"}
+	assert.True(t, InvitationMatchesEmail(inv, "parent@example.com"))
+	assert.False(t, InvitationMatchesEmail(inv, "other@example.com"))
+}
+
+func TestNewUserStatusDefaultsToActive(t *testing.T) {
+	// Without a stored setting the registration mode is open
+	assert.Equal(t, RegistrationOpen, RegistrationMode())
+	assert.Equal(t, UserStatusActive, NewUserStatus(false))
+	assert.Equal(t, UserStatusActive, NewUserStatus(true))
+}
@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Failed logins are counted per account, per source IP and per IP+account. Once a key
+// reaches its threshold, logins are locked for loginLockBase, doubling with every
+// further failure up to loginLockMax. Counters expire loginFailureWindow after the
+// last failure.
+const (
+	loginLockBase      = 5 * time.Minute
+	loginLockMax       = 24 * time.Hour
+	loginFailureWindow = 24 * time.Hour
+	// ErrLoginLocked is returned while logins are locked
+	ErrLoginLocked = "RATE_LIMIT_EXCEEDED"
+)
+
+// loginThrottleKey is one throttle counter for a login attempt
+type loginThrottleKey struct {
+	Key       string
+	Threshold int
+	// Account marks keys tied to the account, whose lockouts the owner is told about
+	Account bool
+}
+
+// loginThrottleKeys returns the counters a login attempt for email from ip counts against.
+// The IP+account key catches guessing from one place; the account key catches
+// distributed guessing; the IP key catches one source trying many accounts.
+func loginThrottleKeys(email, ip string) []loginThrottleKey {
+	email = strings.ToLower(strings.TrimSpace(email))
+	return []loginThrottleKey{
+		{Key: "ipacct:" + email + ":" + ip, Threshold: 5, Account: true},
+		{Key: "acct:" + email, Threshold: 20, Account: true},
+		{Key: "ip:" + ip, Threshold: 50},
+	}
+}
+
+// LoginLockDuration is how long logins stay locked after the given number of failures
+func LoginLockDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := loginLockBase
+	for i := threshold; i < failures && d < loginLockMax; i++ {
+		d *= 2
+	}
+	if d > loginLockMax {
+		d = loginLockMax
+	}
+	return d
+}
+
+// throttleStore keeps the counters, in Redis (shared by all instances) or Postgres
+type throttleStore interface {
+	// fail counts a failure and returns the new count
+	fail(key string, now time.Time) (int, error)
+	lock(key string, until time.Time) error
+	// lockedUntil returns the latest lock among the keys (zero if none is locked)
+	lockedUntil(keys []string, now time.Time) (time.Time, error)
+	clear(keys []string) error
+	clearPrefix(prefix string) error
+}
+
+func currentThrottleStore() throttleStore {
+	if config.Redis != nil {
+		return redisThrottleStore{}
+	}
+	return dbThrottleStore{}
+}
+
+// LoginLockedFor returns how long logins for email from ip remain locked (0 if not locked)
+func LoginLockedFor(email, ip string, now time.Time) time.Duration {
+	var keys []string
+	for _, k := range loginThrottleKeys(email, ip) {
+		keys = append(keys, k.Key)
+	}
+	until, err := currentThrottleStore().lockedUntil(keys, now)
+	if err != nil && config.Redis != nil {
+		zap.L().Warn("Login throttle lookup failed in Redis, using database", zap.Error(err))
+		until, err = dbThrottleStore{}.lockedUntil(keys, now)
+	}
+	if err != nil {
+		zap.L().Warn("Login throttle lookup failed", zap.Error(err))
+		return 0
+	}
+	if until.After(now) {
+		return until.Sub(now)
+	}
+	return 0
+}
+
+// RecordLoginFailure counts a failed login and locks the keys that reached their
+// threshold. user is the account the email belongs to (nil if there is none); its owner
+// is emailed the first time one of the account's keys locks.
+func RecordLoginFailure(user *models.User, email, ip string, now time.Time) {
+	store := currentThrottleStore()
+	for _, k := range loginThrottleKeys(email, ip) {
+		failures, err := store.fail(k.Key, now)
+		if err != nil && config.Redis != nil {
+			zap.L().Warn("Login throttle update failed in Redis, using database", zap.Error(err))
+			store = dbThrottleStore{}
+			failures, err = store.fail(k.Key, now)
+		}
+		if err != nil {
+			zap.L().Warn("Login throttle update failed", zap.String("key", k.Key), zap.Error(err))
+			continue
+		}
+		d := LoginLockDuration(failures, k.Threshold)
+		if d == 0 {
+			continue
+		}
+		if err := store.lock(k.Key, now.Add(d)); err != nil {
+			zap.L().Warn("Failed to lock login", zap.String("key", k.Key), zap.Error(err))
+		}
+		zap.L().Info("Login locked", zap.String("key", k.Key), zap.Int("failures", failures), zap.Duration("duration", d))
+		if k.Account && failures == k.Threshold && user != nil {
+			notifyLoginLockout(*user, ip, d)
+		}
+	}
+}
+
+// RecordLoginSuccess clears the account's counters after a successful login. The IP
+// counter is kept, since one valid password says nothing about other accounts.
+func RecordLoginSuccess(email, ip string) {
+	keys := loginThrottleKeys(email, ip)
+	if err := currentThrottleStore().clear([]string{keys[0].Key, keys[1].Key}); err != nil {
+		zap.L().Warn("Failed to reset login throttle", zap.Error(err))
+	}
+}
+
+// UnlockLogin clears all failure counters and locks of an account, from every IP
+func UnlockLogin(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	store := currentThrottleStore()
+	if err := store.clearPrefix("ipacct:" + email + ":"); err != nil {
+		return err
+	}
+	return store.clear([]string{"acct:" + email})
+}
+
+func notifyLoginLockout(user models.User, ip string, d time.Duration) {
+	err := NotifyUser(user, NotificationMessage{
+		Event:   NotifyAccount,
+		Subject: "Login temporarily locked",
+		Body: fmt.Sprintf("There were several failed attempts to log in to your Reservio account (last from %s), so logins are paused for %s. "+
+			"If this wasn't you, consider changing your password. An administrator can unlock the account.", ip, d),
+	})
+	if err != nil {
+		zap.L().Warn("Failed to send lockout notice", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// redisThrottleStore keeps counters under login:fail:<key> and locks under
+// login:lock:<key>, both with TTLs so stale entries disappear on their own
+type redisThrottleStore struct{}
+
+func (redisThrottleStore) fail(key string, now time.Time) (int, error) {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	_ = conn.Send("MULTI")
+	_ = conn.Send("INCR", "login:fail:"+key)
+	_ = conn.Send("PEXPIRE", "login:fail:"+key, loginFailureWindow.Milliseconds())
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	return redis.Int(values[0], nil)
+}
+
+func (redisThrottleStore) lock(key string, until time.Time) error {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	ttl := time.Until(until).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := conn.Do("SET", "login:lock:"+key, until.Unix(), "PX", ttl)
+	return err
+}
+
+func (redisThrottleStore) lockedUntil(keys []string, now time.Time) (time.Time, error) {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = "login:lock:" + k
+	}
+	values, err := redis.Values(conn.Do("MGET", args...))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, v := range values {
+		// Missing keys come back as nil
+		if unix, err := redis.Int64(v, nil); err == nil && time.Unix(unix, 0).After(latest) {
+			latest = time.Unix(unix, 0)
+		}
+	}
+	return latest, nil
+}
+
+func (redisThrottleStore) clear(keys []string) error {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	var args []interface{}
+	for _, k := range keys {
+		args = append(args, "login:fail:"+k, "login:lock:"+k)
+	}
+	_, err := conn.Do("DEL", args...)
+	return err
+}
+
+func (redisThrottleStore) clearPrefix(prefix string) error {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	for _, kind := range []string{"login:fail:", "login:lock:"} {
+		cursor := 0
+		for {
+			values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", kind+escapeRedisPattern(prefix)+"*", "COUNT", 100))
+			if err != nil {
+				return err
+			}
+			cursor, _ = redis.Int(values[0], nil)
+			keys, _ := redis.Strings(values[1], nil)
+			if len(keys) > 0 {
+				args := make([]interface{}, len(keys))
+				for i, k := range keys {
+					args[i] = k
+				}
+				if _, err := conn.Do("DEL", args...); err != nil {
+					return err
+				}
+			}
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func escapeRedisPattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// dbThrottleStore keeps counters in the login_throttles table
+type dbThrottleStore struct{}
+
+func (dbThrottleStore) fail(key string, now time.Time) (int, error) {
+	entry := models.LoginThrottle{Key: key, Failures: 1, ExpiresAt: now.Add(loginFailureWindow)}
+	// An expired counter starts over
+	err := config.DB.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":   gorm.Expr("CASE WHEN login_throttles.expires_at <= ? THEN 1 ELSE login_throttles.failures + 1 END", now),
+				"expires_at": entry.ExpiresAt,
+				"updated_at": now,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+	).Create(&entry).Error
+	return entry.Failures, err
+}
+
+func (dbThrottleStore) lock(key string, until time.Time) error {
+	// Keep the row at least as long as the lock
+	return config.DB.Model(&models.LoginThrottle{}).Where("key = ?", key).Updates(map[string]interface{}{
+		"locked_until": until,
+		"expires_at":   gorm.Expr("GREATEST(expires_at, ?)", until),
+	}).Error
+}
+
+func (dbThrottleStore) lockedUntil(keys []string, now time.Time) (time.Time, error) {
+	var entries []models.LoginThrottle
+	if err := config.DB.Where("key IN ? AND locked_until > ?", keys, now).Find(&entries).Error; err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, e := range entries {
+		if e.LockedUntil.After(latest) {
+			latest = *e.LockedUntil
+		}
+	}
+	return latest, nil
+}
+
+func (dbThrottleStore) clear(keys []string) error {
+	return config.DB.Where("key IN ?", keys).Delete(&models.LoginThrottle{}).Error
+}
+
+func (dbThrottleStore) clearPrefix(prefix string) error {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	return config.DB.Where(`key LIKE ? ESCAPE '\'`, escaped+"%").Delete(&models.LoginThrottle{}).Error
+}
+
+// PurgeLoginThrottles deletes stale counters from the database and returns how many
+func PurgeLoginThrottles(now time.Time) int64 {
+	result := config.DB.Where("expires_at <= ?", now).Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		zap.L().Warn("Failed to purge login throttles", zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLockDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), LoginLockDuration(4, 5))
+	assert.Equal(t, 5*time.Minute, LoginLockDuration(5, 5))
+	assert.Equal(t, 10*time.Minute, LoginLockDuration(6, 5))
+	assert.Equal(t, 40*time.Minute, LoginLockDuration(8, 5))
+	assert.Equal(t, loginLockMax, LoginLockDuration(100, 5))
+}
+
+func TestLoginThrottleKeys(t *testing.T) {
+	keys := loginThrottleKeys(" Parent@Example.com ", "203.0.113.7")
+	assert.Len(t, keys, 3)
+	assert.Equal(t, "ipacct:parent@example.com:203.0.113.7", keys[0].Key)
+	assert.Equal(t, "acct:parent@example.com", keys[1].Key)
+	assert.Equal(t, "ip:203.0.113.7", keys[2].Key)
+	assert.True(t, keys[0].Account)
+	assert.False(t, keys[2].Account)
+	assert.Less(t, keys[0].Threshold, keys[1].Threshold)
+}
+
+func TestEscapeRedisPattern(t *testing.T) {
+	assert.Equal(t, `ipacct:a\*b\?c\[d\]:`, escapeRedisPattern("ipacct:a*b?c[d]:"))
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// MagicLinkTTL is how long a login link works
+	MagicLinkTTL = 15 * time.Minute
+	// ErrMagicLinkDisabled is returned when admins haven't enabled magic-link login
+	ErrMagicLinkDisabled = "MAGIC_LINK_DISABLED"
+)
+
+// Magic-link requests are limited per email address and per client IP, whether or not
+// the account exists, so the limits don't reveal anything either
+var (
+	RateLimitMagicLinkEmail = RateLimitPolicy{Name: "magic-link-email", Limit: 5, Period: time.Hour, Burst: 3}
+	RateLimitMagicLinkIP    = RateLimitPolicy{Name: "magic-link-ip", Limit: 30, Period: time.Hour, Burst: 10}
+)
+
+// MagicLinkEnabled reports whether passwordless login is switched on
+func MagicLinkEnabled() bool {
+	return SettingBool(SettingMagicLinkLogin)
+}
+
+// MagicLinkAllowed reports whether a user may log in with a link. Staff accounts have to
+// use their password (and second factor), so a mailbox alone never grants staff access.
+func MagicLinkAllowed(user models.User) bool {
+	return !IsStaffRole(user.Role)
+}
+
+// AllowMagicLinkRequest counts a link request against both limits
+func AllowMagicLinkRequest(email, ip string, now time.Time) RateLimitResult {
+	return AllowAddressRequest(RateLimitMagicLinkEmail, RateLimitMagicLinkIP, email, ip, now)
+}
+
+func newMagicLinkToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// IssueMagicLink stores a new login token for the user, replacing unused ones, and
+// returns the link to send
+func IssueMagicLink(user models.User, ip string, now time.Time) (string, error) {
+	config.DB.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	token := newMagicLinkToken()
+	record := models.MagicLinkToken{
+		UserID:    user.ID,
+		TokenHash: HashToken(token),
+		IP:        ip,
+		ExpiresAt: now.Add(MagicLinkTTL),
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return FrontendURL("/magic-link", url.Values{"token": {token}}), nil
+}
+
+// SendMagicLink emails a login link to the user
+func SendMagicLink(user models.User, link string) error {
+	return NotifyUser(user, NotificationMessage{
+		Event:   NotifyAccount,
+		Subject: "Your login link",
+		Body: "Use this link to log in to Reservio: " + link +
+			"\n\nThe link works once within " + MagicLinkTTL.String() + ". If you didn't ask for it, ignore this email; your account stays safe.",
+	})
+}
+
+// ConsumeMagicLink redeems a login token and returns its user. Each token works once;
+// concurrent attempts with the same token can't both succeed.
+func ConsumeMagicLink(token string, now time.Time) (models.User, error) {
+	var user models.User
+	invalid := NewValidationError(ErrInvalidToken, "Invalid or expired login link", nil)
+	if token == "" {
+		return user, invalid
+	}
+	var record models.MagicLinkToken
+	if err := config.DB.Where("token_hash = ?", HashToken(token)).First(&record).Error; err != nil {
+		return user, invalid
+	}
+	result := config.DB.Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", record.ID, now).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return user, invalid
+	}
+	if err := config.DB.First(&user, record.UserID).Error; err != nil || !MagicLinkAllowed(user) {
+		return user, invalid
+	}
+	return user, nil
+}
+
+// PurgeMagicLinks deletes login tokens that expired over a day ago and returns how many
+func PurgeMagicLinks(now time.Time) int64 {
+	result := config.DB.Where("expires_at < ?", now.Add(-24*time.Hour)).Delete(&models.MagicLinkToken{})
+	if result.Error != nil {
+		zap.L().Warn("Failed to purge magic links", zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLinkAllowed(t *testing.T) {
+	assert.True(t, MagicLinkAllowed(models.User{Role: RoleParent}))
+	assert.False(t, MagicLinkAllowed(models.User{Role: RoleAdmin}))
+	assert.False(t, MagicLinkAllowed(models.User{Role: RoleTeacher}))
+}
+
+func TestMagicLinkRequestLimit(t *testing.T) {
+	now := time.Now()
+	for i := 0; i < RateLimitMagicLinkEmail.Burst; i++ {
+		assert.True(t, AllowMagicLinkRequest("Limit@Example.com", "198.51.100.7", now).Allowed)
+	}
+	result := AllowMagicLinkRequest("limit@example.com", "198.51.100.7", now)
+	assert.False(t, result.Allowed, "the limit is per address, case-insensitively")
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	assert.True(t, AllowMagicLinkRequest("other@example.com", "198.51.100.7", now).Allowed)
+	assert.True(t, AllowMagicLinkRequest("limit@example.com", "198.51.100.7", now.Add(RateLimitMagicLinkEmail.Period)).Allowed)
+}
+
+func TestConsumeMagicLinkRejectsEmptyToken(t *testing.T) {
+	_, err := ConsumeMagicLink("", time.Now())
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrInvalidToken, err.(ValidationError).Code)
+	}
+}
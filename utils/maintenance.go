@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StartMaintenancePurger removes stale login throttle counters, expired magic links, old
+// security events and old activity feed entries every hour until ctx is cancelled (Redis
+// entries expire by themselves)
+func StartMaintenancePurger(ctx context.Context) {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n := PurgeLoginThrottles(time.Now()); n > 0 {
+			zap.L().Info("Purged stale login throttles", zap.Int64("count", n))
+		}
+		if n := PurgeMagicLinks(time.Now()); n > 0 {
+			zap.L().Info("Purged expired magic links", zap.Int64("count", n))
+		}
+		if n := PurgeSecurityEvents(time.Now()); n > 0 {
+			zap.L().Info("Purged old security events", zap.Int64("count", n))
+		}
+		if n := PurgeActivityEvents(time.Now()); n > 0 {
+			zap.L().Info("Purged old activity events", zap.Int64("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
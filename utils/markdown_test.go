@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	html := RenderMarkdown("# Menu\n\n**Monday:** soup\n\n- apples\n- pears")
+	assert.Contains(t, html, "<h1>Menu</h1>")
+	assert.Contains(t, html, "<strong>Monday:</strong>")
+	assert.Contains(t, html, "<li>apples</li>")
+
+	link := RenderMarkdown("[form](https://example.com/form.pdf)")
+	assert.Contains(t, link, `href="https://example.com/form.pdf"`)
+	assert.Contains(t, link, `rel="nofollow noreferrer noopener"`)
+}
+
+func TestRenderMarkdownStripsUnsafeContent(t *testing.T) {
+	cases := []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"[click](javascript:alert(1))",
+		"<a href=\"https://example.com\" onclick=\"alert(1)\">x</a>",
+		"<iframe src=\"https://evil.example\"></iframe>",
+	}
+	for _, source := range cases {
+		html := RenderMarkdown(source)
+		assert.NotContains(t, html, "<script", source)
+		assert.NotContains(t, html, "onerror", source)
+		assert.NotContains(t, html, "onclick", source)
+		assert.NotContains(t, html, "javascript:", source)
+		assert.NotContains(t, html, "<iframe", source)
+	}
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Notification event types a user can configure
+const (
+	NotifyApprovals     = "approvals"
+	NotifyReminders     = "reminders"
+	NotifyAnnouncements = "announcements"
+	NotifyMessages      = "messages"
+	// NotifyAccount covers security mail (password resets etc.); it is always
+	// delivered by email and cannot be switched off.
+	NotifyAccount = "account"
+)
+
+// Notification delivery channels
+const (
+	ChannelEmail = "email"
+	ChannelInApp = "in_app"
+	ChannelSMS   = "sms"
+)
+
+// NotificationEvents lists the user-configurable event types
+var NotificationEvents = []string{NotifyApprovals, NotifyReminders, NotifyAnnouncements, NotifyMessages}
+
+// NotificationChannels lists every delivery channel
+var NotificationChannels = []string{ChannelEmail, ChannelInApp, ChannelSMS}
+
+// NotificationMessage is a single notification handed to the dispatcher.
+// Urgent messages ignore quiet hours and are also sent by SMS.
+type NotificationMessage struct {
+	Event   string
+	Subject string
+	Body    string
+	Urgent  bool
+}
+
+// NotificationSender delivers a message to a user over one channel
+type NotificationSender func(user models.User, msg NotificationMessage) error
+
+var notificationSenders = struct {
+	sync.RWMutex
+	m map[string]NotificationSender
+}{m: map[string]NotificationSender{
+	ChannelEmail: func(user models.User, msg NotificationMessage) error {
+		return SendMail(user.Email, msg.Subject, msg.Body)
+	},
+	ChannelInApp: func(user models.User, msg NotificationMessage) error {
+		return config.DB.Create(&models.Notification{
+			UserID: user.ID,
+			Event:  msg.Event,
+			Title:  msg.Subject,
+			Body:   msg.Body,
+		}).Error
+	},
+}}
+
+// RegisterNotificationChannel installs (or replaces) the sender used for a channel
+func RegisterNotificationChannel(channel string, sender NotificationSender) {
+	notificationSenders.Lock()
+	defer notificationSenders.Unlock()
+	notificationSenders.m[channel] = sender
+}
+
+func notificationSender(channel string) NotificationSender {
+	notificationSenders.RLock()
+	defer notificationSenders.RUnlock()
+	return notificationSenders.m[channel]
+}
+
+// DefaultNotificationPreference returns the preferences used for users who never changed them:
+// email and in-app for everything, SMS off, no quiet hours.
+func DefaultNotificationPreference(userID uint) models.NotificationPreference {
+	return models.NotificationPreference{
+		UserID:             userID,
+		ApprovalsEmail:     true,
+		ApprovalsInApp:     true,
+		RemindersEmail:     true,
+		RemindersInApp:     true,
+		AnnouncementsEmail: true,
+		AnnouncementsInApp: true,
+		MessagesEmail:      true,
+		MessagesInApp:      true,
+	}
+}
+
+// GetNotificationPreference loads the user's preferences, falling back to the defaults
+// (not persisted) when the user has none yet.
+func GetNotificationPreference(userID uint) (models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := config.DB.Where("user_id = ?", userID).First(&pref).Error
+	if err == gorm.ErrRecordNotFound {
+		return DefaultNotificationPreference(userID), nil
+	}
+	return pref, err
+}
+
+// NotificationPreferenceFlag returns a pointer to the flag for an event/channel pair,
+// or nil if the combination is unknown.
+func NotificationPreferenceFlag(pref *models.NotificationPreference, event, channel string) *bool {
+	switch event + "/" + channel {
+	case NotifyApprovals + "/" + ChannelEmail:
+		return &pref.ApprovalsEmail
+	case NotifyApprovals + "/" + ChannelInApp:
+		return &pref.ApprovalsInApp
+	case NotifyApprovals + "/" + ChannelSMS:
+		return &pref.ApprovalsSMS
+	case NotifyReminders + "/" + ChannelEmail:
+		return &pref.RemindersEmail
+	case NotifyReminders + "/" + ChannelInApp:
+		return &pref.RemindersInApp
+	case NotifyReminders + "/" + ChannelSMS:
+		return &pref.RemindersSMS
+	case NotifyAnnouncements + "/" + ChannelEmail:
+		return &pref.AnnouncementsEmail
+	case NotifyAnnouncements + "/" + ChannelInApp:
+		return &pref.AnnouncementsInApp
+	case NotifyAnnouncements + "/" + ChannelSMS:
+		return &pref.AnnouncementsSMS
+	case NotifyMessages + "/" + ChannelEmail:
+		return &pref.MessagesEmail
+	case NotifyMessages + "/" + ChannelInApp:
+		return &pref.MessagesInApp
+	case NotifyMessages + "/" + ChannelSMS:
+		return &pref.MessagesSMS
+	}
+	return nil
+}
+
+// NotificationChannelEnabled reports whether the user wants this event on this channel
+func NotificationChannelEnabled(pref models.NotificationPreference, event, channel string) bool {
+	if event == NotifyAccount {
+		return channel == ChannelEmail
+	}
+	flag := NotificationPreferenceFlag(&pref, event, channel)
+	return flag != nil && *flag
+}
+
+var clockRegex = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// ValidateQuietHours checks the quiet-hours window; start and end must both be set or both empty
+func ValidateQuietHours(start, end, timezone string) error {
+	if (start == "") != (end == "") {
+		return NewValidationError(ErrInvalidInput, "Quiet hours need both start and end", map[string]interface{}{
+			"field": "quiet_hours",
+		})
+	}
+	for field, value := range map[string]string{"start": start, "end": end} {
+		if value != "" && !clockRegex.MatchString(value) {
+			return NewValidationError(ErrInvalidInput, "Invalid quiet hours time. Use HH:MM", map[string]interface{}{
+				"field":  "quiet_hours." + field,
+				"value":  value,
+				"format": "HH:MM",
+			})
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return NewValidationError(ErrInvalidInput, "Unknown timezone", map[string]interface{}{
+				"field": "quiet_hours.timezone",
+				"value": timezone,
+			})
+		}
+	}
+	return nil
+}
+
+// InQuietHours reports whether now falls inside the user's quiet-hours window.
+// Windows may wrap around midnight (e.g. 22:00-07:00).
+func InQuietHours(pref models.NotificationPreference, now time.Time) bool {
+	if pref.QuietHoursStart == "" || pref.QuietHoursEnd == "" || pref.QuietHoursStart == pref.QuietHoursEnd {
+		return false
+	}
+	if pref.Timezone != "" {
+		if loc, err := time.LoadLocation(pref.Timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	start, err1 := time.Parse("15:04", pref.QuietHoursStart)
+	end, err2 := time.Parse("15:04", pref.QuietHoursEnd)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+	if startMin < endMin {
+		return minute >= startMin && minute < endMin
+	}
+	return minute >= startMin || minute < endMin
+}
+
+// QuietHoursEnd returns the first end of the user's quiet-hours window after now,
+// in the user's timezone
+func QuietHoursEnd(pref models.NotificationPreference, now time.Time) time.Time {
+	loc := time.UTC
+	if pref.Timezone != "" {
+		if l, err := time.LoadLocation(pref.Timezone); err == nil {
+			loc = l
+		}
+	}
+	end, err := time.Parse("15:04", pref.QuietHoursEnd)
+	if err != nil {
+		return now
+	}
+	local := now.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+// Notify sends a notification to a user through every channel their preferences allow.
+// It is the single entry point for all notifications the system sends.
+func Notify(userID uint, msg NotificationMessage) error {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+	return NotifyUser(user, msg)
+}
+
+// NotifyUser is Notify for callers that already loaded the user.
+// Email and SMS are held back during quiet hours unless the message is urgent and go
+// out when quiet hours end; in-app delivery is never delayed. A channel error is only
+// returned when no channel delivered (or held back) the message, so callers can retry
+// without sending duplicates on the channels that worked.
+func NotifyUser(user models.User, msg NotificationMessage) error {
+	pref, err := GetNotificationPreference(user.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	quiet := msg.Event != NotifyAccount && !msg.Urgent && InQuietHours(pref, now)
+
+	var lastErr error
+	delivered := false
+	for _, channel := range NotificationChannels {
+		// Urgent notices (e.g. closures) also go out by SMS to everyone who opted in
+		enabled := NotificationChannelEnabled(pref, msg.Event, channel) || (msg.Urgent && channel == ChannelSMS)
+		if !enabled {
+			continue
+		}
+		if quiet && channel != ChannelInApp {
+			if err := deferNotification(user.ID, channel, msg, QuietHoursEnd(pref, now)); err != nil {
+				zap.L().Warn("Failed to defer notification", zap.Uint("user_id", user.ID), zap.String("channel", channel), zap.Error(err))
+				lastErr = err
+			} else {
+				delivered = true
+			}
+			continue
+		}
+		sender := notificationSender(channel)
+		if sender == nil {
+			zap.L().Debug("No sender for notification channel", zap.String("channel", channel))
+			continue
+		}
+		if err := sender(user, msg); err != nil {
+			zap.L().Warn("Notification delivery failed", zap.Uint("user_id", user.ID), zap.String("channel", channel), zap.Error(err))
+			lastErr = err
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		return nil
+	}
+	return lastErr
+}
+
+// CountUnreadNotifications returns how many in-app notifications the user has not read yet
+func CountUnreadNotifications(userID uint) int64 {
+	var count int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count)
+	return count
+}
+
+// NotifyAllUsers sends the same notification to every user. Delivery errors are
+// logged per user and do not stop the broadcast.
+func NotifyAllUsers(msg NotificationMessage) {
+	var users []models.User
+	if err := config.DB.Find(&users).Error; err != nil {
+		zap.L().Error("Failed to load users for broadcast", zap.Error(err))
+		return
+	}
+	for _, user := range users {
+		_ = NotifyUser(user, msg)
+	}
+}
+
+// NotifyReservationStatus tells the parent that a reservation was approved or rejected
+func NotifyReservationStatus(reservation models.Reservation) error {
+	var child models.Child
+	if err := config.DB.First(&child, reservation.ChildID).Error; err != nil {
+		return err
+	}
+	var slot models.Slot
+	if err := config.DB.First(&slot, reservation.SlotID).Error; err != nil {
+		return err
+	}
+	return Notify(child.ParentID, NotificationMessage{
+		Event:   NotifyApprovals,
+		Subject: fmt.Sprintf("Reservation %s", reservation.Status),
+		Body:    fmt.Sprintf("The reservation for %s on %s has been %s.", child.Name, slot.Date, reservation.Status),
+	})
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInQuietHours(t *testing.T) {
+	pref := models.NotificationPreference{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "UTC"}
+	at := func(hour, minute int) time.Time { return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC) }
+
+	assert.True(t, InQuietHours(pref, at(23, 30)))
+	assert.True(t, InQuietHours(pref, at(6, 59)))
+	assert.False(t, InQuietHours(pref, at(7, 0)))
+	assert.False(t, InQuietHours(pref, at(12, 0)))
+
+	pref.QuietHoursStart, pref.QuietHoursEnd = "12:00", "14:00"
+	assert.True(t, InQuietHours(pref, at(13, 0)))
+	assert.False(t, InQuietHours(pref, at(14, 0)))
+
+	assert.False(t, InQuietHours(models.NotificationPreference{}, at(3, 0)))
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	pref := models.NotificationPreference{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "UTC"}
+
+	assert.Equal(t, time.Date(2025, 1, 2, 7, 0, 0, 0, time.UTC), QuietHoursEnd(pref, time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC), QuietHoursEnd(pref, time.Date(2025, 1, 1, 6, 59, 0, 0, time.UTC)))
+
+	// The end is a wall-clock time in the user's timezone
+	pref.Timezone = "Europe/Prague"
+	assert.True(t, QuietHoursEnd(pref, time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)).Equal(time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)))
+}
+
+func TestValidateQuietHours(t *testing.T) {
+	assert.NoError(t, ValidateQuietHours("", "", ""))
+	assert.NoError(t, ValidateQuietHours("22:00", "07:00", "Europe/Prague"))
+	assert.Error(t, ValidateQuietHours("22:00", "", ""))
+	assert.Error(t, ValidateQuietHours("25:00", "07:00", ""))
+	assert.Error(t, ValidateQuietHours("22:00", "07:00", "Mars/Olympus"))
+}
+
+func TestNotificationChannelEnabled(t *testing.T) {
+	pref := DefaultNotificationPreference(1)
+	assert.True(t, NotificationChannelEnabled(pref, NotifyApprovals, ChannelEmail))
+	assert.False(t, NotificationChannelEnabled(pref, NotifyApprovals, ChannelSMS))
+	assert.False(t, NotificationChannelEnabled(pref, "unknown", ChannelEmail))
+
+	pref.ApprovalsEmail = false
+	assert.False(t, NotificationChannelEnabled(pref, NotifyApprovals, ChannelEmail))
+
+	// Account mail can't be switched off and only goes by email
+	assert.True(t, NotificationChannelEnabled(pref, NotifyAccount, ChannelEmail))
+	assert.False(t, NotificationChannelEnabled(pref, NotifyAccount, ChannelInApp))
+}
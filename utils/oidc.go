@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OIDC error codes, passed to the front-end as ?sso_error= after a failed login
+const (
+	ErrSSONoAccount        = "SSO_NO_ACCOUNT"
+	ErrSSOEmailNotVerified = "SSO_EMAIL_NOT_VERIFIED"
+	ErrSSOFailed           = "SSO_FAILED"
+)
+
+// OIDCConfig is the single sign-on configuration, read from the environment:
+//
+//	OIDC_ISSUER / OIDC_CLIENT_ID / OIDC_CLIENT_SECRET — provider and client registration
+//	OIDC_REDIRECT_URL   — callback URL registered with the provider (…/api/auth/oidc/callback)
+//	OIDC_SCOPES         — requested scopes (default "openid email profile")
+//	OIDC_GROUPS_CLAIM   — ID token claim listing the user's groups (default "groups")
+//	OIDC_ADMIN_GROUPS   — groups mapped to the admin role; when set, the role follows the groups on every login
+//	OIDC_AUTO_PROVISION — "true" creates accounts for unknown users on first login
+//	OIDC_POST_LOGIN_URL — where the browser is sent after login (default "/")
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	GroupsClaim   string
+	AdminGroups   []string
+	AutoProvision bool
+	PostLoginURL  string
+}
+
+// LoadOIDCConfig returns the configuration and whether SSO is enabled
+func LoadOIDCConfig() (OIDCConfig, bool) {
+	cfg := OIDCConfig{
+		Issuer:        strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        splitList(getenvDefault("OIDC_SCOPES", "openid email profile"), " "),
+		GroupsClaim:   getenvDefault("OIDC_GROUPS_CLAIM", "groups"),
+		AdminGroups:   splitList(os.Getenv("OIDC_ADMIN_GROUPS"), ","),
+		AutoProvision: os.Getenv("OIDC_AUTO_PROVISION") == "true",
+		PostLoginURL:  getenvDefault("OIDC_POST_LOGIN_URL", "/"),
+	}
+	return cfg, cfg.Issuer != "" && cfg.ClientID != "" && cfg.RedirectURL != ""
+}
+
+func splitList(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// OIDCClient holds the discovered provider, the ID token verifier and the OAuth2 client
+type OIDCClient struct {
+	Config   OIDCConfig
+	OAuth2   oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+}
+
+var oidcClient struct {
+	sync.Mutex
+	client *OIDCClient
+}
+
+// GetOIDCClient runs discovery on first use (or after the configuration changed) and
+// caches the result; a failed discovery is retried on the next login
+func GetOIDCClient(ctx context.Context) (*OIDCClient, error) {
+	cfg, ok := LoadOIDCConfig()
+	if !ok {
+		return nil, errors.New("single sign-on is not configured")
+	}
+	oidcClient.Lock()
+	defer oidcClient.Unlock()
+	if c := oidcClient.client; c != nil && c.Config.Issuer == cfg.Issuer && c.Config.ClientID == cfg.ClientID {
+		c.Config = cfg
+		return c, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	client := &OIDCClient{
+		Config: cfg,
+		OAuth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		Verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	oidcClient.client = client
+	return client, nil
+}
+
+// OIDCClaims are the ID token claims used to find or create the account
+type OIDCClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Groups        []string
+}
+
+// ParseOIDCClaims reads the standard claims and the configured groups claim, which
+// providers send either as a list or as a single string
+func ParseOIDCClaims(token *oidc.IDToken, groupsClaim string) (OIDCClaims, error) {
+	var raw map[string]interface{}
+	if err := token.Claims(&raw); err != nil {
+		return OIDCClaims{}, err
+	}
+	claims := OIDCClaims{Issuer: token.Issuer, Subject: token.Subject}
+	claims.Email, _ = raw["email"].(string)
+	claims.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+	switch v := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string:
+		claims.EmailVerified = v == "true"
+	}
+	claims.GivenName, _ = raw["given_name"].(string)
+	claims.FamilyName, _ = raw["family_name"].(string)
+	switch groups := raw[groupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				claims.Groups = append(claims.Groups, s)
+			}
+		}
+	case string:
+		claims.Groups = splitList(groups, ",")
+	}
+	return claims, nil
+}
+
+// OIDCRole maps groups to a role. It returns "" when no admin groups are configured,
+// meaning the account's role is left alone.
+func OIDCRole(groups, adminGroups []string) string {
+	if len(adminGroups) == 0 {
+		return ""
+	}
+	for _, g := range groups {
+		for _, admin := range adminGroups {
+			if g == admin {
+				return "admin"
+			}
+		}
+	}
+	return "parent"
+}
+
+// ResolveOIDCUser finds the account for an SSO identity: first by the linked subject,
+// then by verified email (linking the account), and finally, with auto-provisioning,
+// by creating a new password-less account. created reports a new account.
+func ResolveOIDCUser(cfg OIDCConfig, claims OIDCClaims) (user models.User, created bool, err error) {
+	role := OIDCRole(claims.Groups, cfg.AdminGroups)
+	// The admin groups only decide admin access: non-members lose the admin role, but other
+	// staff roles assigned in the app are kept
+	syncRole := func(user *models.User) {
+		if role == RoleParent && user.Role != RoleAdmin {
+			return
+		}
+		if role != "" && user.Role != role {
+			user.Role = role
+			config.DB.Model(user).Update("role", role)
+		}
+	}
+
+	if err := config.DB.Where("oidc_issuer = ? AND oidc_subject = ?", claims.Issuer, claims.Subject).First(&user).Error; err == nil {
+		syncRole(&user)
+		return user, false, nil
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return user, false, NewValidationError(ErrSSOEmailNotVerified, "The identity provider did not confirm the email address", nil)
+	}
+
+	issuer, subject := claims.Issuer, claims.Subject
+	if err := config.DB.Where("LOWER(email) = ?", claims.Email).First(&user).Error; err == nil {
+		if user.OIDCSubject != nil {
+			// Already linked to a different identity
+			return user, false, NewValidationError(ErrSSONoAccount, "This account is linked to another sign-on identity", nil)
+		}
+		// The provider vouched for the address
+		updates := map[string]interface{}{"oidc_issuer": issuer, "oidc_subject": subject}
+		if !user.EmailVerified {
+			updates["email_verified"] = true
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			return user, false, err
+		}
+		syncRole(&user)
+		return user, false, nil
+	}
+
+	if !cfg.AutoProvision {
+		return user, false, NewValidationError(ErrSSONoAccount, "No account exists for this identity", nil)
+	}
+	// Provisioning follows the registration mode: invite_only needs an invitation for the
+	// address, and approval_required leaves uninvited accounts pending
+	now := time.Now()
+	invitation, invited := FindInvitationForEmail(claims.Email, now)
+	if !invited && RegistrationMode() == RegistrationInviteOnly {
+		return user, false, NewValidationError(ErrSSONoAccount, "Registration is by invitation only", nil)
+	}
+	if role == "" {
+		role = "parent"
+		if invited {
+			role = invitation.Role
+		}
+	}
+	user = models.User{
+		Email:           claims.Email,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		Role:            role,
+		Status:          NewUserStatus(invited),
+		FirstName:       claims.GivenName,
+		LastName:        claims.FamilyName,
+		OIDCIssuer:      &issuer,
+		OIDCSubject:     &subject,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if invited {
+			return AcceptInvitation(tx, invitation, user.ID, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return user, false, err
+	}
+	return user, true, nil
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCRole(t *testing.T) {
+	assert.Equal(t, "", OIDCRole([]string{"staff"}, nil))
+	assert.Equal(t, "admin", OIDCRole([]string{"teachers", "staff"}, []string{"staff"}))
+	assert.Equal(t, "parent", OIDCRole([]string{"families"}, []string{"staff"}))
+}
+
+func TestLoadOIDCConfig(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "")
+	_, enabled := LoadOIDCConfig()
+	assert.False(t, enabled)
+
+	t.Setenv("OIDC_ISSUER", "https://id.example.com/")
+	t.Setenv("OIDC_CLIENT_ID", "reservio")
+	t.Setenv("OIDC_REDIRECT_URL", "https://reservio.example/api/auth/oidc/callback")
+	t.Setenv("OIDC_ADMIN_GROUPS", "staff, it ")
+	cfg, enabled := LoadOIDCConfig()
+	assert.True(t, enabled)
+	assert.Equal(t, "https://id.example.com", cfg.Issuer)
+	assert.Equal(t, []string{"openid", "email", "profile"}, cfg.Scopes)
+	assert.Equal(t, []string{"staff", "it"}, cfg.AdminGroups)
+	assert.Equal(t, "groups", cfg.GroupsClaim)
+	assert.False(t, cfg.AutoProvision)
+}
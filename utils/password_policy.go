@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// ErrWeakPassword is returned when a password lacks the required character classes
+	ErrWeakPassword = "WEAK_PASSWORD"
+	// ErrBreachedPassword is returned for passwords found in the breached-password list
+	ErrBreachedPassword = "BREACHED_PASSWORD"
+	// ErrPasswordReused is returned when a password matches one of the user's recent passwords
+	ErrPasswordReused = "PASSWORD_REUSED"
+
+	// defaultPasswordHashCost is the bcrypt cost used unless BCRYPT_COST is set
+	defaultPasswordHashCost = 14
+	// maxPasswordBytes is the most bcrypt looks at; longer passwords would be truncated
+	maxPasswordBytes = 72
+)
+
+// PasswordPolicy is what new passwords have to satisfy
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters
+	MinLength int `json:"min_length"`
+	// MinClasses is how many of lower case, upper case, digits and symbols must appear
+	MinClasses int `json:"min_classes"`
+	// History is how many recent passwords (including the current one) can't be reused; 0 allows reuse
+	History int `json:"history"`
+	// BreachedDir holds range files of breached password hashes; the built-in list is used when empty
+	BreachedDir string `json:"-"`
+}
+
+// envIntRange reads an integer setting, falling back to def when unset or out of range
+func envIntRange(key string, def, min, max int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= min && n <= max {
+		return n
+	}
+	return def
+}
+
+// CurrentPasswordPolicy reads the policy from PASSWORD_MIN_LENGTH (default 8),
+// PASSWORD_MIN_CLASSES (1-4, default 1), PASSWORD_HISTORY (default 5) and
+// BREACHED_PASSWORDS_DIR
+func CurrentPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:   envIntRange("PASSWORD_MIN_LENGTH", 8, 1, maxPasswordBytes),
+		MinClasses:  envIntRange("PASSWORD_MIN_CLASSES", 1, 1, 4),
+		History:     envIntRange("PASSWORD_HISTORY", 5, 0, 100),
+		BreachedDir: os.Getenv("BREACHED_PASSWORDS_DIR"),
+	}
+}
+
+// passwordClasses counts the character classes (lower, upper, digit, other) in a password
+func passwordClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			n++
+		}
+	}
+	return n
+}
+
+// Check validates a new password against the policy
+func (p PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return NewValidationError(ErrInvalidInput, fmt.Sprintf("Password must be at least %d characters", p.MinLength), map[string]interface{}{
+			"field":      "password",
+			"min_length": p.MinLength,
+		})
+	}
+	if len(password) > maxPasswordBytes {
+		return NewValidationError(ErrInvalidInput, fmt.Sprintf("Password must be at most %d bytes", maxPasswordBytes), map[string]interface{}{
+			"field":     "password",
+			"max_bytes": maxPasswordBytes,
+		})
+	}
+	if passwordClasses(password) < p.MinClasses {
+		return NewValidationError(ErrWeakPassword, fmt.Sprintf("Password must mix at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses), map[string]interface{}{
+			"field":       "password",
+			"min_classes": p.MinClasses,
+		})
+	}
+	if p.Breached(password) {
+		return NewValidationError(ErrBreachedPassword, "This password has appeared in a data breach. Please choose a different one", map[string]interface{}{
+			"field": "password",
+		})
+	}
+	return nil
+}
+
+//go:embed breached_passwords.txt
+var builtInBreachedPasswords string
+
+var (
+	builtInBreachedOnce   sync.Once
+	builtInBreachedByPref map[string][]string
+)
+
+// builtInBreachedRange returns the hash suffixes of the built-in list for a prefix
+func builtInBreachedRange(prefix string) []string {
+	builtInBreachedOnce.Do(func() {
+		builtInBreachedByPref = map[string][]string{}
+		for _, line := range strings.Split(builtInBreachedPasswords, "\n") {
+			hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+			if len(hash) != 40 || strings.HasPrefix(hash, "#") {
+				continue
+			}
+			hash = strings.ToUpper(hash)
+			builtInBreachedByPref[hash[:5]] = append(builtInBreachedByPref[hash[:5]], hash[5:])
+		}
+	})
+	return builtInBreachedByPref[prefix]
+}
+
+// rangeContains reports whether a range file ("SUFFIX:COUNT" per line, as served by
+// k-anonymity breach APIs) lists the suffix
+func rangeContains(r io.Reader, suffix string) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(hash, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Breached reports whether the password is in the breached-password list. Only the first
+// five hex digits of its SHA-1 hash pick the range file to search, so BREACHED_PASSWORDS_DIR
+// can hold a downloaded copy of a k-anonymity range set (<PREFIX> or <PREFIX>.txt per range)
+// and nothing is sent over the network.
+func (p PasswordPolicy) Breached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	if p.BreachedDir == "" {
+		for _, s := range builtInBreachedRange(prefix) {
+			if s == suffix {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, name := range []string{prefix + ".txt", prefix} {
+		f, err := os.Open(filepath.Join(p.BreachedDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			zap.L().Warn("Failed to read breached password range", zap.String("prefix", prefix), zap.Error(err))
+			return false
+		}
+		found, err := rangeContains(f, suffix)
+		f.Close()
+		if err != nil {
+			zap.L().Warn("Failed to read breached password range", zap.String("prefix", prefix), zap.Error(err))
+		}
+		return found
+	}
+	return false
+}
+
+// PasswordHashCost is the bcrypt cost for new hashes (BCRYPT_COST, default 14)
+func PasswordHashCost() int {
+	return envIntRange("BCRYPT_COST", defaultPasswordHashCost, bcrypt.MinCost, bcrypt.MaxCost)
+}
+
+// HashPassword hashes a password with the configured cost
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost())
+	return string(hash), err
+}
+
+// PasswordNeedsRehash reports whether a stored hash was made with a different cost than
+// the configured one
+func PasswordNeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost != PasswordHashCost()
+}
+
+// RehashPassword stores a new hash of the user's (just verified) password with the
+// configured cost. Sessions stay valid since the password itself didn't change.
+func RehashPassword(user *models.User, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := config.DB.Model(user).UpdateColumn("password", hash).Error; err != nil {
+		return err
+	}
+	user.Password = hash
+	return nil
+}
+
+// passwordReused reports whether the password matches the user's current password or one
+// of the recent ones kept in the history
+func passwordReused(db *gorm.DB, user models.User, password string, history int) (bool, error) {
+	if history <= 0 {
+		return false, nil
+	}
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil {
+		return true, nil
+	}
+	if history == 1 {
+		return false, nil
+	}
+	var previous []models.PasswordHistory
+	if err := db.Where("user_id = ?", user.ID).Order("created_at DESC, id DESC").Limit(history - 1).Find(&previous).Error; err != nil {
+		return false, err
+	}
+	for _, entry := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ChangePassword sets a new password on the user, refusing recently used ones, and bumps
+// the session version. The replaced hash is written to the history through tx; the caller
+// saves the user in the same transaction, so a failed save doesn't leave the history behind.
+func ChangePassword(tx *gorm.DB, user *models.User, password string) error {
+	policy := CurrentPasswordPolicy()
+	reused, err := passwordReused(tx, *user, password, policy.History)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return NewValidationError(ErrPasswordReused, fmt.Sprintf("Password must differ from your last %d passwords", policy.History), map[string]interface{}{
+			"field":   "password",
+			"history": policy.History,
+		})
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if user.Password != "" && policy.History > 1 {
+		if err := tx.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+			return err
+		}
+		var keep []uint
+		if err := tx.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).
+			Order("created_at DESC, id DESC").Limit(policy.History-1).Pluck("id", &keep).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND id NOT IN ?", user.ID, keep).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+	}
+
+	user.Password = hash
+	user.SessionVersion++ // invalidate other sessions
+	return nil
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, MinClasses: 3}
+
+	err := policy.Check("Short1!")
+	if assert.Error(t, err) {
+		assert.Equal(t, "Password must be at least 10 characters", err.Error())
+	}
+	err = policy.Check("onlylowercase12")
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrWeakPassword, err.(ValidationError).Code)
+	}
+	err = policy.Check(string(make([]byte, 73)))
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrInvalidInput, err.(ValidationError).Code)
+	}
+	assert.NoError(t, policy.Check("Mixed-case 2024 phrase"))
+}
+
+func TestPasswordClasses(t *testing.T) {
+	assert.Equal(t, 1, passwordClasses("abcdefgh"))
+	assert.Equal(t, 2, passwordClasses("abcd1234"))
+	assert.Equal(t, 4, passwordClasses("Ab1!"))
+	assert.Equal(t, 2, passwordClasses("žluťoučký kůň"))
+}
+
+func TestBreachedPasswordBuiltInList(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 1}
+	assert.True(t, policy.Breached("password123"))
+	assert.True(t, policy.Breached("qwerty123"))
+	assert.False(t, policy.Breached("testpassword123"))
+
+	err := policy.Check("password123")
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrBreachedPassword, err.(ValidationError).Code)
+	}
+}
+
+func TestBreachedPasswordRangeDir(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1 of "correct horse battery staple"
+	hash := "ABF7AAD6438836DBE526AA231ABDE2D0EEF74D42"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+hash[5:]+":42\r\n"), 0o600))
+
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 1, BreachedDir: dir}
+	assert.True(t, policy.Breached("correct horse battery staple"))
+	assert.False(t, policy.Breached("password123"), "the directory replaces the built-in list")
+}
+
+func TestPasswordHashCost(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "")
+	assert.Equal(t, 14, PasswordHashCost())
+	t.Setenv("BCRYPT_COST", "12")
+	assert.Equal(t, 12, PasswordHashCost())
+	t.Setenv("BCRYPT_COST", "99")
+	assert.Equal(t, 14, PasswordHashCost(), "out-of-range values are ignored")
+
+	t.Setenv("BCRYPT_COST", "5")
+	hash, err := HashPassword("testpassword123")
+	assert.NoError(t, err)
+	assert.False(t, PasswordNeedsRehash(hash))
+	old, _ := bcrypt.GenerateFromPassword([]byte("testpassword123"), bcrypt.MinCost)
+	assert.True(t, PasswordNeedsRehash(string(old)))
+}
+
+func TestCurrentPasswordPolicy(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_MIN_CLASSES", "3")
+	t.Setenv("PASSWORD_HISTORY", "0")
+	policy := CurrentPasswordPolicy()
+	assert.Equal(t, 12, policy.MinLength)
+	assert.Equal(t, 3, policy.MinClasses)
+	assert.Equal(t, 0, policy.History)
+	assert.False(t, IsPasswordStrong("longbutlowercase"))
+	assert.True(t, IsPasswordStrong("Long-Enough-Pass"))
+}
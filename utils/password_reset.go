@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a password reset link works
+const PasswordResetTTL = 30 * time.Minute
+
+// Reset requests are limited per email address and per client IP, whether or not the
+// account exists
+var (
+	RateLimitPasswordResetEmail = RateLimitPolicy{Name: "password-reset-email", Limit: 3, Period: time.Hour, Burst: 3}
+	RateLimitPasswordResetIP    = RateLimitPolicy{Name: "password-reset-ip", Limit: 20, Period: time.Hour, Burst: 10}
+)
+
+// AllowPasswordResetRequest counts a reset request against both limits
+func AllowPasswordResetRequest(email, ip string, now time.Time) RateLimitResult {
+	return AllowAddressRequest(RateLimitPasswordResetEmail, RateLimitPasswordResetIP, email, ip, now)
+}
+
+func newPasswordResetToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// IssuePasswordReset replaces the user's reset tokens with a new one and returns the
+// link to email. Only the token's hash is stored.
+func IssuePasswordReset(user models.User, now time.Time) (string, error) {
+	config.DB.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{})
+
+	token := newPasswordResetToken()
+	prt := models.PasswordResetToken{UserID: user.ID, TokenHash: HashToken(token), ExpiresAt: now.Add(PasswordResetTTL).Unix()}
+	if err := config.DB.Create(&prt).Error; err != nil {
+		return "", err
+	}
+	return FrontendURL("/reset-password", url.Values{"token": {token}}), nil
+}
+
+// SendPasswordReset emails the reset link
+func SendPasswordReset(user models.User, link string) error {
+	return NotifyUser(user, NotificationMessage{
+		Event:   NotifyAccount,
+		Subject: "Password Reset",
+		Body: "Reset your password: " + link +
+			"\n\nThe link works once within " + PasswordResetTTL.String() + ". If you didn't ask for it, ignore this email; your password stays unchanged.",
+	})
+}
+
+// PasswordResetUser returns the user ID a reset token was issued for without using the
+// token up, so a rejected new password doesn't cost the user their link
+func PasswordResetUser(token string, now time.Time) (uint, error) {
+	invalid := NewValidationError(ErrInvalidToken, "Invalid or expired token", nil)
+	if token == "" {
+		return 0, invalid
+	}
+	var prt models.PasswordResetToken
+	if err := config.DB.Where("token = ?", HashToken(token)).First(&prt).Error; err != nil {
+		return 0, invalid
+	}
+	if now.Unix() > prt.ExpiresAt {
+		config.DB.Unscoped().Delete(&prt)
+		return 0, NewValidationError(ErrTokenExpired, "Token has expired", nil)
+	}
+	return prt.UserID, nil
+}
+
+// ConsumePasswordReset redeems a reset token and returns the user ID it was issued for.
+// The token is deleted through tx, so concurrent attempts with the same token can't both
+// succeed and the token survives if the rest of the transaction fails.
+func ConsumePasswordReset(tx *gorm.DB, token string, now time.Time) (uint, error) {
+	userID, err := PasswordResetUser(token, now)
+	if err != nil {
+		return 0, err
+	}
+	result := tx.Unscoped().Where("token = ? AND user_id = ?", HashToken(token), userID).Delete(&models.PasswordResetToken{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return 0, NewValidationError(ErrInvalidToken, "Invalid or expired token", nil)
+	}
+	return userID, nil
+}
+
+// NotifyPasswordChanged tells the user their password was changed, so an unexpected
+// change doesn't go unnoticed
+func NotifyPasswordChanged(user models.User, now time.Time) error {
+	return NotifyUser(user, NotificationMessage{
+		Event:   NotifyAccount,
+		Subject: "Your password was changed",
+		Body: "The password of your Reservio account was changed on " + now.UTC().Format("2006-01-02 15:04 MST") +
+			" and all sessions were logged out. If this wasn't you, reset your password right away: " + FrontendURL("/forgot-password", nil),
+	})
+}
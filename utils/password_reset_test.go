@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetRequestLimit(t *testing.T) {
+	now := time.Now()
+	for i := 0; i < RateLimitPasswordResetEmail.Burst; i++ {
+		assert.True(t, AllowPasswordResetRequest("Reset@Example.com", "198.51.100.9", now).Allowed)
+	}
+	result := AllowPasswordResetRequest(" reset@example.com", "198.51.100.9", now)
+	assert.False(t, result.Allowed, "the limit is per address, case-insensitively")
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	assert.True(t, AllowPasswordResetRequest("someone-else@example.com", "198.51.100.9", now).Allowed)
+}
+
+func TestPasswordResetIPLimit(t *testing.T) {
+	now := time.Now()
+	ip := "198.51.100.10"
+	var result RateLimitResult
+	for i := 0; i <= RateLimitPasswordResetIP.Burst; i++ {
+		result = AllowPasswordResetRequest("ip-limit-"+string(rune('a'+i))+"@example.com", ip, now)
+	}
+	assert.False(t, result.Allowed, "spraying many addresses from one IP is limited too")
+}
+
+func TestConsumePasswordResetRejectsEmptyToken(t *testing.T) {
+	_, err := ConsumePasswordReset(nil, "", time.Now())
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrInvalidToken, err.(ValidationError).Code)
+	}
+}
@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"reservio/config"
+	"reservio/models"
+)
+
+// Permissions checked by the staff routes under /api/admin
+const (
+	PermUsersView           = "users.view"
+	PermUsersManage         = "users.manage"
+	PermUsersImpersonate    = "users.impersonate"
+	PermSlotsManage         = "slots.manage"
+	PermReservationsView    = "reservations.view"
+	PermReservationsApprove = "reservations.approve"
+	PermRosterView          = "roster.view"
+	PermCheckIn             = "checkin.manage"
+	PermAnnouncementsView   = "announcements.view"
+	PermAnnouncementsManage = "announcements.manage"
+	PermMessagesSend        = "messages.send"
+	PermWebhooksView        = "webhooks.view"
+	PermWebhooksManage      = "webhooks.manage"
+	PermSettingsView        = "settings.view"
+	PermSettingsManage      = "settings.manage"
+	PermActivityView        = "activity.view"
+)
+
+// Permissions lists every permission, in display order
+var Permissions = []string{
+	PermUsersView, PermUsersManage, PermUsersImpersonate,
+	PermSlotsManage,
+	PermReservationsView, PermReservationsApprove,
+	PermRosterView, PermCheckIn,
+	PermAnnouncementsView, PermAnnouncementsManage,
+	PermMessagesSend,
+	PermWebhooksView, PermWebhooksManage,
+	PermSettingsView, PermSettingsManage,
+	PermActivityView,
+}
+
+// Built-in roles
+const (
+	RoleParent  = "parent"
+	RoleAdmin   = "admin"
+	RoleOffice  = "office"
+	RoleTeacher = "teacher"
+	RoleAuditor = "auditor"
+)
+
+type builtInRole struct {
+	Description string
+	Permissions []string
+}
+
+var builtInRoles = map[string]builtInRole{
+	RoleParent: {Description: "Parent account without staff access"},
+	RoleAdmin:  {Description: "Full access", Permissions: Permissions},
+	RoleOffice: {
+		Description: "Approves reservations, posts announcements and messages parents",
+		Permissions: []string{PermReservationsView, PermReservationsApprove, PermRosterView, PermAnnouncementsView, PermAnnouncementsManage, PermMessagesSend},
+	},
+	RoleTeacher: {
+		Description: "Sees rosters and checks children in and out",
+		Permissions: []string{PermReservationsView, PermRosterView, PermCheckIn},
+	},
+	RoleAuditor: {
+		Description: "Read-only access to staff pages",
+		Permissions: []string{PermUsersView, PermReservationsView, PermRosterView, PermAnnouncementsView, PermWebhooksView, PermSettingsView, PermActivityView},
+	},
+}
+
+// builtInRoleOrder is the order built-in roles are listed in
+var builtInRoleOrder = []string{RoleParent, RoleAdmin, RoleOffice, RoleTeacher, RoleAuditor}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,19}$`)
+
+// RoleInfo describes a built-in or custom role
+type RoleInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in"`
+}
+
+// IsBuiltInRole reports whether a role is one of the built-in roles
+func IsBuiltInRole(role string) bool {
+	_, ok := builtInRoles[role]
+	return ok
+}
+
+// splitPermissions parses the stored comma-separated permission list
+func splitPermissions(s string) []string {
+	perms := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// findCustomRole loads a custom role; false when it doesn't exist
+func findCustomRole(name string) (models.Role, bool) {
+	var role models.Role
+	if config.DB == nil || name == "" {
+		return role, false
+	}
+	if err := config.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		return role, false
+	}
+	return role, true
+}
+
+// RolePermissions returns the permissions a role grants (none for unknown roles)
+func RolePermissions(role string) []string {
+	if builtIn, ok := builtInRoles[role]; ok {
+		return append([]string{}, builtIn.Permissions...)
+	}
+	if custom, ok := findCustomRole(role); ok {
+		return splitPermissions(custom.Permissions)
+	}
+	return []string{}
+}
+
+// HasPermission reports whether a role grants a permission
+func HasPermission(role, permission string) bool {
+	for _, p := range RolePermissions(role) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaffRole reports whether a role grants any permission, i.e. can use the staff routes
+func IsStaffRole(role string) bool {
+	return len(RolePermissions(role)) > 0
+}
+
+// RoleExists reports whether a role is built in or defined as a custom role
+func RoleExists(role string) bool {
+	if IsBuiltInRole(role) {
+		return true
+	}
+	_, ok := findCustomRole(role)
+	return ok
+}
+
+// ListRoles returns the built-in roles followed by the custom roles sorted by name
+func ListRoles() ([]RoleInfo, error) {
+	roles := make([]RoleInfo, 0, len(builtInRoleOrder))
+	for _, name := range builtInRoleOrder {
+		builtIn := builtInRoles[name]
+		perms := append([]string{}, builtIn.Permissions...)
+		roles = append(roles, RoleInfo{Name: name, Description: builtIn.Description, Permissions: perms, BuiltIn: true})
+	}
+	var custom []models.Role
+	if err := config.DB.Order("name").Find(&custom).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range custom {
+		roles = append(roles, CustomRoleInfo(role))
+	}
+	return roles, nil
+}
+
+// CustomRoleInfo describes a stored custom role
+func CustomRoleInfo(role models.Role) RoleInfo {
+	return RoleInfo{Name: role.Name, Description: role.Description, Permissions: splitPermissions(role.Permissions)}
+}
+
+// StaffRoles returns the names of every role that grants at least one permission
+func StaffRoles() []string {
+	names := []string{}
+	for _, name := range builtInRoleOrder {
+		if len(builtInRoles[name].Permissions) > 0 {
+			names = append(names, name)
+		}
+	}
+	if config.DB == nil {
+		return names
+	}
+	var custom []models.Role
+	config.DB.Order("name").Find(&custom)
+	for _, role := range custom {
+		if len(splitPermissions(role.Permissions)) > 0 {
+			names = append(names, role.Name)
+		}
+	}
+	return names
+}
+
+// ValidateRoleName checks the name of a new custom role
+func ValidateRoleName(name string) error {
+	if !roleNamePattern.MatchString(name) {
+		return NewValidationError(ErrInvalidRole, "Role name must be 2-20 lowercase letters, digits, '-' or '_', starting with a letter", map[string]interface{}{
+			"field": "name",
+			"value": name,
+		})
+	}
+	if IsBuiltInRole(name) {
+		return NewValidationError(ErrInvalidRole, "Built-in roles can't be redefined", map[string]interface{}{
+			"field": "name",
+			"value": name,
+		})
+	}
+	return nil
+}
+
+// NormalizePermissions validates a permission list and returns it deduplicated in the
+// order of Permissions, ready to store
+func NormalizePermissions(perms []string) ([]string, error) {
+	requested := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		known := false
+		for _, valid := range Permissions {
+			if p == valid {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, NewValidationError(ErrInvalidInput, "Unknown permission", map[string]interface{}{
+				"field":             "permissions",
+				"value":             p,
+				"valid_permissions": Permissions,
+			})
+		}
+		requested[p] = true
+	}
+	normalized := []string{}
+	for _, p := range Permissions {
+		if requested[p] {
+			normalized = append(normalized, p)
+		}
+	}
+	return normalized, nil
+}
+
+// customRoleNames returns the names of the custom roles, sorted
+func customRoleNames() []string {
+	names := []string{}
+	if config.DB == nil {
+		return names
+	}
+	config.DB.Model(&models.Role{}).Order("name").Pluck("name", &names)
+	return names
+}
+
+// CanGrantPermissions reports whether every permission is also granted by the actor's
+// role, so staff can't hand out more access than they have themselves
+func CanGrantPermissions(actorRole string, perms []string) bool {
+	held := make(map[string]bool)
+	for _, p := range RolePermissions(actorRole) {
+		held[p] = true
+	}
+	for _, p := range perms {
+		if !held[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// CanGrantRole reports whether the actor may assign a role (or change the role of a user
+// who has it)
+func CanGrantRole(actorRole, role string) bool {
+	return CanGrantPermissions(actorRole, RolePermissions(role))
+}
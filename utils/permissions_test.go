@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltInRolePermissions(t *testing.T) {
+	for _, p := range Permissions {
+		assert.True(t, HasPermission(RoleAdmin, p), p)
+	}
+	assert.False(t, IsStaffRole(RoleParent))
+	assert.True(t, IsStaffRole(RoleTeacher))
+
+	assert.True(t, HasPermission(RoleTeacher, PermCheckIn))
+	assert.False(t, HasPermission(RoleTeacher, PermUsersManage))
+	assert.True(t, HasPermission(RoleOffice, PermReservationsApprove))
+	assert.True(t, HasPermission(RoleOffice, PermAnnouncementsManage))
+	assert.False(t, HasPermission(RoleOffice, PermSettingsManage))
+
+	for _, p := range RolePermissions(RoleAuditor) {
+		assert.Regexp(t, `\.view$`, p, "auditors are read-only")
+	}
+}
+
+func TestCanGrantRole(t *testing.T) {
+	assert.True(t, CanGrantRole(RoleAdmin, RoleTeacher))
+	assert.True(t, CanGrantRole(RoleOffice, RoleParent))
+	assert.False(t, CanGrantRole(RoleOffice, RoleAdmin))
+	assert.False(t, CanGrantRole(RoleTeacher, RoleAuditor))
+	assert.False(t, CanGrantPermissions(RoleAuditor, []string{PermUsersView, PermUsersManage}))
+}
+
+func TestNormalizePermissions(t *testing.T) {
+	perms, err := NormalizePermissions([]string{PermCheckIn, PermUsersView, PermCheckIn})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{PermUsersView, PermCheckIn}, perms)
+
+	_, err = NormalizePermissions([]string{"users.delete"})
+	assert.Error(t, err)
+}
+
+func TestValidateRoleName(t *testing.T) {
+	assert.NoError(t, ValidateRoleName("front-desk"))
+	assert.Error(t, ValidateRoleName("Teacher2"))
+	assert.Error(t, ValidateRoleName("a"))
+	assert.Error(t, ValidateRoleName("this-role-name-is-too-long"))
+	assert.Error(t, ValidateRoleName(RoleAuditor), "built-in")
+}
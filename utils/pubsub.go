@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"reservio/config"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// publishToInstances sends a message to every app instance through a Redis channel.
+// Without Redis, or if publishing fails, local delivers it on this instance only.
+// With Redis the message reaches this instance through relayFromInstances.
+func publishToInstances(channel string, payload []byte, local func([]byte)) {
+	if config.Redis != nil {
+		conn := config.Redis.Get()
+		_, err := conn.Do("PUBLISH", channel, payload)
+		_ = conn.Close()
+		if err == nil {
+			return
+		}
+		zap.L().Warn("Failed to publish to Redis, delivering locally", zap.String("channel", channel), zap.Error(err))
+	}
+	local(payload)
+}
+
+// relayFromInstances hands every message published on channel (by any instance) to
+// handle until ctx is cancelled, reconnecting after errors. It does nothing without Redis.
+func relayFromInstances(ctx context.Context, channel string, handle func([]byte)) {
+	if config.Redis == nil {
+		return
+	}
+	for ctx.Err() == nil {
+		if err := relayOnce(ctx, channel, handle); err != nil && ctx.Err() == nil {
+			zap.L().Warn("Redis relay disconnected, retrying", zap.String("channel", channel), zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func relayOnce(ctx context.Context, channel string, handle func([]byte)) error {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(channel); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = psc.Unsubscribe()
+		case <-done:
+		}
+	}()
+
+	for {
+		switch msg := psc.Receive().(type) {
+		case redis.Message:
+			handle(msg.Data)
+		case redis.Subscription:
+			if msg.Count == 0 {
+				return nil
+			}
+		case error:
+			return msg
+		}
+	}
+}
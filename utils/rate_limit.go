@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reservio/config"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// RateLimitPolicy allows Limit requests per Period, with up to Burst at once. Requests
+// are spread using GCRA (the generic cell rate algorithm), which only needs one
+// timestamp per key.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// Named rate limit policies
+var (
+	// RateLimitAuth is strict, for login, registration and other /api/auth endpoints
+	RateLimitAuth = RateLimitPolicy{Name: "auth", Limit: 20, Period: time.Minute, Burst: 10}
+	// RateLimitRelaxed is for cheap, public reads such as GET /api/slots
+	RateLimitRelaxed = RateLimitPolicy{Name: "relaxed", Limit: 600, Period: time.Minute, Burst: 100}
+	// RateLimitDefault applies to everything else
+	RateLimitDefault = RateLimitPolicy{Name: "default", Limit: 300, Period: time.Minute, Burst: 20}
+)
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // until the next request is allowed (when denied)
+	Reset      time.Duration // until the full burst is available again
+}
+
+func (p RateLimitPolicy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// gcra applies one request at now to a key whose theoretical arrival time is tat and
+// returns the result and the new tat (unchanged when denied)
+func gcra(p RateLimitPolicy, tat, now time.Time) (RateLimitResult, time.Time) {
+	interval := p.interval()
+	burst := interval * time.Duration(p.Burst)
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-burst)
+	if now.Before(allowAt) {
+		return RateLimitResult{
+			Allowed:    false,
+			RetryAfter: allowAt.Sub(now),
+			Reset:      tat.Sub(now),
+		}, tat
+	}
+	return RateLimitResult{
+		Allowed:   true,
+		Remaining: int(now.Sub(allowAt) / interval),
+		Reset:     newTat.Sub(now),
+	}, newTat
+}
+
+// AllowRequest counts a request against key under the policy. With Redis the limit is
+// shared by all instances; otherwise (or if Redis fails) it is kept in memory.
+func AllowRequest(p RateLimitPolicy, key string, now time.Time) RateLimitResult {
+	key = "ratelimit:" + p.Name + ":" + key
+	if config.Redis != nil {
+		result, err := redisGCRA(p, key)
+		if err == nil {
+			return result
+		}
+		zap.L().Warn("Rate limiter failed in Redis, limiting in memory", zap.Error(err))
+	}
+	return localLimiter.allow(p, key, now)
+}
+
+// AllowAddressRequest counts a request concerning an email address (a login link, a
+// password reset) against a per-IP and a per-address policy; the result is the stricter
+// of the two. Both apply whether or not an account has the address, so being limited
+// reveals nothing about accounts.
+func AllowAddressRequest(addressPolicy, ipPolicy RateLimitPolicy, email, ip string, now time.Time) RateLimitResult {
+	byIP := AllowRequest(ipPolicy, ip, now)
+	if !byIP.Allowed {
+		return byIP
+	}
+	return AllowRequest(addressPolicy, strings.ToLower(strings.TrimSpace(email)), now)
+}
+
+// gcraScript runs GCRA atomically in Redis on the server clock. It returns
+// {allowed, remaining, retry_after_ms, reset_ms}.
+var gcraScript = redis.NewScript(1, `
+redis.replicate_commands()
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local new_tat = tat + interval
+local allow_at = new_tat - burst
+if now < allow_at then
+  return {0, 0, allow_at - now, tat - now}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+func redisGCRA(p RateLimitPolicy, key string) (RateLimitResult, error) {
+	conn := config.Redis.Get()
+	defer conn.Close()
+	interval := p.interval().Milliseconds()
+	if interval < 1 {
+		interval = 1
+	}
+	values, err := redis.Int64s(gcraScript.Do(conn, key, interval, interval*int64(p.Burst)))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 4 {
+		return RateLimitResult{}, errors.New("unexpected rate limit script reply")
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+// memoryLimiter keeps GCRA timestamps in process. A key whose timestamp has passed is
+// back to a full burst, so such idle keys are evicted by a sweep at most once a minute.
+type memoryLimiter struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+var localLimiter = &memoryLimiter{tats: make(map[string]time.Time)}
+
+func (m *memoryLimiter) allow(p RateLimitPolicy, key string, now time.Time) RateLimitResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) >= time.Minute {
+		m.sweep(now)
+	}
+	result, tat := gcra(p, m.tats[key], now)
+	m.tats[key] = tat
+	return result
+}
+
+func (m *memoryLimiter) sweep(now time.Time) {
+	for key, tat := range m.tats {
+		if !tat.After(now) {
+			delete(m.tats, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+// RateLimitHeaders returns the RateLimit-* (and Retry-After) response headers for a result
+func RateLimitHeaders(p RateLimitPolicy, result RateLimitResult) map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(p.Burst),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(ceilSeconds(result.Reset)),
+		"RateLimit-Policy":    strconv.Itoa(p.Limit) + ";w=" + strconv.Itoa(int(p.Period.Seconds())) + ";burst=" + strconv.Itoa(p.Burst) + `;policy="` + strings.ReplaceAll(p.Name, `"`, "") + `"`,
+	}
+	if !result.Allowed {
+		headers["Retry-After"] = strconv.Itoa(ceilSeconds(result.RetryAfter))
+	}
+	return headers
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGCRA(t *testing.T) {
+	policy := RateLimitPolicy{Name: "test", Limit: 60, Period: time.Minute, Burst: 3}
+	now := time.Unix(1700000000, 0)
+	var tat time.Time
+
+	// The burst is available at once
+	for i := 2; i >= 0; i-- {
+		var result RateLimitResult
+		result, tat = gcra(policy, tat, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, tat2 := gcra(policy, tat, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, tat, tat2, "a denied request doesn't use quota")
+
+	// One request per second refills
+	result, _ = gcra(policy, tat, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryLimiterEvictsIdleKeys(t *testing.T) {
+	policy := RateLimitPolicy{Name: "test", Limit: 60, Period: time.Minute, Burst: 5}
+	limiter := &memoryLimiter{tats: make(map[string]time.Time)}
+	now := time.Unix(1700000000, 0)
+
+	limiter.allow(policy, "a", now)
+	limiter.allow(policy, "b", now)
+	assert.Len(t, limiter.tats, 2)
+
+	// Both keys are back to a full burst long before the next sweep
+	limiter.allow(policy, "c", now.Add(2*time.Minute))
+	assert.Len(t, limiter.tats, 1)
+	assert.Contains(t, limiter.tats, "c")
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	headers := RateLimitHeaders(RateLimitAuth, RateLimitResult{Allowed: false, RetryAfter: 2500 * time.Millisecond, Reset: 30 * time.Second})
+	assert.Equal(t, "10", headers["RateLimit-Limit"])
+	assert.Equal(t, "0", headers["RateLimit-Remaining"])
+	assert.Equal(t, "30", headers["RateLimit-Reset"])
+	assert.Equal(t, "3", headers["Retry-After"])
+	assert.Equal(t, `20;w=60;burst=10;policy="auth"`, headers["RateLimit-Policy"])
+
+	headers = RateLimitHeaders(RateLimitAuth, RateLimitResult{Allowed: true, Remaining: 4})
+	assert.NotContains(t, headers, "Retry-After")
+}
+
+func TestClientIP(t *testing.T) {
+	defer func() { _ = SetTrustedProxies(nil) }()
+	assert.Error(t, SetTrustedProxies([]string{"not-an-ip"}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "198.51.100.10:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+
+	// Untrusted peers can't choose their IP
+	assert.NoError(t, SetTrustedProxies(nil))
+	assert.Equal(t, "198.51.100.10", ClientIP(req))
+
+	assert.NoError(t, SetTrustedProxies([]string{"198.51.100.0/24", "10.0.0.1"}))
+	assert.Equal(t, "203.0.113.5", ClientIP(req))
+
+	// Spoofed entries left of the first untrusted hop are ignored
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.5, 10.0.0.1")
+	assert.Equal(t, "203.0.113.5", ClientIP(req))
+
+	// All hops trusted: the left-most one is the best guess
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, "10.0.0.1", ClientIP(req))
+
+	req.Header.Set("X-Forwarded-For", "garbage")
+	assert.Equal(t, "198.51.100.10", ClientIP(req))
+}
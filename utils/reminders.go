@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// Reminder settings (env):
+//
+//	REMINDER_OFFSETS   comma-separated durations before the slot starts (default "24h,2h")
+//	SLOT_START_TIME    HH:MM a slot day starts, server local time (default "08:00")
+//	REMINDER_INTERVAL  how often the scheduler looks for due reminders (default "1m")
+const defaultReminderOffsets = "24h,2h"
+
+// ParseReminderOffsets parses a comma-separated list of durations, largest first
+func ParseReminderOffsets(value string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid reminder offset %q", part)
+		}
+		offsets = append(offsets, d)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// ReminderOffsets returns the configured reminder offsets, falling back to the defaults
+func ReminderOffsets() []time.Duration {
+	offsets, err := ParseReminderOffsets(getenvDefault("REMINDER_OFFSETS", defaultReminderOffsets))
+	if err != nil || len(offsets) == 0 {
+		zap.L().Warn("Invalid REMINDER_OFFSETS, using defaults", zap.Error(err))
+		offsets, _ = ParseReminderOffsets(defaultReminderOffsets)
+	}
+	return offsets
+}
+
+// SlotStartTime returns when a slot begins: its date at SLOT_START_TIME in server local time
+func SlotStartTime(slot models.Slot) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02 15:04", slot.Date+" "+getenvDefault("SLOT_START_TIME", "08:00"), time.Local)
+}
+
+// dueReminderOffsets returns the offsets whose reminder time has passed for a slot starting
+// at start. Nothing is due once the slot has started.
+func dueReminderOffsets(start, now time.Time, offsets []time.Duration) []time.Duration {
+	if !now.Before(start) {
+		return nil
+	}
+	var due []time.Duration
+	for _, offset := range offsets {
+		if !now.Before(start.Add(-offset)) {
+			due = append(due, offset)
+		}
+	}
+	return due
+}
+
+// reminderClaimTimeout is how long a pending claim blocks other runs; a claim older than
+// that belongs to a run that died before delivering and is taken over
+const reminderClaimTimeout = 10 * time.Minute
+
+// claimReminder atomically claims a reminder as pending; it returns false if another run
+// (or another instance) already sent it or is sending it.
+func claimReminder(reservationID uint, offset time.Duration, now time.Time) (bool, error) {
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ReservationReminder{
+		ReservationID: reservationID,
+		Offset:        offset.String(),
+		Status:        models.ReminderPending,
+		ClaimedAt:     now,
+	})
+	if result.Error != nil || result.RowsAffected == 1 {
+		return result.RowsAffected == 1, result.Error
+	}
+	result = config.DB.Model(&models.ReservationReminder{}).
+		Where("reservation_id = ? AND \"offset\" = ? AND status = ? AND claimed_at < ?", reservationID, offset.String(), models.ReminderPending, now.Add(-reminderClaimTimeout)).
+		Update("claimed_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// skipReminder records an older offset that is no longer worth sending
+func skipReminder(reservationID uint, offset time.Duration, now time.Time) error {
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ReservationReminder{
+		ReservationID: reservationID,
+		Offset:        offset.String(),
+		Status:        models.ReminderSkipped,
+		ClaimedAt:     now,
+	}).Error
+}
+
+// finishReminder marks a claimed reminder sent, or releases the claim when delivery
+// failed so the next run tries again
+func finishReminder(reservationID uint, offset time.Duration, now time.Time, deliveryErr error) error {
+	claim := config.DB.Where("reservation_id = ? AND \"offset\" = ? AND status = ?", reservationID, offset.String(), models.ReminderPending)
+	if deliveryErr != nil {
+		return claim.Delete(&models.ReservationReminder{}).Error
+	}
+	return claim.Model(&models.ReservationReminder{}).Updates(map[string]interface{}{"status": models.ReminderSent, "sent_at": now}).Error
+}
+
+// SendDueReminders sends reminders for approved reservations whose reminder time has come
+// and returns how many were sent. Cancelled reservations, deleted slots and deleted children
+// are skipped. When the scheduler was down and several offsets are due at once, only the
+// closest one is sent and the older ones are recorded as skipped. A reminder that could not
+// be delivered is retried on the next run.
+func SendDueReminders(now time.Time) int {
+	offsets := ReminderOffsets()
+	horizon := now.Add(offsets[0] + 24*time.Hour).Format("2006-01-02")
+
+	type row struct {
+		ReservationID uint
+		ParentID      uint
+		ChildName     string
+		SlotDate      string
+	}
+	var rows []row
+	err := config.DB.Table("reservations").
+		Select("reservations.id AS reservation_id, children.parent_id, children.name AS child_name, slots.date AS slot_date").
+		Joins("JOIN slots ON slots.id = reservations.slot_id AND slots.deleted_at IS NULL").
+		Joins("JOIN children ON children.id = reservations.child_id AND children.deleted_at IS NULL").
+		Where("reservations.deleted_at IS NULL AND reservations.status = ?", "approved").
+		Where("slots.date >= ? AND slots.date <= ?", now.Format("2006-01-02"), horizon).
+		Scan(&rows).Error
+	if err != nil {
+		zap.L().Error("Failed to load reservations for reminders", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, res := range rows {
+		start, err := SlotStartTime(models.Slot{Date: res.SlotDate})
+		if err != nil {
+			continue
+		}
+		due := dueReminderOffsets(start, now, offsets)
+		if len(due) == 0 {
+			continue
+		}
+		// due is sorted largest first, so the last entry is the closest reminder
+		closest := due[len(due)-1]
+		for _, offset := range due[:len(due)-1] {
+			if err := skipReminder(res.ReservationID, offset, now); err != nil {
+				zap.L().Error("Failed to record skipped reminder", zap.Uint("reservation_id", res.ReservationID), zap.Error(err))
+			}
+		}
+		claimed, err := claimReminder(res.ReservationID, closest, now)
+		if err != nil {
+			zap.L().Error("Failed to claim reminder", zap.Uint("reservation_id", res.ReservationID), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		deliveryErr := Notify(res.ParentID, NotificationMessage{
+			Event:   NotifyReminders,
+			Subject: "Upcoming reservation",
+			Body:    fmt.Sprintf("Reminder: %s is booked for %s (starting %s).", res.ChildName, res.SlotDate, start.Format("15:04")),
+		})
+		if deliveryErr != nil {
+			zap.L().Warn("Failed to deliver reminder", zap.Uint("reservation_id", res.ReservationID), zap.Error(deliveryErr))
+		}
+		if err := finishReminder(res.ReservationID, closest, now, deliveryErr); err != nil {
+			zap.L().Error("Failed to record reminder", zap.Uint("reservation_id", res.ReservationID), zap.Error(err))
+		}
+		if deliveryErr == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// StartReminderScheduler runs SendDueReminders every REMINDER_INTERVAL until ctx is cancelled.
+// It is safe to run on several instances at once.
+func StartReminderScheduler(ctx context.Context) {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+	interval, err := time.ParseDuration(getenvDefault("REMINDER_INTERVAL", "1m"))
+	if err != nil || interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n := SendDueReminders(time.Now()); n > 0 {
+			zap.L().Info("Sent reservation reminders", zap.Int("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReminderOffsets(t *testing.T) {
+	offsets, err := ParseReminderOffsets("2h, 24h")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{24 * time.Hour, 2 * time.Hour}, offsets)
+
+	_, err = ParseReminderOffsets("tomorrow")
+	assert.Error(t, err)
+	_, err = ParseReminderOffsets("-1h")
+	assert.Error(t, err)
+}
+
+func TestDueReminderOffsets(t *testing.T) {
+	offsets := []time.Duration{24 * time.Hour, 2 * time.Hour}
+	start := time.Date(2025, 6, 10, 8, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, dueReminderOffsets(start, start.Add(-48*time.Hour), offsets))
+	assert.Equal(t, []time.Duration{24 * time.Hour}, dueReminderOffsets(start, start.Add(-23*time.Hour), offsets))
+	assert.Equal(t, offsets, dueReminderOffsets(start, start.Add(-time.Hour), offsets))
+	// Nothing is sent once the slot has started
+	assert.Empty(t, dueReminderOffsets(start, start, offsets))
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var trustedProxies struct {
+	sync.RWMutex
+	loaded bool
+	nets   []*net.IPNet
+}
+
+// SetTrustedProxies sets the proxies (IPs or CIDRs) whose X-Forwarded-For headers are
+// believed. By default the list comes from TRUSTED_PROXIES (comma-separated).
+func SetTrustedProxies(entries []string) error {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return NewValidationError(ErrInvalidInput, "Invalid trusted proxy", map[string]interface{}{"value": entry})
+		}
+		nets = append(nets, ipNet)
+	}
+	trustedProxies.Lock()
+	trustedProxies.nets = nets
+	trustedProxies.loaded = true
+	trustedProxies.Unlock()
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	trustedProxies.RLock()
+	loaded := trustedProxies.loaded
+	trustedProxies.RUnlock()
+	if !loaded {
+		if err := SetTrustedProxies(strings.Split(os.Getenv("TRUSTED_PROXIES"), ",")); err != nil {
+			zap.L().Error("Ignoring invalid TRUSTED_PROXIES", zap.Error(err))
+			_ = SetTrustedProxies(nil)
+		}
+	}
+	trustedProxies.RLock()
+	defer trustedProxies.RUnlock()
+	for _, n := range trustedProxies.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client that sent the request. X-Forwarded-For
+// is only believed when the request comes from a trusted proxy; the header is then
+// read right to left, skipping further trusted proxies, so clients can't spoof it.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !isTrustedProxy(remote) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// A malformed entry; nothing left of it can be trusted
+			break
+		}
+		if !isTrustedProxy(ip) {
+			return ip.String()
+		}
+		host = ip.String()
+	}
+	return host
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+// Security event types
+const (
+	SecurityLogin                  = "login"
+	SecurityLoginFailed            = "login_failed"
+	SecurityLogout                 = "logout"
+	SecurityLogoutAll              = "logout_all"
+	SecurityPasswordChanged        = "password_changed"
+	SecurityPasswordResetRequested = "password_reset_requested"
+	SecurityPasswordReset          = "password_reset"
+	SecurityRoleChanged            = "role_changed"
+	// SecurityAlert marks a suspicious pattern found in the log
+	SecurityAlert = "alert"
+)
+
+// SecurityEventTypes lists every security event type
+var SecurityEventTypes = []string{
+	SecurityLogin, SecurityLoginFailed, SecurityLogout, SecurityLogoutAll, SecurityPasswordChanged,
+	SecurityPasswordResetRequested, SecurityPasswordReset, SecurityRoleChanged, SecurityAlert,
+}
+
+// Security event outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomeBlocked is an attempt refused before checking credentials, e.g. while locked out
+	OutcomeBlocked = "blocked"
+)
+
+const (
+	// SecurityEventRetention is how long security events are kept
+	SecurityEventRetention = 365 * 24 * time.Hour
+	// securityAlertWindow is how far back the alert checks look
+	securityAlertWindow = time.Hour
+	// securityAlertFailures is how many failed logins make a login from a new IP suspicious
+	securityAlertFailures = 3
+	// securityAlertSprayAccounts is how many accounts failing from one IP look like password spraying
+	securityAlertSprayAccounts = 10
+)
+
+// RecordSecurityEvent stores a security event with the request's client IP and user agent,
+// then checks it for suspicious patterns. Failures are logged, not returned, so they never
+// break the request.
+func RecordSecurityEvent(r *http.Request, event models.SecurityEvent) {
+	if config.DB == nil {
+		return
+	}
+	if r != nil {
+		event.IP = ClientIP(r)
+		event.UserAgent = truncate(r.UserAgent(), 512)
+	}
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+	event.Email = strings.ToLower(strings.TrimSpace(event.Email))
+	event.Details = truncate(event.Details, 500)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if err := config.DB.Create(&event).Error; err != nil {
+		zap.L().Warn("Failed to record security event", zap.String("type", event.Type), zap.Error(err))
+		return
+	}
+	checkSecurityAlerts(event)
+}
+
+// SecurityEventFor builds an event about a user (nil for an unknown account)
+func SecurityEventFor(eventType, outcome string, user *models.User, details string) models.SecurityEvent {
+	event := models.SecurityEvent{Type: eventType, Outcome: outcome, Details: details}
+	if user != nil {
+		id := user.ID
+		event.UserID = &id
+		event.Email = user.Email
+	}
+	return event
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// ParseSecurityEventTypes validates a comma-separated list of event types; empty means all
+func ParseSecurityEventTypes(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, t := range SecurityEventTypes {
+		known[t] = true
+	}
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if !known[t] {
+			return nil, NewValidationError(ErrInvalidInput, "Unknown security event type: "+t, map[string]interface{}{
+				"type":        t,
+				"valid_types": SecurityEventTypes,
+			})
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// checkSecurityAlerts looks for suspicious patterns around a new event:
+//   - a successful login from an IP the account never logged in from, after several failed
+//     logins for the account
+//   - failed logins for many different accounts from one IP (password spraying)
+func checkSecurityAlerts(event models.SecurityEvent) {
+	since := event.CreatedAt.Add(-securityAlertWindow)
+
+	switch {
+	case event.Type == SecurityLogin && event.Outcome == OutcomeSuccess && event.UserID != nil:
+		var failures, knownIP, earlier int64
+		config.DB.Model(&models.SecurityEvent{}).
+			Where("user_id = ? AND type = ? AND created_at >= ?", *event.UserID, SecurityLoginFailed, since).
+			Count(&failures)
+		if failures < securityAlertFailures {
+			return
+		}
+		config.DB.Model(&models.SecurityEvent{}).
+			Where("user_id = ? AND type = ? AND outcome = ? AND id <> ?", *event.UserID, SecurityLogin, OutcomeSuccess, event.ID).
+			Count(&earlier)
+		config.DB.Model(&models.SecurityEvent{}).
+			Where("user_id = ? AND type = ? AND outcome = ? AND ip = ? AND id <> ?", *event.UserID, SecurityLogin, OutcomeSuccess, event.IP, event.ID).
+			Count(&knownIP)
+		// Without earlier logins every IP is new, so there is nothing to compare with
+		if earlier == 0 || knownIP > 0 {
+			return
+		}
+		raiseSecurityAlert(event, fmt.Sprintf("Login from new IP %s after %d failed attempts", event.IP, failures))
+
+	case event.Type == SecurityLoginFailed && event.IP != "":
+		var accounts int64
+		config.DB.Model(&models.SecurityEvent{}).
+			Where("type = ? AND ip = ? AND created_at >= ?", SecurityLoginFailed, event.IP, since).
+			Distinct("email").Count(&accounts)
+		if accounts < securityAlertSprayAccounts {
+			return
+		}
+		// One alert per IP and window, not one for every later failure
+		var alerted int64
+		config.DB.Model(&models.SecurityEvent{}).
+			Where("type = ? AND ip = ? AND created_at >= ?", SecurityAlert, event.IP, since).
+			Count(&alerted)
+		if alerted > 0 {
+			return
+		}
+		raiseSecurityAlert(models.SecurityEvent{IP: event.IP, UserAgent: event.UserAgent, CreatedAt: event.CreatedAt},
+			fmt.Sprintf("Failed logins for %d accounts from IP %s within %s", accounts, event.IP, securityAlertWindow))
+	}
+}
+
+// raiseSecurityAlert records an alert event and, with the security_alerts setting on,
+// emails it to staff who can manage users
+func raiseSecurityAlert(source models.SecurityEvent, message string) {
+	alert := models.SecurityEvent{
+		Type:      SecurityAlert,
+		Outcome:   OutcomeFailure,
+		UserID:    source.UserID,
+		Email:     source.Email,
+		IP:        source.IP,
+		UserAgent: source.UserAgent,
+		Details:   truncate(message, 500),
+		CreatedAt: source.CreatedAt,
+	}
+	if err := config.DB.Create(&alert).Error; err != nil {
+		zap.L().Warn("Failed to record security alert", zap.Error(err))
+	}
+	zap.L().Warn("Security alert", zap.String("message", message), zap.String("ip", source.IP))
+
+	if !SettingBool(SettingSecurityAlerts) {
+		return
+	}
+	var recipients []models.User
+	config.DB.Where("role IN ?", StaffRoles()).Find(&recipients)
+	body := message
+	if source.Email != "" {
+		body += "\nAccount: " + source.Email
+	}
+	body += "\nTime: " + source.CreatedAt.UTC().Format(time.RFC3339) + "\n\nSee the security log: " + FrontendURL("/admin/security", nil)
+	for _, recipient := range recipients {
+		if !HasPermission(recipient.Role, PermUsersManage) {
+			continue
+		}
+		// Sent in the background so the request that triggered the alert isn't held up
+		go func(recipient models.User) {
+			if err := NotifyUser(recipient, NotificationMessage{Event: NotifyAccount, Subject: "Security alert", Body: body}); err != nil {
+				zap.L().Warn("Failed to send security alert", zap.Uint("user_id", recipient.ID), zap.Error(err))
+			}
+		}(recipient)
+	}
+}
+
+// PurgeSecurityEvents deletes events older than SecurityEventRetention and returns how many
+func PurgeSecurityEvents(now time.Time) int64 {
+	result := config.DB.Where("created_at < ?", now.Add(-SecurityEventRetention)).Delete(&models.SecurityEvent{})
+	if result.Error != nil {
+		zap.L().Warn("Failed to purge security events", zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecurityEventTypes(t *testing.T) {
+	types, err := ParseSecurityEventTypes("")
+	assert.NoError(t, err)
+	assert.Nil(t, types)
+
+	types, err = ParseSecurityEventTypes("login, login_failed")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{SecurityLogin, SecurityLoginFailed}, types)
+
+	_, err = ParseSecurityEventTypes("login,nope")
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrInvalidInput, err.(ValidationError).Code)
+	}
+}
+
+func TestSecurityEventFor(t *testing.T) {
+	user := models.User{Email: "someone@example.com"}
+	user.ID = 7
+	event := SecurityEventFor(SecurityLogin, OutcomeSuccess, &user, "password")
+	if assert.NotNil(t, event.UserID) {
+		assert.Equal(t, uint(7), *event.UserID)
+	}
+	assert.Equal(t, "someone@example.com", event.Email)
+
+	event = SecurityEventFor(SecurityLoginFailed, OutcomeFailure, nil, "unknown account")
+	assert.Nil(t, event.UserID)
+	assert.Equal(t, OutcomeFailure, event.Outcome)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, 512, len(truncate(strings.Repeat("x", 600), 512)))
+	assert.Equal(t, "a", truncate("a→b", 2), "multi-byte characters aren't split")
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"encoding/json"
+	"sort"
+
+	"reservio/config"
+	"reservio/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// Known setting keys
+const (
+	// SettingRequireAdmin2FA makes two-factor authentication mandatory for admins
+	SettingRequireAdmin2FA = "require_admin_2fa"
+	// SettingRequireVerifiedEmail blocks reservations until the user's email is verified
+	SettingRequireVerifiedEmail = "require_verified_email"
+	// SettingRegistrationMode controls who can create an account (see RegistrationModes)
+	SettingRegistrationMode = "registration_mode"
+	// SettingMagicLinkLogin lets parents log in with a link sent by email instead of a password
+	SettingMagicLinkLogin = "magic_link_login"
+	// SettingSecurityAlerts emails staff who manage users when the security log shows a suspicious pattern
+	SettingSecurityAlerts = "security_alerts"
+)
+
+// settingDefinition gives a setting its default and checks values admins submit
+type settingDefinition struct {
+	Default  interface{}
+	Validate func(value interface{}) bool
+	Hint     string
+}
+
+func isBool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
+
+func isOneOf(values ...string) func(interface{}) bool {
+	return func(value interface{}) bool {
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		for _, v := range values {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var settingDefinitions = map[string]settingDefinition{
+	SettingRequireAdmin2FA:      {Default: false, Validate: isBool, Hint: "must be true or false"},
+	SettingRequireVerifiedEmail: {Default: false, Validate: isBool, Hint: "must be true or false"},
+	SettingMagicLinkLogin:       {Default: false, Validate: isBool, Hint: "must be true or false"},
+	SettingSecurityAlerts:       {Default: false, Validate: isBool, Hint: "must be true or false"},
+	SettingRegistrationMode: {
+		Default:  RegistrationOpen,
+		Validate: isOneOf(RegistrationModes...),
+		Hint:     "must be one of open, invite_only, approval_required",
+	},
+}
+
+// GetSetting returns the stored value of a setting (JSON-decoded), or its default
+func GetSetting(key string) interface{} {
+	def := settingDefinitions[key]
+	if config.DB == nil {
+		return def.Default
+	}
+	var setting models.Setting
+	if err := config.DB.Where("key = ?", key).First(&setting).Error; err != nil {
+		return def.Default
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(setting.Value), &value); err != nil || (def.Validate != nil && !def.Validate(value)) {
+		return def.Default
+	}
+	return value
+}
+
+// SettingBool returns a boolean setting
+func SettingBool(key string) bool {
+	value, _ := GetSetting(key).(bool)
+	return value
+}
+
+// SettingString returns a string setting
+func SettingString(key string) string {
+	value, _ := GetSetting(key).(string)
+	return value
+}
+
+// AllSettings returns every known setting with its current value
+func AllSettings() map[string]interface{} {
+	values := make(map[string]interface{}, len(settingDefinitions))
+	for key := range settingDefinitions {
+		values[key] = GetSetting(key)
+	}
+	return values
+}
+
+// ValidateSettings checks that every key is known and every value has the right type
+func ValidateSettings(values map[string]interface{}) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		def, ok := settingDefinitions[key]
+		if !ok {
+			return NewValidationError(ErrInvalidInput, "Unknown setting", map[string]interface{}{"key": key})
+		}
+		if def.Validate != nil && !def.Validate(values[key]) {
+			return NewValidationError(ErrInvalidInput, "Invalid setting value", map[string]interface{}{
+				"key":  key,
+				"hint": def.Hint,
+			})
+		}
+	}
+	return nil
+}
+
+// SaveSettings validates and stores settings
+func SaveSettings(values map[string]interface{}) error {
+	if err := ValidateSettings(values); err != nil {
+		return err
+	}
+	for key, value := range values {
+		encoded, _ := json.Marshal(value)
+		setting := models.Setting{Key: key, Value: string(encoded)}
+		if err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&setting).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
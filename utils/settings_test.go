@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSettings(t *testing.T) {
+	assert.NoError(t, ValidateSettings(map[string]interface{}{SettingRequireAdmin2FA: true}))
+
+	err := ValidateSettings(map[string]interface{}{"no_such_setting": true})
+	assert.Error(t, err)
+	assert.Equal(t, "Unknown setting", err.Error())
+
+	err = ValidateSettings(map[string]interface{}{SettingRequireAdmin2FA: "yes"})
+	assert.Error(t, err)
+	assert.Equal(t, "Invalid setting value", err.Error())
+}
+
+func TestValidateRegistrationMode(t *testing.T) {
+	for _, mode := range RegistrationModes {
+		assert.NoError(t, ValidateSettings(map[string]interface{}{SettingRegistrationMode: mode}))
+	}
+	assert.Error(t, ValidateSettings(map[string]interface{}{SettingRegistrationMode: "closed"}))
+	assert.Error(t, ValidateSettings(map[string]interface{}{SettingRegistrationMode: true}))
+}
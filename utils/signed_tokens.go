@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Token error codes
+const (
+	ErrInvalidToken = "INVALID_TOKEN"
+	ErrTokenExpired = "TOKEN_EXPIRED"
+)
+
+// SignedToken is a stateless, HMAC-signed token for links sent by email. Email binds it
+// to an address, so it stops working once the address changes.
+type SignedToken struct {
+	Purpose string `json:"p"`
+	UserID  uint   `json:"u"`
+	Email   string `json:"e"`
+	Expires int64  `json:"x"`
+}
+
+// tokenSigningKey is EMAIL_TOKEN_SECRET, falling back to SESSION_SECRET. Only test mode
+// has a fixed key; elsewhere a missing secret is fatal instead of signing with a public key.
+func tokenSigningKey() []byte {
+	for _, env := range []string{"EMAIL_TOKEN_SECRET", "SESSION_SECRET"} {
+		if key := os.Getenv(env); key != "" {
+			return []byte(key)
+		}
+	}
+	if os.Getenv("TEST_MODE") == "1" {
+		return []byte("reservio-test-token-key")
+	}
+	log.Fatal("EMAIL_TOKEN_SECRET or SESSION_SECRET must be set")
+	return nil
+}
+
+// CheckTokenSigningKey stops the server at startup when email links can't be signed
+func CheckTokenSigningKey() {
+	tokenSigningKey()
+}
+
+func signPayload(payload string) string {
+	mac := hmac.New(sha256.New, tokenSigningKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignToken encodes and signs a token
+func SignToken(t SignedToken) string {
+	data, _ := json.Marshal(t)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signPayload(payload)
+}
+
+// VerifySignedToken checks the signature, purpose and expiry of a token
+func VerifySignedToken(raw, purpose string, now time.Time) (SignedToken, error) {
+	var t SignedToken
+	payload, sig, ok := strings.Cut(raw, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signPayload(payload))) {
+		return t, NewValidationError(ErrInvalidToken, "Invalid or expired token", nil)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &t) != nil || t.Purpose != purpose {
+		return SignedToken{}, NewValidationError(ErrInvalidToken, "Invalid or expired token", nil)
+	}
+	if now.Unix() > t.Expires {
+		return t, NewValidationError(ErrTokenExpired, "Token has expired", nil)
+	}
+	return t, nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedTokens(t *testing.T) {
+	now := time.Now()
+	raw := EmailVerificationToken(7, "Parent@Example.com", now)
+
+	token, err := VerifySignedToken(raw, PurposeEmailVerification, now)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), token.UserID)
+	assert.Equal(t, "parent@example.com", token.Email)
+
+	// Wrong purpose
+	_, err = VerifySignedToken(raw, "password_reset", now)
+	assert.Equal(t, ErrInvalidToken, err.(ValidationError).Code)
+
+	// Tampered payload
+	forged := SignToken(SignedToken{Purpose: PurposeEmailVerification, UserID: 8, Email: "x@example.com", Expires: now.Add(time.Hour).Unix()})
+	_, sig, _ := strings.Cut(raw, ".")
+	payload, _, _ := strings.Cut(forged, ".")
+	_, err = VerifySignedToken(payload+"."+sig, PurposeEmailVerification, now)
+	assert.Equal(t, ErrInvalidToken, err.(ValidationError).Code)
+
+	_, err = VerifySignedToken("garbage", PurposeEmailVerification, now)
+	assert.Equal(t, ErrInvalidToken, err.(ValidationError).Code)
+
+	// Expired
+	_, err = VerifySignedToken(raw, PurposeEmailVerification, now.Add(EmailVerificationTTL()+time.Second))
+	assert.Equal(t, ErrTokenExpired, err.(ValidationError).Code)
+}
+
+func TestCanResendVerification(t *testing.T) {
+	now := time.Now()
+	assert.True(t, CanResendVerification(models.User{}, now))
+
+	sent := now.Add(-30 * time.Second)
+	assert.False(t, CanResendVerification(models.User{VerificationSentAt: &sent}, now))
+	sent = now.Add(-2 * time.Minute)
+	assert.True(t, CanResendVerification(models.User{VerificationSentAt: &sent}, now))
+}
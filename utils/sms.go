@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reservio/models"
+
+	"go.uber.org/zap"
+)
+
+// SMS settings (env):
+//
+//	SMS_PROVIDER                log (default), http or twilio
+//	SMS_HTTP_URL / SMS_HTTP_TOKEN          generic HTTP provider endpoint and bearer token
+//	SMS_TWILIO_ACCOUNT_SID / SMS_TWILIO_AUTH_TOKEN / SMS_TWILIO_BASE_URL
+//	SMS_FROM                    sender number or alphanumeric ID
+//	SMS_DEFAULT_COUNTRY_CODE    calling code for numbers entered without one (e.g. "420")
+//	SMS_MAX_SEGMENTS            longest message we send, in segments (default 3)
+//	SMS_COST_PER_SEGMENT        price of one segment in cents (default 0)
+//	SMS_MAX_COST                most a single message may cost in cents (default 0 = no limit)
+
+// ErrInvalidPhone is the error code for phone numbers that can't be normalized to E.164
+const ErrInvalidPhone = "INVALID_PHONE"
+
+// SMSProvider sends a text message to an E.164 number
+type SMSProvider interface {
+	Send(to, body string) error
+}
+
+// LogSMSProvider only logs messages; used in development and tests
+type LogSMSProvider struct{}
+
+// Send logs the message instead of sending it
+func (LogSMSProvider) Send(to, body string) error {
+	log.Printf("[SMS] to %s: %s", to, body)
+	return nil
+}
+
+// HTTPSMSProvider posts {"to","from","body"} as JSON to a generic SMS gateway,
+// authenticated with a bearer token.
+type HTTPSMSProvider struct {
+	URL    string
+	Token  string
+	From   string
+	Client *http.Client
+}
+
+// Send posts the message to the gateway; any non-2xx response is an error
+func (p HTTPSMSProvider) Send(to, body string) error {
+	payload, _ := json.Marshal(map[string]string{"to": to, "from": p.From, "body": body})
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	return doSMSRequest(p.Client, req)
+}
+
+// TwilioSMSProvider uses Twilio's Messages API format (form-encoded To/From/Body with
+// basic auth). BaseURL can point at any Twilio-compatible service.
+type TwilioSMSProvider struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	BaseURL    string
+	Client     *http.Client
+}
+
+// Send creates a message through the Messages endpoint
+func (p TwilioSMSProvider) Send(to, body string) error {
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.twilio.com"
+	}
+	endpoint := strings.TrimRight(base, "/") + "/2010-04-01/Accounts/" + url.PathEscape(p.AccountSID) + "/Messages.json"
+	form := url.Values{"To": {to}, "From": {p.From}, "Body": {body}}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.AccountSID, p.AuthToken)
+	return doSMSRequest(p.Client, req)
+}
+
+func doSMSRequest(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS provider responded with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+var smsProvider = struct {
+	sync.Mutex
+	p SMSProvider
+}{}
+
+// NewSMSProviderFromEnv builds the provider selected by SMS_PROVIDER
+func NewSMSProviderFromEnv() SMSProvider {
+	switch os.Getenv("SMS_PROVIDER") {
+	case "http":
+		return HTTPSMSProvider{URL: os.Getenv("SMS_HTTP_URL"), Token: os.Getenv("SMS_HTTP_TOKEN"), From: os.Getenv("SMS_FROM")}
+	case "twilio":
+		return TwilioSMSProvider{
+			AccountSID: os.Getenv("SMS_TWILIO_ACCOUNT_SID"),
+			AuthToken:  os.Getenv("SMS_TWILIO_AUTH_TOKEN"),
+			From:       os.Getenv("SMS_FROM"),
+			BaseURL:    os.Getenv("SMS_TWILIO_BASE_URL"),
+		}
+	}
+	return LogSMSProvider{}
+}
+
+// SetSMSProvider replaces the provider (useful in tests)
+func SetSMSProvider(p SMSProvider) {
+	smsProvider.Lock()
+	defer smsProvider.Unlock()
+	smsProvider.p = p
+}
+
+func currentSMSProvider() SMSProvider {
+	smsProvider.Lock()
+	defer smsProvider.Unlock()
+	if smsProvider.p == nil {
+		smsProvider.p = NewSMSProviderFromEnv()
+	}
+	return smsProvider.p
+}
+
+var e164Regex = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+
+// NormalizePhone converts a phone number to E.164 (+<country><number>). Spaces, dashes,
+// dots and parentheses are ignored, a leading "00" is treated as "+", and numbers without
+// a country code get defaultCountryCode (dropping a national trunk "0"). Without a default
+// country code such numbers are rejected, since the country can't be guessed.
+func NormalizePhone(raw, defaultCountryCode string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+	case strings.HasPrefix(cleaned, "00"):
+		cleaned = "+" + cleaned[2:]
+	case defaultCountryCode != "":
+		cleaned = "+" + strings.TrimPrefix(defaultCountryCode, "+") + strings.TrimPrefix(cleaned, "0")
+	default:
+		return "", NewValidationError(ErrInvalidPhone, "Phone number needs a country code, e.g. +420601234567", map[string]interface{}{
+			"field": "phone",
+			"value": raw,
+		})
+	}
+
+	if !e164Regex.MatchString(cleaned) {
+		return "", NewValidationError(ErrInvalidPhone, "Invalid phone number. Use international format, e.g. +420601234567", map[string]interface{}{
+			"field": "phone",
+			"value": raw,
+		})
+	}
+	return cleaned, nil
+}
+
+// NormalizeUserPhone normalizes a phone number entered by a user using SMS_DEFAULT_COUNTRY_CODE.
+// An empty number stays empty.
+func NormalizeUserPhone(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	return NormalizePhone(raw, os.Getenv("SMS_DEFAULT_COUNTRY_CODE"))
+}
+
+// gsm7Chars is the GSM 03.38 basic character set; anything else forces UCS-2 encoding
+const gsm7Chars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+func isGSM7(body string) bool {
+	for _, r := range body {
+		if !strings.ContainsRune(gsm7Chars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// smsSegmentSizes returns the characters that fit in a single-part message and in each
+// part of a multipart message for the body's encoding.
+func smsSegmentSizes(body string) (single, multi int) {
+	if isGSM7(body) {
+		return 160, 153
+	}
+	return 70, 67
+}
+
+// SMSSegments returns how many segments the body needs
+func SMSSegments(body string) int {
+	length := len([]rune(body))
+	single, multi := smsSegmentSizes(body)
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+// PrepareSMS applies the per-message limits: bodies longer than SMS_MAX_SEGMENTS are
+// truncated, and messages costing more than SMS_MAX_COST are refused.
+func PrepareSMS(body string) (string, error) {
+	maxSegments := envInt("SMS_MAX_SEGMENTS", 3)
+	if maxSegments < 1 {
+		maxSegments = 1
+	}
+	if SMSSegments(body) > maxSegments {
+		single, multi := smsSegmentSizes(body)
+		limit := single
+		if maxSegments > 1 {
+			limit = multi * maxSegments
+		}
+		body = string([]rune(body)[:limit-3]) + "..."
+	}
+
+	if maxCost := envInt("SMS_MAX_COST", 0); maxCost > 0 {
+		cost := SMSSegments(body) * envInt("SMS_COST_PER_SEGMENT", 0)
+		if cost > maxCost {
+			return "", fmt.Errorf("SMS would cost %d cents, limit is %d", cost, maxCost)
+		}
+	}
+	return body, nil
+}
+
+// SendSMS applies the message limits and sends through the configured provider
+func SendSMS(to, body string) error {
+	prepared, err := PrepareSMS(body)
+	if err != nil {
+		return err
+	}
+	return currentSMSProvider().Send(to, prepared)
+}
+
+// sendSMSNotification is the dispatcher's SMS channel. Users without a phone number or
+// who haven't opted in are skipped silently.
+func sendSMSNotification(user models.User, msg NotificationMessage) error {
+	if user.Phone == "" || !user.SMSOptIn {
+		zap.L().Debug("Skipping SMS, no phone or not opted in", zap.Uint("user_id", user.ID))
+		return nil
+	}
+	return SendSMS(user.Phone, msg.Subject+": "+msg.Body)
+}
+
+func init() {
+	RegisterNotificationChannel(ChannelSMS, sendSMSNotification)
+}
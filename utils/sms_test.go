@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	phone, err := NormalizePhone("+420 601 234 567", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "+420601234567", phone)
+
+	phone, err = NormalizePhone("00420-601-234-567", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "+420601234567", phone)
+
+	phone, err = NormalizePhone("(0601) 234 567", "420")
+	assert.NoError(t, err)
+	assert.Equal(t, "+420601234567", phone)
+
+	_, err = NormalizePhone("not a phone", "")
+	assert.Error(t, err)
+	_, err = NormalizePhone("+123", "")
+	assert.Error(t, err)
+
+	// Without a default country code the country of a national number is unknown
+	_, err = NormalizePhone("420601234567", "")
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrInvalidPhone, err.(ValidationError).Code)
+	}
+}
+
+func TestSMSSegments(t *testing.T) {
+	assert.Equal(t, 1, SMSSegments(strings.Repeat("a", 160)))
+	assert.Equal(t, 2, SMSSegments(strings.Repeat("a", 161)))
+	assert.Equal(t, 1, SMSSegments(strings.Repeat("ř", 70)))
+	assert.Equal(t, 2, SMSSegments(strings.Repeat("ř", 71)))
+}
+
+func TestPrepareSMS(t *testing.T) {
+	t.Setenv("SMS_MAX_SEGMENTS", "2")
+	body, err := PrepareSMS(strings.Repeat("a", 500))
+	assert.NoError(t, err)
+	assert.Equal(t, 306, len(body))
+	assert.True(t, strings.HasSuffix(body, "..."))
+	assert.Equal(t, 2, SMSSegments(body))
+
+	t.Setenv("SMS_COST_PER_SEGMENT", "5")
+	t.Setenv("SMS_MAX_COST", "8")
+	_, err = PrepareSMS(strings.Repeat("a", 200))
+	assert.Error(t, err)
+	_, err = PrepareSMS("short")
+	assert.NoError(t, err)
+}
+
+func TestHTTPSMSProviders(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		got = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	twilio := TwilioSMSProvider{AccountSID: "AC1", AuthToken: "tok", From: "+15550000000", BaseURL: server.URL}
+	assert.NoError(t, twilio.Send("+420601234567", "hello"))
+	assert.Equal(t, "/2010-04-01/Accounts/AC1/Messages.json", got.URL.Path)
+	assert.Equal(t, "+420601234567", got.PostForm.Get("To"))
+	user, pass, _ := got.BasicAuth()
+	assert.Equal(t, "AC1", user)
+	assert.Equal(t, "tok", pass)
+
+	generic := HTTPSMSProvider{URL: server.URL, Token: "secret"}
+	assert.NoError(t, generic.Send("+420601234567", "hello"))
+	assert.Equal(t, "Bearer secret", got.Header.Get("Authorization"))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	assert.Error(t, HTTPSMSProvider{URL: failing.URL}.Send("+420601234567", "hello"))
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B test vectors (SHA-1, secret "12345678901234567890")
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, "t=%d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+
+	code, _ := TOTPCode(secret, step)
+	matched, ok := ValidateTOTP(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step, matched)
+
+	// Replaying the same code is rejected
+	_, ok = ValidateTOTP(secret, code, now, matched)
+	assert.False(t, ok)
+
+	// One step of clock drift is tolerated, two are not
+	previous, _ := TOTPCode(secret, step-1)
+	_, ok = ValidateTOTP(secret, previous, now, 0)
+	assert.True(t, ok)
+	old, _ := TOTPCode(secret, step-2)
+	_, ok = ValidateTOTP(secret, old, now, 0)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "admin@example.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Reservio:admin@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Reservio")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(RecoveryCodeCount)
+	assert.Len(t, codes, RecoveryCodeCount)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+	assert.Equal(t, "abcde-12345", NormalizeRecoveryCode(" ABCDE12345 "))
+	assert.Equal(t, HashToken("abcde-12345"), HashToken(NormalizeRecoveryCode("ABCDE-12345")))
+}
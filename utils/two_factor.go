@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// pendingTwoFactorTTL is how long the second login step stays open after the password check
+	pendingTwoFactorTTL = 5 * time.Minute
+	// maxTwoFactorAttempts wrong codes end the pending login; the password must be entered again
+	maxTwoFactorAttempts = 5
+)
+
+// TwoFactorRequired reports whether the user must have two-factor authentication enabled.
+// require_admin_2fa covers every staff role, not just admins.
+func TwoFactorRequired(user models.User) bool {
+	return SettingBool(SettingRequireAdmin2FA) && IsStaffRole(user.Role)
+}
+
+// VerifyTwoFactor checks a TOTP code or, if code is empty, a recovery code for a user with
+// two-factor enabled. Accepted TOTP steps and recovery codes are consumed atomically, so
+// concurrent requests can't use the same code twice.
+func VerifyTwoFactor(user models.User, code, recoveryCode string, now time.Time) bool {
+	if !user.TOTPEnabled {
+		return false
+	}
+	if code != "" {
+		step, ok := ValidateTOTP(user.TOTPSecret, code, now, user.TOTPLastStep)
+		if !ok {
+			return false
+		}
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			UpdateColumn("totp_last_step", step)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+	if recoveryCode == "" {
+		return false
+	}
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, HashToken(NormalizeRecoveryCode(recoveryCode))).
+		Update("used_at", now)
+	if result.Error == nil && result.RowsAffected == 1 {
+		zap.L().Info("Recovery code used", zap.Uint("user_id", user.ID))
+		return true
+	}
+	return false
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and returns a fresh set. The plain
+// codes are only available here; the database keeps their hashes.
+func ReplaceRecoveryCodes(userID uint) ([]string, error) {
+	codes := GenerateRecoveryCodes(RecoveryCodeCount)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		rows := make([]models.RecoveryCode, len(codes))
+		for i, code := range codes {
+			rows[i] = models.RecoveryCode{UserID: userID, CodeHash: HashToken(code)}
+		}
+		return tx.Create(&rows).Error
+	})
+	return codes, err
+}
+
+// RemainingRecoveryCodes counts a user's unused recovery codes
+func RemainingRecoveryCodes(userID uint) int64 {
+	var n int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n)
+	return n
+}
+
+// SetPendingTwoFactor records in the session that the user passed the password check and
+// still has to enter a second factor. The session is not authenticated until then.
+func SetPendingTwoFactor(w http.ResponseWriter, r *http.Request, userID uint) {
+	session, _ := config.Store.Get(r, "session")
+	delete(session.Values, "user_id")
+	session.Values["pending_2fa_user_id"] = strconv.FormatUint(uint64(userID), 10)
+	session.Values["pending_2fa_expiry"] = time.Now().Add(pendingTwoFactorTTL).Unix()
+	session.Values["pending_2fa_attempts"] = 0
+	if err := session.Save(r, w); err != nil {
+		zap.L().Warn("SetPendingTwoFactor save error", zap.Error(err))
+	}
+}
+
+// PendingTwoFactorUser returns the user waiting for the second login step, if any
+func PendingTwoFactorUser(r *http.Request) (uint, bool) {
+	session, _ := config.Store.Get(r, "session")
+	idStr, _ := session.Values["pending_2fa_user_id"].(string)
+	expiry, _ := session.Values["pending_2fa_expiry"].(int64)
+	attempts, _ := session.Values["pending_2fa_attempts"].(int)
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil || time.Now().Unix() > expiry || attempts >= maxTwoFactorAttempts {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// FailPendingTwoFactor counts a wrong code and returns how many attempts are left
+func FailPendingTwoFactor(w http.ResponseWriter, r *http.Request) int {
+	session, _ := config.Store.Get(r, "session")
+	attempts, _ := session.Values["pending_2fa_attempts"].(int)
+	attempts++
+	session.Values["pending_2fa_attempts"] = attempts
+	if attempts >= maxTwoFactorAttempts {
+		clearPendingTwoFactor(session.Values)
+	}
+	if err := session.Save(r, w); err != nil {
+		zap.L().Warn("FailPendingTwoFactor save error", zap.Error(err))
+	}
+	return maxTwoFactorAttempts - attempts
+}
+
+// CompleteTwoFactorLogin ends the pending step and logs the user in
+func CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request, userID uint) {
+	session, _ := config.Store.Get(r, "session")
+	clearPendingTwoFactor(session.Values)
+	SetSession(w, r, userID)
+}
+
+func clearPendingTwoFactor(values map[interface{}]interface{}) {
+	delete(values, "pending_2fa_user_id")
+	delete(values, "pending_2fa_expiry")
+	delete(values, "pending_2fa_attempts")
+}
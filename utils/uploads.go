@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFileTooLarge is the error code for uploads over the size limit
+const ErrFileTooLarge = "FILE_TOO_LARGE"
+
+// UploadPolicy restricts what an upload may contain. AllowedTypes maps each accepted
+// content type to the extension stored files get.
+type UploadPolicy struct {
+	Dir          string
+	AllowedTypes map[string]string
+	MaxSize      int64
+}
+
+// ProfilePicturePolicy stores profile pictures in the public uploads directory
+var ProfilePicturePolicy = UploadPolicy{
+	Dir:          "uploads",
+	AllowedTypes: map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/gif": ".gif"},
+	MaxSize:      10 << 20,
+}
+
+// AttachmentPolicy returns the policy for announcement attachments. They are kept outside
+// the public uploads directory (ATTACHMENT_DIR, default "attachments") and only served
+// through the authenticated download endpoint. ATTACHMENT_MAX_SIZE is in bytes (default 10MB).
+func AttachmentPolicy() UploadPolicy {
+	maxSize := int64(10 << 20)
+	if n, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64); err == nil && n > 0 {
+		maxSize = n
+	}
+	return UploadPolicy{
+		Dir: getenvDefault("ATTACHMENT_DIR", "attachments"),
+		AllowedTypes: map[string]string{
+			"application/pdf": ".pdf",
+			"image/jpeg":      ".jpg",
+			"image/png":       ".png",
+			"text/plain":      ".txt",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       ".xlsx",
+		},
+		MaxSize: maxSize,
+	}
+}
+
+// StoredUpload describes a file saved by SaveUpload
+type StoredUpload struct {
+	Filename     string // name on disk, inside the policy directory
+	OriginalName string // client-supplied name, reduced to its base name
+	ContentType  string
+	Size         int64
+}
+
+// sniffMatches checks the file content agrees with the declared type, so a renamed
+// executable can't be uploaded as a PDF. Office documents are ZIP containers.
+func sniffMatches(declared string, head []byte) bool {
+	sniffed := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument."):
+		return sniffed == "application/zip"
+	case declared == "text/plain":
+		return strings.HasPrefix(sniffed, "text/plain")
+	default:
+		return sniffed == declared
+	}
+}
+
+// SaveUpload reads the multipart file in field, checks it against the policy and stores
+// it as "<prefix>_<timestamp><ext>" in the policy directory.
+func SaveUpload(w http.ResponseWriter, r *http.Request, field, prefix string, policy UploadPolicy) (StoredUpload, error) {
+	var stored StoredUpload
+	// Allow a little room for the other multipart fields
+	r.Body = http.MaxBytesReader(w, r.Body, policy.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(policy.MaxSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return stored, fileTooLarge(policy)
+		}
+		return stored, NewValidationError(ErrInvalidInput, "Invalid form data", nil)
+	}
+
+	file, handler, err := r.FormFile(field)
+	if err != nil {
+		return stored, NewValidationError(ErrInvalidInput, "File is required", map[string]interface{}{"field": field})
+	}
+	defer file.Close()
+
+	if handler.Size > policy.MaxSize {
+		return stored, fileTooLarge(policy)
+	}
+	contentType := handler.Header.Get("Content-Type")
+	ext, ok := policy.AllowedTypes[contentType]
+	if !ok || !sniffFile(file, contentType) {
+		allowed := make([]string, 0, len(policy.AllowedTypes))
+		for t := range policy.AllowedTypes {
+			allowed = append(allowed, t)
+		}
+		return stored, NewValidationError(ErrInvalidInput, "Unsupported file type", map[string]interface{}{
+			"content_type":  contentType,
+			"allowed_types": allowed,
+		})
+	}
+
+	if err := os.MkdirAll(policy.Dir, 0755); err != nil {
+		return stored, err
+	}
+	filename := fmt.Sprintf("%s_%d%s", prefix, time.Now().UnixNano(), ext)
+	out, err := os.Create(filepath.Join(policy.Dir, filename))
+	if err != nil {
+		return stored, err
+	}
+	defer out.Close()
+	size, err := io.Copy(out, file)
+	if err != nil {
+		return stored, err
+	}
+
+	return StoredUpload{
+		Filename:     filename,
+		OriginalName: filepath.Base(strings.ReplaceAll(handler.Filename, "\\", "/")),
+		ContentType:  contentType,
+		Size:         size,
+	}, nil
+}
+
+func sniffFile(file multipart.File, declared string) bool {
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	return sniffMatches(declared, head[:n])
+}
+
+func fileTooLarge(policy UploadPolicy) error {
+	return NewValidationError(ErrFileTooLarge, "File is too large", map[string]interface{}{
+		"max_size": policy.MaxSize,
+	})
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffMatches(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj\n")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	zip := []byte("PK\x03\x04\x14\x00\x06\x00")
+	exe := []byte("MZ\x90\x00\x03\x00\x00\x00")
+
+	assert.True(t, sniffMatches("application/pdf", pdf))
+	assert.True(t, sniffMatches("image/png", png))
+	assert.True(t, sniffMatches("application/vnd.openxmlformats-officedocument.wordprocessingml.document", zip))
+	assert.True(t, sniffMatches("text/plain", []byte("Menu for Monday")))
+
+	assert.False(t, sniffMatches("application/pdf", exe))
+	assert.False(t, sniffMatches("image/png", pdf))
+	assert.False(t, sniffMatches("text/plain", exe))
+}
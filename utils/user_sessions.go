@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"github.com/gorilla/sessions"
+	"go.uber.org/zap"
+)
+
+const (
+	// SessionMaxAge is how long a session lasts without activity, in seconds
+	SessionMaxAge = 3600
+	// sessionTouchInterval limits how often last-seen times are written
+	sessionTouchInterval = time.Minute
+	// sessionIDKey is the session value holding the tracked session ID
+	sessionIDKey = "sid"
+)
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// trackSession creates a session record for the user and stores its ID in the session
+func trackSession(r *http.Request, session *sessions.Session, userID uint, now time.Time) {
+	sid := newSessionID()
+	ua := r.UserAgent()
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	record := models.UserSession{
+		UserID:     userID,
+		SIDHash:    HashToken(sid),
+		UserAgent:  ua,
+		IP:         ClientIP(r),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionMaxAge * time.Second),
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		zap.L().Warn("Failed to track session", zap.Error(err))
+		return
+	}
+	session.Values[sessionIDKey] = sid
+}
+
+// findSession returns the active session record for the session's ID
+func findSession(session *sessions.Session, userID uint, now time.Time) (models.UserSession, bool) {
+	var record models.UserSession
+	sid, _ := session.Values[sessionIDKey].(string)
+	if sid == "" {
+		return record, false
+	}
+	err := config.DB.Where("sid_hash = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", HashToken(sid), userID, now).First(&record).Error
+	return record, err == nil
+}
+
+// CurrentSessionID returns the ID of the request's session record (0 if untracked)
+func CurrentSessionID(r *http.Request, userID uint) uint {
+	session, _ := config.Store.Get(r, "session")
+	record, ok := findSession(session, userID, time.Now())
+	if !ok {
+		return 0
+	}
+	return record.ID
+}
+
+// CheckSession reports whether the session's record is still active and records the
+// activity. Sessions from before tracking existed get a record on first use; sessions
+// whose record was revoked or expired are rejected.
+func CheckSession(w http.ResponseWriter, r *http.Request, session *sessions.Session, userID uint) bool {
+	if config.DB == nil {
+		return true
+	}
+	now := time.Now()
+	if sid, _ := session.Values[sessionIDKey].(string); sid == "" {
+		trackSession(r, session, userID, now)
+		if err := session.Save(r, w); err != nil {
+			zap.L().Warn("CheckSession save error", zap.Error(err))
+		}
+		return true
+	}
+	record, ok := findSession(session, userID, now)
+	if !ok {
+		return false
+	}
+	if now.Sub(record.LastSeenAt) >= sessionTouchInterval {
+		config.DB.Model(&record).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   now.Add(SessionMaxAge * time.Second),
+			"ip":           ClientIP(r),
+		})
+	}
+	return true
+}
+
+// SessionActive reports whether the session's record is active, without recording activity
+func SessionActive(session *sessions.Session, userID uint) bool {
+	if config.DB == nil {
+		return true
+	}
+	if sid, _ := session.Values[sessionIDKey].(string); sid == "" {
+		return true
+	}
+	_, ok := findSession(session, userID, time.Now())
+	return ok
+}
+
+// ActiveSessions returns the user's active sessions, most recently used first
+func ActiveSessions(userID uint) ([]models.UserSession, error) {
+	var records []models.UserSession
+	err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&records).Error
+	return records, err
+}
+
+// RevokeSession ends one of the user's sessions; false if there was no such active session
+func RevokeSession(userID, sessionID uint) bool {
+	result := config.DB.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// RevokeUserSessions ends all of the user's sessions except exceptID (0 for none) and
+// returns how many were ended
+func RevokeUserSessions(userID, exceptID uint) int64 {
+	result := config.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userID, exceptID, time.Now()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		zap.L().Warn("Failed to revoke sessions", zap.Uint("user_id", userID), zap.Error(result.Error))
+	}
+	return result.RowsAffected
+}
+
+// revokeCurrentSession ends the record of the request's session, if it has one
+func revokeCurrentSession(session *sessions.Session) {
+	sid, _ := session.Values[sessionIDKey].(string)
+	if sid == "" || config.DB == nil {
+		return
+	}
+	config.DB.Model(&models.UserSession{}).
+		Where("sid_hash = ? AND revoked_at IS NULL", HashToken(sid)).
+		Update("revoked_at", time.Now())
+}
+
+// DescribeUserAgent turns a User-Agent header into a short label like "Firefox on Linux"
+func DescribeUserAgent(ua string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	platform := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
@@ -51,8 +51,10 @@ func IsEmailValid(email string) bool {
 	return re.MatchString(email)
 }
 
+// IsPasswordStrong checks a password's length and character classes against the policy
 func IsPasswordStrong(password string) bool {
-	return len(password) >= 8
+	policy := CurrentPasswordPolicy()
+	return len([]rune(password)) >= policy.MinLength && passwordClasses(password) >= policy.MinClasses
 }
 
 func IsFieldPresent(value string) bool {
@@ -76,20 +78,14 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
-// ValidatePassword validates password strength and returns detailed error
+// ValidatePassword checks a new password against the password policy and returns detailed error
 func ValidatePassword(password string) error {
 	if !IsFieldPresent(password) {
 		return NewValidationError(ErrInvalidInput, "Password is required", map[string]interface{}{
 			"field": "password",
 		})
 	}
-	if !IsPasswordStrong(password) {
-		return NewValidationError(ErrInvalidInput, "Password must be at least 8 characters", map[string]interface{}{
-			"field":      "password",
-			"min_length": 8,
-		})
-	}
-	return nil
+	return CurrentPasswordPolicy().Check(password)
 }
 
 // ValidateBirthdate validates birthdate string in YYYY-MM-DD and ensures age between 0-18
@@ -223,15 +219,13 @@ func ValidateReservation(childID, slotID uint) error {
 	return nil
 }
 
-// ValidateRole validates user role and returns detailed error
+// ValidateRole validates user role (built-in or custom) and returns detailed error
 func ValidateRole(role string) error {
-	validRoles := []string{"parent", "admin"}
-	for _, validRole := range validRoles {
-		if role == validRole {
-			return nil
-		}
+	if RoleExists(role) {
+		return nil
 	}
-	return NewValidationError(ErrInvalidRole, "Invalid role. Must be 'parent' or 'admin'", map[string]interface{}{
+	validRoles := append(append([]string{}, builtInRoleOrder...), customRoleNames()...)
+	return NewValidationError(ErrInvalidRole, "Invalid role. Must be a built-in or custom role", map[string]interface{}{
 		"field":       "role",
 		"value":       role,
 		"valid_roles": validRoles,
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"reservio/config"
+	"reservio/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery headers
+const (
+	WebhookSignatureHeader = "X-Reservio-Signature"
+	WebhookEventHeader     = "X-Reservio-Event"
+	WebhookDeliveryHeader  = "X-Reservio-Delivery"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookPending   = "pending"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+const (
+	webhookMaxResponseBody = 2048
+	webhookLease           = time.Minute
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookWake nudges the worker when new deliveries are queued
+var webhookWake = make(chan struct{}, 1)
+
+func init() {
+	SubscribeEvents(enqueueWebhookDeliveries)
+}
+
+// GenerateWebhookSecret returns a new random signing secret
+func GenerateWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// SignWebhookPayload returns the signature header value for a payload:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<payload>">".
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// VerifyWebhookSignature checks a signature header against the payload. Signatures older
+// than tolerance are rejected to limit replays (tolerance <= 0 disables the check).
+// Receivers can use this as a reference implementation.
+func VerifyWebhookSignature(secret, header string, payload []byte, tolerance time.Duration) bool {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			signature = kv[1]
+		}
+	}
+	if timestamp == 0 || signature == "" {
+		return false
+	}
+	if tolerance > 0 && time.Since(time.Unix(timestamp, 0)) > tolerance {
+		return false
+	}
+	expected := SignWebhookPayload(secret, timestamp, payload)
+	return hmac.Equal([]byte(expected), []byte(fmt.Sprintf("t=%d,v1=%s", timestamp, signature)))
+}
+
+// ValidateWebhookURL checks the endpoint is an absolute http(s) URL
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewValidationError(ErrInvalidInput, "Webhook URL must be an absolute http(s) URL", map[string]interface{}{
+			"field": "url",
+			"value": raw,
+		})
+	}
+	return nil
+}
+
+// ValidateWebhookEvents checks every requested event type exists ("*" means all)
+func ValidateWebhookEvents(events []string) error {
+	if len(events) == 0 {
+		return NewValidationError(ErrInvalidInput, "At least one event type is required", map[string]interface{}{
+			"field":        "events",
+			"valid_events": DomainEventTypes,
+		})
+	}
+	for _, event := range events {
+		if event == "*" {
+			continue
+		}
+		known := false
+		for _, valid := range DomainEventTypes {
+			if event == valid {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return NewValidationError(ErrInvalidInput, "Unknown event type", map[string]interface{}{
+				"field":        "events",
+				"value":        event,
+				"valid_events": DomainEventTypes,
+			})
+		}
+	}
+	return nil
+}
+
+// WebhookSubscribed reports whether an endpoint's event list includes eventType
+func WebhookSubscribed(endpoint models.WebhookEndpoint, eventType string) bool {
+	for _, event := range strings.Split(endpoint.Events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "*" || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookBackoff returns the wait before the next attempt: 30s doubling per attempt, capped at 1h
+func webhookBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+func webhookMaxAttempts() int {
+	if n, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		return n
+	}
+	return 6
+}
+
+// enqueueWebhookDeliveries queues a pending delivery for every active endpoint subscribed to the event
+func enqueueWebhookDeliveries(event DomainEvent) {
+	if config.DB == nil {
+		return
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := config.DB.Where("active = ?", true).Find(&endpoints).Error; err != nil {
+		zap.L().Error("Failed to load webhook endpoints", zap.Error(err))
+		return
+	}
+	var payload []byte
+	queued := false
+	for _, endpoint := range endpoints {
+		if !WebhookSubscribed(endpoint, event.Type) {
+			continue
+		}
+		if payload == nil {
+			payload, _ = json.Marshal(event)
+		}
+		now := time.Now()
+		delivery := models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        WebhookPending,
+			NextAttemptAt: &now,
+		}
+		if err := config.DB.Create(&delivery).Error; err != nil {
+			zap.L().Error("Failed to queue webhook delivery", zap.Uint("endpoint_id", endpoint.ID), zap.Error(err))
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		wakeWebhookWorker()
+	}
+}
+
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// ReplayWebhookDelivery queues a fresh copy of an earlier delivery (same event and payload)
+func ReplayWebhookDelivery(original models.WebhookDelivery) (models.WebhookDelivery, error) {
+	now := time.Now()
+	replay := models.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        WebhookPending,
+		NextAttemptAt: &now,
+		ReplayOfID:    &original.ID,
+	}
+	if err := config.DB.Create(&replay).Error; err != nil {
+		return replay, err
+	}
+	wakeWebhookWorker()
+	return replay, nil
+}
+
+// DeliverWebhook POSTs a signed payload to the endpoint and returns the response code and
+// (truncated) body. Any non-2xx response is returned as an error.
+func DeliverWebhook(client *http.Client, endpoint models.WebhookEndpoint, delivery models.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Reservio-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, time.Now().Unix(), payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// attemptWebhookDelivery claims a due delivery, sends it and records the outcome.
+// The claim (bumping attempts and leasing next_attempt_at) keeps other instances from
+// sending the same delivery concurrently.
+func attemptWebhookDelivery(delivery models.WebhookDelivery, now time.Time) {
+	lease := now.Add(webhookLease)
+	claim := config.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND attempts = ? AND status = ?", delivery.ID, delivery.Attempts, WebhookPending).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "next_attempt_at": lease})
+	if claim.Error != nil || claim.RowsAffected != 1 {
+		return
+	}
+	delivery.Attempts++
+
+	var endpoint models.WebhookEndpoint
+	if err := config.DB.First(&endpoint, delivery.EndpointID).Error; err != nil {
+		config.DB.Model(&delivery).Updates(map[string]interface{}{"status": WebhookFailed, "error": "endpoint no longer exists", "next_attempt_at": nil})
+		return
+	}
+
+	code, body, err := DeliverWebhook(webhookClient, endpoint, delivery)
+	updates := map[string]interface{}{"response_code": code, "response_body": body}
+	if err == nil {
+		delivered := time.Now()
+		updates["status"] = WebhookSucceeded
+		updates["error"] = ""
+		updates["delivered_at"] = delivered
+		updates["next_attempt_at"] = nil
+	} else if delivery.Attempts >= webhookMaxAttempts() {
+		updates["status"] = WebhookFailed
+		updates["error"] = err.Error()
+		updates["next_attempt_at"] = nil
+	} else {
+		updates["error"] = err.Error()
+		updates["next_attempt_at"] = time.Now().Add(webhookBackoff(delivery.Attempts))
+	}
+	if err := config.DB.Model(&delivery).Updates(updates).Error; err != nil {
+		zap.L().Error("Failed to record webhook delivery", zap.Uint("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// ProcessWebhookDeliveries sends every pending delivery that is due and returns how many were attempted
+func ProcessWebhookDeliveries(now time.Time) int {
+	var due []models.WebhookDelivery
+	if err := config.DB.Where("status = ? AND next_attempt_at <= ?", WebhookPending, now).
+		Order("next_attempt_at ASC").Limit(100).Find(&due).Error; err != nil {
+		zap.L().Error("Failed to load webhook deliveries", zap.Error(err))
+		return 0
+	}
+	for _, delivery := range due {
+		attemptWebhookDelivery(delivery, now)
+	}
+	return len(due)
+}
+
+// StartWebhookWorker delivers queued webhooks until ctx is cancelled. It polls every
+// few seconds for retries and wakes immediately when new deliveries are queued.
+func StartWebhookWorker(ctx context.Context) {
+	if os.Getenv("TEST_MODE") == "1" {
+		return
+	}
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		ProcessWebhookDeliveries(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-webhookWake:
+		}
+	}
+}
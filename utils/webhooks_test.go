@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reservio/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"type":"slot.created"}`)
+	header := SignWebhookPayload("secret", time.Now().Unix(), payload)
+
+	assert.True(t, VerifyWebhookSignature("secret", header, payload, 5*time.Minute))
+	assert.False(t, VerifyWebhookSignature("other", header, payload, 5*time.Minute))
+	assert.False(t, VerifyWebhookSignature("secret", header, []byte(`{}`), 5*time.Minute))
+
+	old := SignWebhookPayload("secret", time.Now().Add(-time.Hour).Unix(), payload)
+	assert.False(t, VerifyWebhookSignature("secret", old, payload, 5*time.Minute))
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, time.Minute, webhookBackoff(2))
+	assert.Equal(t, time.Hour, webhookBackoff(20))
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	endpoint := models.WebhookEndpoint{Events: "reservation.created,slot.updated"}
+	assert.True(t, WebhookSubscribed(endpoint, EventSlotUpdated))
+	assert.False(t, WebhookSubscribed(endpoint, EventUserRegistered))
+	assert.True(t, WebhookSubscribed(models.WebhookEndpoint{Events: "*"}, EventUserRegistered))
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	var gotSignature, gotEvent string
+	var gotBody []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get(WebhookEventHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("received"))
+	}))
+	defer server.Close()
+
+	endpoint := models.WebhookEndpoint{URL: server.URL, Secret: "whsec_test"}
+	delivery := models.WebhookDelivery{Event: EventSlotCreated, Payload: `{"id":"abc","type":"slot.created"}`}
+
+	code, body, err := DeliverWebhook(server.Client(), endpoint, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "received", body)
+	assert.Equal(t, EventSlotCreated, gotEvent)
+	assert.Equal(t, delivery.Payload, string(gotBody))
+	assert.True(t, VerifyWebhookSignature(endpoint.Secret, gotSignature, gotBody, time.Minute))
+
+	status = http.StatusInternalServerError
+	code, _, err = DeliverWebhook(server.Client(), endpoint, delivery)
+	assert.Error(t, err)
+	assert.Equal(t, 500, code)
+}